@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/edit4i/editor/internal/db"
 	"github.com/edit4i/editor/internal/service"
@@ -19,6 +20,7 @@ type App struct {
 	config          *service.ConfigService
 	terminalService *service.TerminalService
 	git             *service.GitService
+	ranking         *service.FileRankingService
 }
 
 // NewApp creates a new App application struct
@@ -39,8 +41,22 @@ func (a *App) startup(ctx context.Context) {
 
 	// Initialize services
 	a.projects = service.NewProjectsService(dbConn)
-	a.files = service.NewFileService()
-	a.git = service.NewGitService()
+	a.git = service.NewGitService(func(name string, data interface{}) {
+		// Emit git progress and change events to frontend
+		runtime.EventsEmit(a.ctx, name, data)
+	})
+	a.ranking = service.NewFileRankingService(dbConn, a.git)
+	a.files = service.NewFileService(func(name string, data interface{}) {
+		if event, ok := data.(service.GitStatusChangedEvent); ok {
+			// Changed files are boosted in file searches
+			a.ranking.InvalidateModified(event.ProjectPath)
+		}
+		// Emit file change events to frontend
+		runtime.EventsEmit(a.ctx, name, data)
+	})
+	// Keep file indexes next to the database, so reopened projects are searchable at once
+	a.files.SetIndexDir(filepath.Join(db.DefaultConfig().Directory, "index"))
+	a.files.SetRanker(a.ranking)
 
 	config, err := service.NewConfigService()
 	if err != nil {
@@ -105,13 +121,50 @@ func (a *App) SaveFile(path, content string) error {
 	return a.files.SaveFile(path, content)
 }
 
-// SearchFiles performs a fuzzy search on files in a directory
-func (a *App) SearchFiles(dirPath, query string) ([]*service.FileNode, error) {
+// UnwatchProject stops following file changes in a project that was closed
+func (a *App) UnwatchProject(projectPath string) {
+	a.files.UnwatchProject(projectPath)
+}
+
+// SearchFiles performs a fuzzy search on files in a directory, returning at most limit files.
+// An empty query returns the recently opened and changed files.
+func (a *App) SearchFiles(dirPath, query string, limit int) ([]*service.FileNode, error) {
 	// Create a new context that will be cancelled when a new search starts
 	ctx, cancel := context.WithCancel(a.ctx)
 	defer cancel()
 
-	return a.files.SearchFiles(ctx, dirPath, query)
+	return a.files.SearchFiles(ctx, dirPath, query, limit)
+}
+
+// RecordFileOpen adds an open of a file to its project's history, which ranks file searches
+func (a *App) RecordFileOpen(projectPath, filePath string) error {
+	return a.ranking.RecordFileOpen(projectPath, filePath)
+}
+
+// SearchContent searches the contents of a project's files, streaming the matches as search:results events.
+// It cancels the previous search.
+func (a *App) SearchContent(projectPath string, query string, opts service.SearchOptions) (*service.SearchSummary, error) {
+	return a.files.SearchContent(a.ctx, projectPath, query, opts)
+}
+
+// CancelSearch stops the content search in progress
+func (a *App) CancelSearch() {
+	a.files.CancelSearch()
+}
+
+// PreviewReplace returns the diff each file of a project would get from a replace, without writing anything
+func (a *App) PreviewReplace(projectPath string, query string, replacement string, opts service.SearchOptions) (*service.ReplacePreview, error) {
+	return a.files.PreviewReplace(a.ctx, projectPath, query, replacement, opts)
+}
+
+// ApplyReplace replaces the matches of a search in the files picked from a preview
+func (a *App) ApplyReplace(projectPath string, query string, replacement string, opts service.SearchOptions, files []service.ReplaceFile) (*service.ReplaceResult, error) {
+	return a.files.ApplyReplace(projectPath, query, replacement, opts, files)
+}
+
+// UndoReplace reverts the files written by a replace
+func (a *App) UndoReplace(operationID int) (*service.ReplaceResult, error) {
+	return a.files.UndoReplace(operationID)
 }
 
 // Greet returns a greeting for the given name
@@ -190,6 +243,11 @@ func (a *App) GetGitStatus(projectPath string) ([]service.FileStatus, error) {
 	return a.git.GetStatus(projectPath)
 }
 
+// GetGitStatusWithOptions returns the git status, optionally with renames in the working tree
+func (a *App) GetGitStatusWithOptions(projectPath string, opts service.StatusOptions) ([]service.FileStatus, error) {
+	return a.git.GetStatusWithOptions(projectPath, opts)
+}
+
 // StageFile adds a file to the staging area
 func (a *App) StageFile(projectPath string, file string) error {
 	return a.git.StageFile(projectPath, file)
@@ -200,6 +258,36 @@ func (a *App) UnstageFile(projectPath string, file string) error {
 	return a.git.UnstageFile(projectPath, file)
 }
 
+// StageHunk stages a single hunk of a file's unstaged diff
+func (a *App) StageHunk(projectPath string, file string, hunk service.Hunk) error {
+	return a.git.StageHunk(projectPath, file, hunk)
+}
+
+// UnstageHunk unstages a single hunk of a file's staged diff
+func (a *App) UnstageHunk(projectPath string, file string, hunk service.Hunk) error {
+	return a.git.UnstageHunk(projectPath, file, hunk)
+}
+
+// DiscardHunk reverts a single hunk of a file's unstaged diff
+func (a *App) DiscardHunk(projectPath string, file string, hunk service.Hunk) error {
+	return a.git.DiscardHunk(projectPath, file, hunk)
+}
+
+// StageLines stages the selected lines of a file's unstaged diff
+func (a *App) StageLines(projectPath string, file string, ranges []service.LineRange) error {
+	return a.git.StageLines(projectPath, file, ranges)
+}
+
+// UnstageLines unstages the selected lines of a file's staged diff
+func (a *App) UnstageLines(projectPath string, file string, ranges []service.LineRange) error {
+	return a.git.UnstageLines(projectPath, file, ranges)
+}
+
+// DiscardLines reverts the selected lines of a file's unstaged diff
+func (a *App) DiscardLines(projectPath string, file string, ranges []service.LineRange) error {
+	return a.git.DiscardLines(projectPath, file, ranges)
+}
+
 // DiscardChanges discards changes in a file, reverting it to the last commit
 func (a *App) DiscardChanges(projectPath string, file string) error {
 	return a.git.DiscardChanges(projectPath, file)
@@ -210,6 +298,36 @@ func (a *App) Commit(projectPath string, message string) error {
 	return a.git.Commit(projectPath, message)
 }
 
+// AmendCommit replaces the last commit with the staged changes, optionally changing its message
+func (a *App) AmendCommit(projectPath string, message string) (string, error) {
+	return a.git.AmendCommit(projectPath, message)
+}
+
+// Reset moves the current branch to a commit in soft, mixed or hard mode
+func (a *App) Reset(projectPath string, mode string, target string) (string, error) {
+	return a.git.Reset(projectPath, mode, target)
+}
+
+// RevertCommit creates a commit undoing the changes of a commit
+func (a *App) RevertCommit(projectPath string, hash string) (string, error) {
+	return a.git.RevertCommit(projectPath, hash)
+}
+
+// CherryPick applies the changes of a commit on top of the current branch
+func (a *App) CherryPick(projectPath string, hash string) (string, error) {
+	return a.git.CherryPick(projectPath, hash)
+}
+
+// GetIdentity returns the name, email and signing settings commits are made with in a project
+func (a *App) GetIdentity(projectPath string) (*service.GitIdentity, error) {
+	return a.git.GetIdentity(projectPath)
+}
+
+// SetIdentity overrides the commit identity and signing settings for a project
+func (a *App) SetIdentity(projectPath string, identity service.GitIdentity) error {
+	return a.git.SetIdentity(projectPath, identity)
+}
+
 // ListBranches returns a list of all branches in the repository
 func (a *App) ListBranches(projectPath string) ([]service.BranchInfo, error) {
 	return a.git.ListBranches(projectPath)
@@ -220,6 +338,161 @@ func (a *App) GetCurrentBranch(projectPath string) (string, error) {
 	return a.git.GetCurrentBranch(projectPath)
 }
 
+// GetBranchTracking returns upstream and ahead/behind information for a branch (current branch if empty)
+func (a *App) GetBranchTracking(projectPath string, branch string) (*service.BranchInfo, error) {
+	return a.git.GetBranchTracking(projectPath, branch)
+}
+
+// CheckoutBranch switches the working tree to the given branch
+func (a *App) CheckoutBranch(projectPath string, branch string) error {
+	return a.git.CheckoutBranch(projectPath, branch)
+}
+
+// CreateBranch creates a new branch starting at the given branch, tag or commit (HEAD if empty)
+func (a *App) CreateBranch(projectPath string, name string, startPoint string) error {
+	return a.git.CreateBranch(projectPath, name, startPoint)
+}
+
+// DeleteBranch deletes a local branch
+func (a *App) DeleteBranch(projectPath string, name string, force bool) error {
+	return a.git.DeleteBranch(projectPath, name, force)
+}
+
+// ListTags returns all tags, newest first
+func (a *App) ListTags(projectPath string) ([]service.TagInfo, error) {
+	return a.git.ListTags(projectPath)
+}
+
+// CreateTag tags a commit, annotated when a message is given and optionally signed
+func (a *App) CreateTag(projectPath string, name string, target string, message string, sign bool) (*service.TagInfo, error) {
+	return a.git.CreateTag(projectPath, name, target, message, sign)
+}
+
+// DeleteTag deletes a local tag
+func (a *App) DeleteTag(projectPath string, name string) error {
+	return a.git.DeleteTag(projectPath, name)
+}
+
+// PushTag pushes a tag to a remote
+func (a *App) PushTag(projectPath string, name string, opts service.RemoteOptions) error {
+	return a.git.PushTag(projectPath, name, opts)
+}
+
+// ListSubmodules returns the submodules of a project with the state of their checkouts
+func (a *App) ListSubmodules(projectPath string) ([]service.SubmoduleInfo, error) {
+	return a.git.ListSubmodules(projectPath)
+}
+
+// ListWorktrees returns the working trees of the repository of a project
+func (a *App) ListWorktrees(projectPath string) ([]service.WorktreeInfo, error) {
+	return a.git.ListWorktrees(projectPath)
+}
+
+// AddWorktree checks out a branch in a new working tree, which can be opened as a separate project
+func (a *App) AddWorktree(projectPath string, path string, branch string, newBranch bool) (*service.WorktreeInfo, error) {
+	return a.git.AddWorktree(projectPath, path, branch, newBranch)
+}
+
+// RemoveWorktree deletes a linked working tree
+func (a *App) RemoveWorktree(projectPath string, path string, force bool) error {
+	return a.git.RemoveWorktree(projectPath, path, force)
+}
+
+// RenameBranch renames a local branch
+func (a *App) RenameBranch(projectPath string, oldName string, newName string) error {
+	return a.git.RenameBranch(projectPath, oldName, newName)
+}
+
+// FetchRemote downloads objects and refs from a remote
+func (a *App) FetchRemote(projectPath string, opts service.RemoteOptions) error {
+	return a.git.Fetch(projectPath, opts)
+}
+
+// Pull fetches and integrates the upstream of the current branch
+func (a *App) Pull(projectPath string, opts service.RemoteOptions) (*service.PullResult, error) {
+	return a.git.Pull(projectPath, opts)
+}
+
+// Push uploads the current branch to a remote
+func (a *App) Push(projectPath string, opts service.RemoteOptions) error {
+	return a.git.Push(projectPath, opts)
+}
+
+// Merge merges a branch, tag or commit into the current branch
+func (a *App) Merge(projectPath string, branch string, opts service.MergeOptions) (*service.MergeResult, error) {
+	return a.git.Merge(projectPath, branch, opts)
+}
+
+// ListConflicts returns the base, ours and theirs versions of every conflicted file
+func (a *App) ListConflicts(projectPath string) ([]service.ConflictFile, error) {
+	return a.git.ListConflicts(projectPath)
+}
+
+// ResolveConflict marks a conflicted file as resolved with the chosen version
+func (a *App) ResolveConflict(projectPath string, file string, resolution service.ConflictResolution) error {
+	return a.git.ResolveConflict(projectPath, file, resolution)
+}
+
+// MergeAbort abandons a merge with conflicts
+func (a *App) MergeAbort(projectPath string) error {
+	return a.git.MergeAbort(projectPath)
+}
+
+// RebasePlan lists the commits a rebase of the current branch onto a branch, tag or commit would replay
+func (a *App) RebasePlan(projectPath string, onto string) (*service.RebasePlan, error) {
+	return a.git.RebasePlan(projectPath, onto)
+}
+
+// RebaseExecute replays the steps of a rebase plan, stopping at the first conflict
+func (a *App) RebaseExecute(projectPath string, plan service.RebasePlan) (*service.RebaseResult, error) {
+	return a.git.RebaseExecute(projectPath, plan)
+}
+
+// RebaseContinue commits the resolved step of a stopped rebase and replays the rest
+func (a *App) RebaseContinue(projectPath string) (*service.RebaseResult, error) {
+	return a.git.RebaseContinue(projectPath)
+}
+
+// RebaseAbort abandons a rebase and returns to where it started
+func (a *App) RebaseAbort(projectPath string) error {
+	return a.git.RebaseAbort(projectPath)
+}
+
+// GetRebaseState returns the rebase in progress, or nil when there is none
+func (a *App) GetRebaseState(projectPath string) (*service.RebaseState, error) {
+	return a.git.GetRebaseState(projectPath)
+}
+
+// StashSave stashes the local changes and reverts them
+func (a *App) StashSave(projectPath string, opts service.StashOptions) (*service.StashEntry, error) {
+	return a.git.StashSave(projectPath, opts)
+}
+
+// StashList returns the stash entries, most recent first
+func (a *App) StashList(projectPath string) ([]service.StashEntry, error) {
+	return a.git.StashList(projectPath)
+}
+
+// StashApply restores the changes of a stash entry
+func (a *App) StashApply(projectPath string, index int) error {
+	return a.git.StashApply(projectPath, index)
+}
+
+// StashPop restores the changes of a stash entry and removes it from the stash
+func (a *App) StashPop(projectPath string, index int) error {
+	return a.git.StashPop(projectPath, index)
+}
+
+// StashDrop removes an entry from the stash
+func (a *App) StashDrop(projectPath string, index int) error {
+	return a.git.StashDrop(projectPath, index)
+}
+
+// StashShow returns the diff of every file changed by a stash entry
+func (a *App) StashShow(projectPath string, index int) ([]service.FileDiff, error) {
+	return a.git.StashShow(projectPath, index)
+}
+
 // ListCommits returns a list of commits based on the provided filters
 func (a *App) ListCommits(projectPath string, filter service.CommitFilter) ([]service.CommitInfo, error) {
 	return a.git.ListCommits(projectPath, filter)
@@ -245,12 +518,57 @@ func (a *App) SearchCommits(projectPath string, query string, limit int) ([]serv
 	return a.git.SearchCommits(projectPath, query, limit)
 }
 
+// FileHistory returns the commits that changed a file, following renames
+func (a *App) FileHistory(projectPath string, file string, filter service.CommitFilter) ([]service.CommitInfo, error) {
+	return a.git.FileHistory(projectPath, file, filter)
+}
+
+// CommitGraph returns a page of commits with their graph layout, continuing the lanes of the previous page
+func (a *App) CommitGraph(projectPath string, filter service.CommitFilter, lanes []service.GraphLane) (*service.CommitGraph, error) {
+	return a.git.CommitGraph(projectPath, filter, lanes)
+}
+
 // GetHeadCommit returns the head commit of the repository
 func (a *App) GetHeadCommit(projectPath string) (*service.CommitInfo, error) {
 	return a.git.GetHeadCommit(projectPath)
 }
 
+// VerifyCommits checks the signatures of the given commits, for the commits on screen
+func (a *App) VerifyCommits(projectPath string, hashes []string) (map[string]bool, error) {
+	return a.git.VerifyCommits(projectPath, hashes)
+}
+
+// Blame returns the commit that introduced each line of a file at a revision (HEAD if empty)
+func (a *App) Blame(projectPath string, file string, rev string) (*service.BlameResult, error) {
+	return a.git.Blame(projectPath, file, rev)
+}
+
+// BlameContent blames unsaved editor content against HEAD, marking changed lines as uncommitted
+func (a *App) BlameContent(projectPath string, file string, content string) (*service.BlameResult, error) {
+	return a.git.BlameContent(projectPath, file, content)
+}
+
+// DiffRefs returns the diffs between two branches, tags, commits, INDEX or WORKTREE
+func (a *App) DiffRefs(projectPath string, fromRef string, toRef string, paths []string) ([]service.FileDiff, error) {
+	return a.git.DiffRefs(projectPath, fromRef, toRef, paths)
+}
+
+// GetCommitDetails returns the full information and changed files of a commit
+func (a *App) GetCommitDetails(projectPath string, hash string) (*service.CommitDetails, error) {
+	return a.git.GetCommitDetails(projectPath, hash)
+}
+
+// GetCommitDetailsWithOptions returns the details of a commit compared to the chosen parent
+func (a *App) GetCommitDetailsWithOptions(projectPath string, hash string, opts service.CommitDetailsOptions) (*service.CommitDetails, error) {
+	return a.git.GetCommitDetailsWithOptions(projectPath, hash, opts)
+}
+
 // GetFileDiff returns the diff for a specific file
 func (a *App) GetFileDiff(projectPath string, filePath string, staged bool) (*service.FileDiff, error) {
 	return a.git.GetFileDiff(projectPath, filePath, staged)
 }
+
+// GetFileDiffWithOptions returns the diff for a specific file using the given diff options
+func (a *App) GetFileDiffWithOptions(projectPath string, filePath string, staged bool, opts service.DiffOptions) (*service.FileDiff, error) {
+	return a.git.GetFileDiffWithOptions(projectPath, filePath, staged, opts)
+}
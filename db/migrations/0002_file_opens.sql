@@ -0,0 +1,16 @@
+-- migrate:up
+
+CREATE TABLE file_opens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    open_count INTEGER NOT NULL DEFAULT 1,
+    last_opened DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (project_id, path)
+);
+
+CREATE INDEX idx_file_opens_last_opened ON file_opens(project_id, last_opened);
+
+-- migrate:down
+
+DROP TABLE file_opens;
\ No newline at end of file
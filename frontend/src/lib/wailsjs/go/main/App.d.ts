@@ -5,22 +5,64 @@ import {service} from '../models';
 
 export function AddProject(arg1:string,arg2:string):Promise<db.Project>;
 
+export function AddWorktree(arg1:string,arg2:string,arg3:string,arg4:boolean):Promise<service.WorktreeInfo>;
+
+export function AmendCommit(arg1:string,arg2:string):Promise<string>;
+
+export function ApplyReplace(arg1:string,arg2:string,arg3:string,arg4:service.SearchOptions,arg5:Array<service.ReplaceFile>):Promise<service.ReplaceResult>;
+
+export function Blame(arg1:string,arg2:string,arg3:string):Promise<service.BlameResult>;
+
+export function BlameContent(arg1:string,arg2:string,arg3:string):Promise<service.BlameResult>;
+
+export function CancelSearch():Promise<void>;
+
+export function CheckoutBranch(arg1:string,arg2:string):Promise<void>;
+
+export function CherryPick(arg1:string,arg2:string):Promise<string>;
+
 export function Commit(arg1:string,arg2:string):Promise<void>;
 
+export function CommitGraph(arg1:string,arg2:service.CommitFilter,arg3:Array<service.GraphLane>):Promise<service.CommitGraph>;
+
+export function CreateBranch(arg1:string,arg2:string,arg3:string):Promise<void>;
+
 export function CreateDirectory(arg1:string):Promise<void>;
 
 export function CreateFile(arg1:string):Promise<void>;
 
+export function CreateTag(arg1:string,arg2:string,arg3:string,arg4:string,arg5:boolean):Promise<service.TagInfo>;
+
 export function CreateTerminal(arg1:string,arg2:string,arg3:string):Promise<void>;
 
+export function DeleteBranch(arg1:string,arg2:string,arg3:boolean):Promise<void>;
+
 export function DeleteFile(arg1:string):Promise<void>;
 
+export function DeleteTag(arg1:string,arg2:string):Promise<void>;
+
 export function DestroyTerminal(arg1:string):Promise<void>;
 
+export function DiffRefs(arg1:string,arg2:string,arg3:string,arg4:Array<string>):Promise<Array<service.FileDiff>>;
+
 export function DiscardChanges(arg1:string,arg2:string):Promise<void>;
 
+export function DiscardHunk(arg1:string,arg2:string,arg3:service.Hunk):Promise<void>;
+
+export function DiscardLines(arg1:string,arg2:string,arg3:Array<service.LineRange>):Promise<void>;
+
+export function FetchRemote(arg1:string,arg2:service.RemoteOptions):Promise<void>;
+
+export function FileHistory(arg1:string,arg2:string,arg3:service.CommitFilter):Promise<Array<service.CommitInfo>>;
+
 export function GetAvailableShells():Promise<Array<string>>;
 
+export function GetBranchTracking(arg1:string,arg2:string):Promise<service.BranchInfo>;
+
+export function GetCommitDetails(arg1:string,arg2:string):Promise<service.CommitDetails>;
+
+export function GetCommitDetailsWithOptions(arg1:string,arg2:string,arg3:service.CommitDetailsOptions):Promise<service.CommitDetails>;
+
 export function GetCurrentBranch(arg1:string):Promise<string>;
 
 export function GetEditorConfig():Promise<service.EditorConfig>;
@@ -29,12 +71,20 @@ export function GetFileContent(arg1:string):Promise<string>;
 
 export function GetFileDiff(arg1:string,arg2:string,arg3:boolean):Promise<service.FileDiff>;
 
+export function GetFileDiffWithOptions(arg1:string,arg2:string,arg3:boolean,arg4:service.DiffOptions):Promise<service.FileDiff>;
+
 export function GetGitStatus(arg1:string):Promise<Array<service.FileStatus>>;
 
+export function GetGitStatusWithOptions(arg1:string,arg2:service.StatusOptions):Promise<Array<service.FileStatus>>;
+
 export function GetHeadCommit(arg1:string):Promise<service.CommitInfo>;
 
+export function GetIdentity(arg1:string):Promise<service.GitIdentity>;
+
 export function GetProjectFiles(arg1:string):Promise<service.FileNode>;
 
+export function GetRebaseState(arg1:string):Promise<service.RebaseState>;
+
 export function GetRecentProjects():Promise<Array<db.Project>>;
 
 export function Greet(arg1:string):Promise<string>;
@@ -55,22 +105,92 @@ export function ListCommitsByAuthor(arg1:string,arg2:string,arg3:number):Promise
 
 export function ListCommitsByBranch(arg1:string,arg2:string,arg3:number):Promise<Array<service.CommitInfo>>;
 
+export function ListConflicts(arg1:string):Promise<Array<service.ConflictFile>>;
+
+export function ListSubmodules(arg1:string):Promise<Array<service.SubmoduleInfo>>;
+
+export function ListTags(arg1:string):Promise<Array<service.TagInfo>>;
+
+export function ListWorktrees(arg1:string):Promise<Array<service.WorktreeInfo>>;
+
 export function LoadDirectoryContents(arg1:string):Promise<service.FileNode>;
 
+export function Merge(arg1:string,arg2:string,arg3:service.MergeOptions):Promise<service.MergeResult>;
+
+export function MergeAbort(arg1:string):Promise<void>;
+
 export function OpenConfigFile():Promise<string>;
 
 export function OpenProjectFolder():Promise<string>;
 
+export function PreviewReplace(arg1:string,arg2:string,arg3:string,arg4:service.SearchOptions):Promise<service.ReplacePreview>;
+
+export function Pull(arg1:string,arg2:service.RemoteOptions):Promise<service.PullResult>;
+
+export function Push(arg1:string,arg2:service.RemoteOptions):Promise<void>;
+
+export function PushTag(arg1:string,arg2:string,arg3:service.RemoteOptions):Promise<void>;
+
+export function RebaseAbort(arg1:string):Promise<void>;
+
+export function RebaseContinue(arg1:string):Promise<service.RebaseResult>;
+
+export function RebaseExecute(arg1:string,arg2:service.RebasePlan):Promise<service.RebaseResult>;
+
+export function RebasePlan(arg1:string,arg2:string):Promise<service.RebasePlan>;
+
+export function RecordFileOpen(arg1:string,arg2:string):Promise<void>;
+
+export function RemoveWorktree(arg1:string,arg2:string,arg3:boolean):Promise<void>;
+
+export function RenameBranch(arg1:string,arg2:string,arg3:string):Promise<void>;
+
 export function RenameFile(arg1:string,arg2:string):Promise<void>;
 
+export function Reset(arg1:string,arg2:string,arg3:string):Promise<string>;
+
 export function ResizeTerminal(arg1:string,arg2:number,arg3:number):Promise<void>;
 
+export function ResolveConflict(arg1:string,arg2:string,arg3:service.ConflictResolution):Promise<void>;
+
+export function RevertCommit(arg1:string,arg2:string):Promise<string>;
+
 export function SaveFile(arg1:string,arg2:string):Promise<void>;
 
 export function SearchCommits(arg1:string,arg2:string,arg3:number):Promise<Array<service.CommitInfo>>;
 
-export function SearchFiles(arg1:string,arg2:string):Promise<Array<service.FileNode>>;
+export function SearchContent(arg1:string,arg2:string,arg3:service.SearchOptions):Promise<service.SearchSummary>;
+
+export function SearchFiles(arg1:string,arg2:string,arg3:number):Promise<Array<service.FileNode>>;
+
+export function SetIdentity(arg1:string,arg2:service.GitIdentity):Promise<void>;
 
 export function StageFile(arg1:string,arg2:string):Promise<void>;
 
+export function StageHunk(arg1:string,arg2:string,arg3:service.Hunk):Promise<void>;
+
+export function StageLines(arg1:string,arg2:string,arg3:Array<service.LineRange>):Promise<void>;
+
+export function StashApply(arg1:string,arg2:number):Promise<void>;
+
+export function StashDrop(arg1:string,arg2:number):Promise<void>;
+
+export function StashList(arg1:string):Promise<Array<service.StashEntry>>;
+
+export function StashPop(arg1:string,arg2:number):Promise<void>;
+
+export function StashSave(arg1:string,arg2:service.StashOptions):Promise<service.StashEntry>;
+
+export function StashShow(arg1:string,arg2:number):Promise<Array<service.FileDiff>>;
+
+export function UndoReplace(arg1:number):Promise<service.ReplaceResult>;
+
 export function UnstageFile(arg1:string,arg2:string):Promise<void>;
+
+export function UnstageHunk(arg1:string,arg2:string,arg3:service.Hunk):Promise<void>;
+
+export function UnstageLines(arg1:string,arg2:string,arg3:Array<service.LineRange>):Promise<void>;
+
+export function UnwatchProject(arg1:string):Promise<void>;
+
+export function VerifyCommits(arg1:string,arg2:Array<string>):Promise<{[key: string]: boolean}>;
@@ -6,10 +6,50 @@ export function AddProject(arg1, arg2) {
   return window['go']['main']['App']['AddProject'](arg1, arg2);
 }
 
+export function AddWorktree(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['AddWorktree'](arg1, arg2, arg3, arg4);
+}
+
+export function AmendCommit(arg1, arg2) {
+  return window['go']['main']['App']['AmendCommit'](arg1, arg2);
+}
+
+export function ApplyReplace(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ApplyReplace'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function Blame(arg1, arg2, arg3) {
+  return window['go']['main']['App']['Blame'](arg1, arg2, arg3);
+}
+
+export function BlameContent(arg1, arg2, arg3) {
+  return window['go']['main']['App']['BlameContent'](arg1, arg2, arg3);
+}
+
+export function CancelSearch() {
+  return window['go']['main']['App']['CancelSearch']();
+}
+
+export function CheckoutBranch(arg1, arg2) {
+  return window['go']['main']['App']['CheckoutBranch'](arg1, arg2);
+}
+
+export function CherryPick(arg1, arg2) {
+  return window['go']['main']['App']['CherryPick'](arg1, arg2);
+}
+
 export function Commit(arg1, arg2) {
   return window['go']['main']['App']['Commit'](arg1, arg2);
 }
 
+export function CommitGraph(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CommitGraph'](arg1, arg2, arg3);
+}
+
+export function CreateBranch(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CreateBranch'](arg1, arg2, arg3);
+}
+
 export function CreateDirectory(arg1) {
   return window['go']['main']['App']['CreateDirectory'](arg1);
 }
@@ -18,26 +58,70 @@ export function CreateFile(arg1) {
   return window['go']['main']['App']['CreateFile'](arg1);
 }
 
+export function CreateTag(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['CreateTag'](arg1, arg2, arg3, arg4, arg5);
+}
+
 export function CreateTerminal(arg1, arg2, arg3) {
   return window['go']['main']['App']['CreateTerminal'](arg1, arg2, arg3);
 }
 
+export function DeleteBranch(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DeleteBranch'](arg1, arg2, arg3);
+}
+
 export function DeleteFile(arg1) {
   return window['go']['main']['App']['DeleteFile'](arg1);
 }
 
+export function DeleteTag(arg1, arg2) {
+  return window['go']['main']['App']['DeleteTag'](arg1, arg2);
+}
+
 export function DestroyTerminal(arg1) {
   return window['go']['main']['App']['DestroyTerminal'](arg1);
 }
 
+export function DiffRefs(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['DiffRefs'](arg1, arg2, arg3, arg4);
+}
+
 export function DiscardChanges(arg1, arg2) {
   return window['go']['main']['App']['DiscardChanges'](arg1, arg2);
 }
 
+export function DiscardHunk(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DiscardHunk'](arg1, arg2, arg3);
+}
+
+export function DiscardLines(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DiscardLines'](arg1, arg2, arg3);
+}
+
+export function FetchRemote(arg1, arg2) {
+  return window['go']['main']['App']['FetchRemote'](arg1, arg2);
+}
+
+export function FileHistory(arg1, arg2, arg3) {
+  return window['go']['main']['App']['FileHistory'](arg1, arg2, arg3);
+}
+
 export function GetAvailableShells() {
   return window['go']['main']['App']['GetAvailableShells']();
 }
 
+export function GetBranchTracking(arg1, arg2) {
+  return window['go']['main']['App']['GetBranchTracking'](arg1, arg2);
+}
+
+export function GetCommitDetails(arg1, arg2) {
+  return window['go']['main']['App']['GetCommitDetails'](arg1, arg2);
+}
+
+export function GetCommitDetailsWithOptions(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetCommitDetailsWithOptions'](arg1, arg2, arg3);
+}
+
 export function GetCurrentBranch(arg1) {
   return window['go']['main']['App']['GetCurrentBranch'](arg1);
 }
@@ -54,18 +138,34 @@ export function GetFileDiff(arg1, arg2, arg3) {
   return window['go']['main']['App']['GetFileDiff'](arg1, arg2, arg3);
 }
 
+export function GetFileDiffWithOptions(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['GetFileDiffWithOptions'](arg1, arg2, arg3, arg4);
+}
+
 export function GetGitStatus(arg1) {
   return window['go']['main']['App']['GetGitStatus'](arg1);
 }
 
+export function GetGitStatusWithOptions(arg1, arg2) {
+  return window['go']['main']['App']['GetGitStatusWithOptions'](arg1, arg2);
+}
+
 export function GetHeadCommit(arg1) {
   return window['go']['main']['App']['GetHeadCommit'](arg1);
 }
 
+export function GetIdentity(arg1) {
+  return window['go']['main']['App']['GetIdentity'](arg1);
+}
+
 export function GetProjectFiles(arg1) {
   return window['go']['main']['App']['GetProjectFiles'](arg1);
 }
 
+export function GetRebaseState(arg1) {
+  return window['go']['main']['App']['GetRebaseState'](arg1);
+}
+
 export function GetRecentProjects() {
   return window['go']['main']['App']['GetRecentProjects']();
 }
@@ -106,10 +206,34 @@ export function ListCommitsByBranch(arg1, arg2, arg3) {
   return window['go']['main']['App']['ListCommitsByBranch'](arg1, arg2, arg3);
 }
 
+export function ListConflicts(arg1) {
+  return window['go']['main']['App']['ListConflicts'](arg1);
+}
+
+export function ListSubmodules(arg1) {
+  return window['go']['main']['App']['ListSubmodules'](arg1);
+}
+
+export function ListTags(arg1) {
+  return window['go']['main']['App']['ListTags'](arg1);
+}
+
+export function ListWorktrees(arg1) {
+  return window['go']['main']['App']['ListWorktrees'](arg1);
+}
+
 export function LoadDirectoryContents(arg1) {
   return window['go']['main']['App']['LoadDirectoryContents'](arg1);
 }
 
+export function Merge(arg1, arg2, arg3) {
+  return window['go']['main']['App']['Merge'](arg1, arg2, arg3);
+}
+
+export function MergeAbort(arg1) {
+  return window['go']['main']['App']['MergeAbort'](arg1);
+}
+
 export function OpenConfigFile() {
   return window['go']['main']['App']['OpenConfigFile']();
 }
@@ -118,14 +242,70 @@ export function OpenProjectFolder() {
   return window['go']['main']['App']['OpenProjectFolder']();
 }
 
+export function PreviewReplace(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['PreviewReplace'](arg1, arg2, arg3, arg4);
+}
+
+export function Pull(arg1, arg2) {
+  return window['go']['main']['App']['Pull'](arg1, arg2);
+}
+
+export function Push(arg1, arg2) {
+  return window['go']['main']['App']['Push'](arg1, arg2);
+}
+
+export function PushTag(arg1, arg2, arg3) {
+  return window['go']['main']['App']['PushTag'](arg1, arg2, arg3);
+}
+
+export function RebaseAbort(arg1) {
+  return window['go']['main']['App']['RebaseAbort'](arg1);
+}
+
+export function RebaseContinue(arg1) {
+  return window['go']['main']['App']['RebaseContinue'](arg1);
+}
+
+export function RebaseExecute(arg1, arg2) {
+  return window['go']['main']['App']['RebaseExecute'](arg1, arg2);
+}
+
+export function RebasePlan(arg1, arg2) {
+  return window['go']['main']['App']['RebasePlan'](arg1, arg2);
+}
+
+export function RecordFileOpen(arg1, arg2) {
+  return window['go']['main']['App']['RecordFileOpen'](arg1, arg2);
+}
+
+export function RemoveWorktree(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RemoveWorktree'](arg1, arg2, arg3);
+}
+
+export function RenameBranch(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RenameBranch'](arg1, arg2, arg3);
+}
+
 export function RenameFile(arg1, arg2) {
   return window['go']['main']['App']['RenameFile'](arg1, arg2);
 }
 
+export function Reset(arg1, arg2, arg3) {
+  return window['go']['main']['App']['Reset'](arg1, arg2, arg3);
+}
+
 export function ResizeTerminal(arg1, arg2, arg3) {
   return window['go']['main']['App']['ResizeTerminal'](arg1, arg2, arg3);
 }
 
+export function ResolveConflict(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ResolveConflict'](arg1, arg2, arg3);
+}
+
+export function RevertCommit(arg1, arg2) {
+  return window['go']['main']['App']['RevertCommit'](arg1, arg2);
+}
+
 export function SaveFile(arg1, arg2) {
   return window['go']['main']['App']['SaveFile'](arg1, arg2);
 }
@@ -134,14 +314,74 @@ export function SearchCommits(arg1, arg2, arg3) {
   return window['go']['main']['App']['SearchCommits'](arg1, arg2, arg3);
 }
 
-export function SearchFiles(arg1, arg2) {
-  return window['go']['main']['App']['SearchFiles'](arg1, arg2);
+export function SearchContent(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SearchContent'](arg1, arg2, arg3);
+}
+
+export function SearchFiles(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SearchFiles'](arg1, arg2, arg3);
+}
+
+export function SetIdentity(arg1, arg2) {
+  return window['go']['main']['App']['SetIdentity'](arg1, arg2);
 }
 
 export function StageFile(arg1, arg2) {
   return window['go']['main']['App']['StageFile'](arg1, arg2);
 }
 
+export function StageHunk(arg1, arg2, arg3) {
+  return window['go']['main']['App']['StageHunk'](arg1, arg2, arg3);
+}
+
+export function StageLines(arg1, arg2, arg3) {
+  return window['go']['main']['App']['StageLines'](arg1, arg2, arg3);
+}
+
+export function StashApply(arg1, arg2) {
+  return window['go']['main']['App']['StashApply'](arg1, arg2);
+}
+
+export function StashDrop(arg1, arg2) {
+  return window['go']['main']['App']['StashDrop'](arg1, arg2);
+}
+
+export function StashList(arg1) {
+  return window['go']['main']['App']['StashList'](arg1);
+}
+
+export function StashPop(arg1, arg2) {
+  return window['go']['main']['App']['StashPop'](arg1, arg2);
+}
+
+export function StashSave(arg1, arg2) {
+  return window['go']['main']['App']['StashSave'](arg1, arg2);
+}
+
+export function StashShow(arg1, arg2) {
+  return window['go']['main']['App']['StashShow'](arg1, arg2);
+}
+
+export function UndoReplace(arg1) {
+  return window['go']['main']['App']['UndoReplace'](arg1);
+}
+
 export function UnstageFile(arg1, arg2) {
   return window['go']['main']['App']['UnstageFile'](arg1, arg2);
 }
+
+export function UnstageHunk(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UnstageHunk'](arg1, arg2, arg3);
+}
+
+export function UnstageLines(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UnstageLines'](arg1, arg2, arg3);
+}
+
+export function UnwatchProject(arg1) {
+  return window['go']['main']['App']['UnwatchProject'](arg1);
+}
+
+export function VerifyCommits(arg1, arg2) {
+  return window['go']['main']['App']['VerifyCommits'](arg1, arg2);
+}
@@ -45,50 +45,68 @@ export namespace db {
 
 export namespace service {
 	
-	export class BranchInfo {
-	    name: string;
-	    isRemote: boolean;
-	    isHead: boolean;
+	export class BlameCommit {
+	    hash: string;
+	    author: string;
+	    authorEmail: string;
+	    // Go type: time
+	    date: any;
+	    summary: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new BranchInfo(source);
+	        return new BlameCommit(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.name = source["name"];
-	        this.isRemote = source["isRemote"];
-	        this.isHead = source["isHead"];
+	        this.hash = source["hash"];
+	        this.author = source["author"];
+	        this.authorEmail = source["authorEmail"];
+	        this.date = this.convertValues(source["date"], null);
+	        this.summary = source["summary"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class CommitFilter {
-	    branch: string;
-	    startHash: string;
-	    limit: number;
-	    offset: number;
-	    offsetHash: string;
+	export class BlameLine {
+	    line: number;
+	    content: string;
+	    hash: string;
 	    author: string;
-	    searchQuery: string;
 	    // Go type: time
-	    startDate: any;
-	    // Go type: time
-	    endDate: any;
+	    date: any;
+	    origLine: number;
+	    uncommitted: boolean;
 	
 	    static createFrom(source: any = {}) {
-	        return new CommitFilter(source);
+	        return new BlameLine(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.branch = source["branch"];
-	        this.startHash = source["startHash"];
-	        this.limit = source["limit"];
-	        this.offset = source["offset"];
-	        this.offsetHash = source["offsetHash"];
+	        this.line = source["line"];
+	        this.content = source["content"];
+	        this.hash = source["hash"];
 	        this.author = source["author"];
-	        this.searchQuery = source["searchQuery"];
-	        this.startDate = this.convertValues(source["startDate"], null);
-	        this.endDate = this.convertValues(source["endDate"], null);
+	        this.date = this.convertValues(source["date"], null);
+	        this.origLine = source["origLine"];
+	        this.uncommitted = source["uncommitted"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -109,29 +127,67 @@ export namespace service {
 		    return a;
 		}
 	}
-	export class CommitInfo {
-	    hash: string;
-	    message: string;
-	    author: string;
-	    authorEmail: string;
+	export class BlameResult {
+	    path: string;
+	    rev: string;
+	    lines: BlameLine[];
+	    commits: {[key: string]: BlameCommit};
+	
+	    static createFrom(source: any = {}) {
+	        return new BlameResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.rev = source["rev"];
+	        this.lines = this.convertValues(source["lines"], BlameLine);
+	        this.commits = this.convertValues(source["commits"], BlameCommit, true);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BranchInfo {
+	    name: string;
+	    isRemote: boolean;
+	    isHead: boolean;
+	    upstream: string;
+	    ahead: number;
+	    behind: number;
 	    // Go type: time
-	    date: any;
-	    parentHashes: string[];
-	    hasMore: boolean;
+	    lastCommitDate: any;
+	    lastCommitAuthor: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new CommitInfo(source);
+	        return new BranchInfo(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.hash = source["hash"];
-	        this.message = source["message"];
-	        this.author = source["author"];
-	        this.authorEmail = source["authorEmail"];
-	        this.date = this.convertValues(source["date"], null);
-	        this.parentHashes = source["parentHashes"];
-	        this.hasMore = source["hasMore"];
+	        this.name = source["name"];
+	        this.isRemote = source["isRemote"];
+	        this.isHead = source["isHead"];
+	        this.upstream = source["upstream"];
+	        this.ahead = source["ahead"];
+	        this.behind = source["behind"];
+	        this.lastCommitDate = this.convertValues(source["lastCommitDate"], null);
+	        this.lastCommitAuthor = source["lastCommitAuthor"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -152,38 +208,46 @@ export namespace service {
 		    return a;
 		}
 	}
-	export class DiffStats {
-	    added: number;
-	    deleted: number;
-	    modified: number;
+	export class DiffLine {
+	    type: string;
+	    content: string;
+	    oldLine: number;
+	    newLine: number;
+	    noNewline: boolean;
 	
 	    static createFrom(source: any = {}) {
-	        return new DiffStats(source);
+	        return new DiffLine(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.added = source["added"];
-	        this.deleted = source["deleted"];
-	        this.modified = source["modified"];
+	        this.type = source["type"];
+	        this.content = source["content"];
+	        this.oldLine = source["oldLine"];
+	        this.newLine = source["newLine"];
+	        this.noNewline = source["noNewline"];
 	    }
 	}
-	export class EditorConfig {
-	    // Go type: struct { Theme string "json:\"theme\" mapstructure:\"theme\""; FontSize int "json:\"fontSize\" mapstructure:\"fontSize\""; TabSize int "json:\"tabSize\" mapstructure:\"tabSize\""; WordWrap bool "json:\"wordWrap\" mapstructure:\"wordWrap\""; LineNumbers bool "json:\"lineNumbers\" mapstructure:\"lineNumbers\""; RelativeLines bool "json:\"relativeLines\" mapstructure:\"relativeLines\""; Minimap bool "json:\"minimap\" mapstructure:\"minimap\""; StickyScroll bool "json:\"stickyScroll\" mapstructure:\"stickyScroll\""; Vim struct { Enabled bool "json:\"enabled\" mapstructure:\"enabled\""; DefaultMode string "json:\"defaultMode\" mapstructure:\"defaultMode\"" } "json:\"vim\" mapstructure:\"vim\"" }
-	    editor: any;
-	    // Go type: struct { DefaultShell string "json:\"defaultShell\" mapstructure:\"defaultShell\""; FontSize int "json:\"fontSize\" mapstructure:\"fontSize\""; FontFamily string "json:\"fontFamily\" mapstructure:\"fontFamily\""; Theme struct { Background string "json:\"background\" mapstructure:\"background\""; Foreground string "json:\"foreground\" mapstructure:\"foreground\""; Cursor string "json:\"cursor\" mapstructure:\"cursor\""; SelectionBackground string "json:\"selectionBackground\" mapstructure:\"selectionBackground\""; SelectionForeground string "json:\"selectionForeground\" mapstructure:\"selectionForeground\"" } "json:\"theme\" mapstructure:\"theme\"" }
-	    terminal: any;
-	    keyboard: struct { CustomBindings map[string]service.;
+	export class Hunk {
+	    oldStart: number;
+	    oldLines: number;
+	    newStart: number;
+	    newLines: number;
+	    header: string;
+	    lines: DiffLine[];
 	
 	    static createFrom(source: any = {}) {
-	        return new EditorConfig(source);
+	        return new Hunk(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.editor = this.convertValues(source["editor"], Object);
-	        this.terminal = this.convertValues(source["terminal"], Object);
-	        this.keyboard = this.convertValues(source["keyboard"], Object);
+	        this.oldStart = source["oldStart"];
+	        this.oldLines = source["oldLines"];
+	        this.newStart = source["newStart"];
+	        this.newLines = source["newLines"];
+	        this.header = source["header"];
+	        this.lines = this.convertValues(source["lines"], DiffLine);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -206,7 +270,10 @@ export namespace service {
 	}
 	export class FileDiff {
 	    path: string;
+	    oldPath: string;
+	    status: string;
 	    content: string;
+	    hunks: Hunk[];
 	    stats: DiffStats;
 	    isBinary: boolean;
 	
@@ -217,7 +284,10 @@ export namespace service {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.path = source["path"];
+	        this.oldPath = source["oldPath"];
+	        this.status = source["status"];
 	        this.content = source["content"];
+	        this.hunks = this.convertValues(source["hunks"], Hunk);
 	        this.stats = this.convertValues(source["stats"], DiffStats);
 	        this.isBinary = source["isBinary"];
 	    }
@@ -240,29 +310,40 @@ export namespace service {
 		    return a;
 		}
 	}
-	export class FileNode {
-	    name: string;
+	export class DiffStats {
+	    added: number;
+	    deleted: number;
+	    modified: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiffStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.added = source["added"];
+	        this.deleted = source["deleted"];
+	        this.modified = source["modified"];
+	    }
+	}
+	export class CommitFile {
 	    path: string;
-	    type: string;
-	    size?: number;
-	    // Go type: time
-	    lastModified: any;
-	    children?: FileNode[];
-	    isLoaded: boolean;
+	    oldPath: string;
+	    status: string;
+	    stats: DiffStats;
+	    isBinary: boolean;
 	
 	    static createFrom(source: any = {}) {
-	        return new FileNode(source);
+	        return new CommitFile(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.name = source["name"];
 	        this.path = source["path"];
-	        this.type = source["type"];
-	        this.size = source["size"];
-	        this.lastModified = this.convertValues(source["lastModified"], null);
-	        this.children = this.convertValues(source["children"], FileNode);
-	        this.isLoaded = source["isLoaded"];
+	        this.oldPath = source["oldPath"];
+	        this.status = source["status"];
+	        this.stats = this.convertValues(source["stats"], DiffStats);
+	        this.isBinary = source["isBinary"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -283,34 +364,1099 @@ export namespace service {
 		    return a;
 		}
 	}
-	export class FileStatus {
-	    file: string;
-	    status: string;
-	    staged: boolean;
+	export class CommitDetails {
+	    hash: string;
+	    message: string;
+	    author: string;
+	    authorEmail: string;
+	    // Go type: time
+	    authorDate: any;
+	    committer: string;
+	    committerEmail: string;
+	    // Go type: time
+	    commitDate: any;
+	    parentHashes: string[];
+	    comparedTo: string;
+	    files: CommitFile[];
+	    diffs: FileDiff[];
 	
 	    static createFrom(source: any = {}) {
-	        return new FileStatus(source);
+	        return new CommitDetails(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.file = source["file"];
-	        this.status = source["status"];
-	        this.staged = source["staged"];
+	        this.hash = source["hash"];
+	        this.message = source["message"];
+	        this.author = source["author"];
+	        this.authorEmail = source["authorEmail"];
+	        this.authorDate = this.convertValues(source["authorDate"], null);
+	        this.committer = source["committer"];
+	        this.committerEmail = source["committerEmail"];
+	        this.commitDate = this.convertValues(source["commitDate"], null);
+	        this.parentHashes = source["parentHashes"];
+	        this.comparedTo = source["comparedTo"];
+	        this.files = this.convertValues(source["files"], CommitFile);
+	        this.diffs = this.convertValues(source["diffs"], FileDiff);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class KeyBinding {
-	    key: string;
-	    modifiers: string[];
+	export class DiffOptions {
+	    contextLines?: number;
+	    worktreeRenames: boolean;
 	
 	    static createFrom(source: any = {}) {
-	        return new KeyBinding(source);
+	        return new DiffOptions(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.key = source["key"];
-	        this.modifiers = source["modifiers"];
+	        this.contextLines = source["contextLines"];
+	        this.worktreeRenames = source["worktreeRenames"];
+	    }
+	}
+	export class CommitDetailsOptions {
+	    parent: number;
+	    diff: DiffOptions;
+	
+	    static createFrom(source: any = {}) {
+	        return new CommitDetailsOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.parent = source["parent"];
+	        this.diff = this.convertValues(source["diff"], DiffOptions);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class CommitFilter {
+	    branch: string;
+	    tag: string;
+	    startHash: string;
+	    limit: number;
+	    offset: number;
+	    offsetHash: string;
+	    author: string;
+	    searchQuery: string;
+	    // Go type: time
+	    startDate: any;
+	    // Go type: time
+	    endDate: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new CommitFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.branch = source["branch"];
+	        this.tag = source["tag"];
+	        this.startHash = source["startHash"];
+	        this.limit = source["limit"];
+	        this.offset = source["offset"];
+	        this.offsetHash = source["offsetHash"];
+	        this.author = source["author"];
+	        this.searchQuery = source["searchQuery"];
+	        this.startDate = this.convertValues(source["startDate"], null);
+	        this.endDate = this.convertValues(source["endDate"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GraphLane {
+	    hash: string;
+	    color: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GraphLane(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hash = source["hash"];
+	        this.color = source["color"];
+	    }
+	}
+	export class GraphDecoration {
+	    name: string;
+	    kind: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GraphDecoration(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.kind = source["kind"];
+	    }
+	}
+	export class GraphEdge {
+	    kind: string;
+	    from: number;
+	    to: number;
+	    color: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GraphEdge(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.color = source["color"];
+	    }
+	}
+	export class GraphRow {
+	    hash: string;
+	    column: number;
+	    color: number;
+	    width: number;
+	    edges: GraphEdge[];
+	    decorations: GraphDecoration[];
+	
+	    static createFrom(source: any = {}) {
+	        return new GraphRow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hash = source["hash"];
+	        this.column = source["column"];
+	        this.color = source["color"];
+	        this.width = source["width"];
+	        this.edges = this.convertValues(source["edges"], GraphEdge);
+	        this.decorations = this.convertValues(source["decorations"], GraphDecoration);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CommitInfo {
+	    hash: string;
+	    message: string;
+	    author: string;
+	    authorEmail: string;
+	    // Go type: time
+	    date: any;
+	    parentHashes: string[];
+	    path: string;
+	    signed: boolean;
+	    verified?: boolean;
+	    hasMore: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CommitInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hash = source["hash"];
+	        this.message = source["message"];
+	        this.author = source["author"];
+	        this.authorEmail = source["authorEmail"];
+	        this.date = this.convertValues(source["date"], null);
+	        this.parentHashes = source["parentHashes"];
+	        this.path = source["path"];
+	        this.signed = source["signed"];
+	        this.verified = source["verified"];
+	        this.hasMore = source["hasMore"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CommitGraph {
+	    commits: CommitInfo[];
+	    rows: GraphRow[];
+	    lanes: GraphLane[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CommitGraph(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.commits = this.convertValues(source["commits"], CommitInfo);
+	        this.rows = this.convertValues(source["rows"], GraphRow);
+	        this.lanes = this.convertValues(source["lanes"], GraphLane);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class ConflictFile {
+	    path: string;
+	    base: string;
+	    ours: string;
+	    theirs: string;
+	    hasBase: boolean;
+	    hasOurs: boolean;
+	    hasTheirs: boolean;
+	    isBinary: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConflictFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.base = source["base"];
+	        this.ours = source["ours"];
+	        this.theirs = source["theirs"];
+	        this.hasBase = source["hasBase"];
+	        this.hasOurs = source["hasOurs"];
+	        this.hasTheirs = source["hasTheirs"];
+	        this.isBinary = source["isBinary"];
+	    }
+	}
+	export class ConflictResolution {
+	    choice: string;
+	    content: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConflictResolution(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.choice = source["choice"];
+	        this.content = source["content"];
+	    }
+	}
+	
+	
+	
+	export class EditorConfig {
+	    // Go type: struct { Theme string "json:\"theme\" mapstructure:\"theme\""; FontSize int "json:\"fontSize\" mapstructure:\"fontSize\""; TabSize int "json:\"tabSize\" mapstructure:\"tabSize\""; WordWrap bool "json:\"wordWrap\" mapstructure:\"wordWrap\""; LineNumbers bool "json:\"lineNumbers\" mapstructure:\"lineNumbers\""; RelativeLines bool "json:\"relativeLines\" mapstructure:\"relativeLines\""; Minimap bool "json:\"minimap\" mapstructure:\"minimap\""; StickyScroll bool "json:\"stickyScroll\" mapstructure:\"stickyScroll\""; Vim struct { Enabled bool "json:\"enabled\" mapstructure:\"enabled\""; DefaultMode string "json:\"defaultMode\" mapstructure:\"defaultMode\"" } "json:\"vim\" mapstructure:\"vim\"" }
+	    editor: any;
+	    // Go type: struct { DefaultShell string "json:\"defaultShell\" mapstructure:\"defaultShell\""; FontSize int "json:\"fontSize\" mapstructure:\"fontSize\""; FontFamily string "json:\"fontFamily\" mapstructure:\"fontFamily\""; Theme struct { Background string "json:\"background\" mapstructure:\"background\""; Foreground string "json:\"foreground\" mapstructure:\"foreground\""; Cursor string "json:\"cursor\" mapstructure:\"cursor\""; SelectionBackground string "json:\"selectionBackground\" mapstructure:\"selectionBackground\""; SelectionForeground string "json:\"selectionForeground\" mapstructure:\"selectionForeground\"" } "json:\"theme\" mapstructure:\"theme\"" }
+	    terminal: any;
+	    keyboard: struct { CustomBindings map[string]service.;
+	
+	    static createFrom(source: any = {}) {
+	        return new EditorConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.editor = this.convertValues(source["editor"], Object);
+	        this.terminal = this.convertValues(source["terminal"], Object);
+	        this.keyboard = this.convertValues(source["keyboard"], Object);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class FileNode {
+	    name: string;
+	    path: string;
+	    type: string;
+	    size?: number;
+	    // Go type: time
+	    lastModified: any;
+	    children?: FileNode[];
+	    isLoaded: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileNode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.type = source["type"];
+	        this.size = source["size"];
+	        this.lastModified = this.convertValues(source["lastModified"], null);
+	        this.children = this.convertValues(source["children"], FileNode);
+	        this.isLoaded = source["isLoaded"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FileStatus {
+	    file: string;
+	    status: string;
+	    staged: boolean;
+	    origFile: string;
+	    similarity: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.status = source["status"];
+	        this.staged = source["staged"];
+	        this.origFile = source["origFile"];
+	        this.similarity = source["similarity"];
+	    }
+	}
+	export class GitIdentity {
+	    name: string;
+	    email: string;
+	    signingKey: string;
+	    signingFormat: string;
+	    signCommits: boolean;
+	    scope: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GitIdentity(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.email = source["email"];
+	        this.signingKey = source["signingKey"];
+	        this.signingFormat = source["signingFormat"];
+	        this.signCommits = source["signCommits"];
+	        this.scope = source["scope"];
+	    }
+	}
+	
+	
+	
+	
+	
+	export class KeyBinding {
+	    key: string;
+	    modifiers: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new KeyBinding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.modifiers = source["modifiers"];
+	    }
+	}
+	export class LineRange {
+	    side: string;
+	    start: number;
+	    end: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LineRange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.side = source["side"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	    }
+	}
+	export class MergeOptions {
+	    message: string;
+	    fastForward: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MergeOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.message = source["message"];
+	        this.fastForward = source["fastForward"];
+	    }
+	}
+	export class MergeResult {
+	    status: string;
+	    head: string;
+	    conflicts: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MergeResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.head = source["head"];
+	        this.conflicts = source["conflicts"];
+	    }
+	}
+	export class PullResult {
+	    status: string;
+	    head: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PullResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.head = source["head"];
+	    }
+	}
+	export class RebaseStep {
+	    action: string;
+	    hash: string;
+	    subject: string;
+	    message: string;
+	    author: string;
+	    // Go type: time
+	    date: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new RebaseStep(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.action = source["action"];
+	        this.hash = source["hash"];
+	        this.subject = source["subject"];
+	        this.message = source["message"];
+	        this.author = source["author"];
+	        this.date = this.convertValues(source["date"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RebasePlan {
+	    onto: string;
+	    branch: string;
+	    head: string;
+	    steps: RebaseStep[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RebasePlan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.onto = source["onto"];
+	        this.branch = source["branch"];
+	        this.head = source["head"];
+	        this.steps = this.convertValues(source["steps"], RebaseStep);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RebaseResult {
+	    status: string;
+	    head: string;
+	    conflicts: string[];
+	    stopped?: RebaseStep;
+	
+	    static createFrom(source: any = {}) {
+	        return new RebaseResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.head = source["head"];
+	        this.conflicts = source["conflicts"];
+	        this.stopped = this.convertValues(source["stopped"], RebaseStep);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RebaseState {
+	    onto: string;
+	    branch: string;
+	    origHead: string;
+	    done: RebaseStep[];
+	    todo: RebaseStep[];
+	    stopped?: RebaseStep;
+	    conflicts: string[];
+	    foreign: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RebaseState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.onto = source["onto"];
+	        this.branch = source["branch"];
+	        this.origHead = source["origHead"];
+	        this.done = this.convertValues(source["done"], RebaseStep);
+	        this.todo = this.convertValues(source["todo"], RebaseStep);
+	        this.stopped = this.convertValues(source["stopped"], RebaseStep);
+	        this.conflicts = source["conflicts"];
+	        this.foreign = source["foreign"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class RemoteAuth {
+	    method: string;
+	    username: string;
+	    password: string;
+	    keyPath: string;
+	    keyPassphrase: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RemoteAuth(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.method = source["method"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.keyPath = source["keyPath"];
+	        this.keyPassphrase = source["keyPassphrase"];
+	    }
+	}
+	export class RemoteOptions {
+	    remote: string;
+	    branch: string;
+	    auth: RemoteAuth;
+	    prune: boolean;
+	    pullMode: string;
+	    force: boolean;
+	    setUpstream: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RemoteOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.remote = source["remote"];
+	        this.branch = source["branch"];
+	        this.auth = this.convertValues(source["auth"], RemoteAuth);
+	        this.prune = source["prune"];
+	        this.pullMode = source["pullMode"];
+	        this.force = source["force"];
+	        this.setUpstream = source["setUpstream"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ReplaceFile {
+	    path: string;
+	    hash: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReplaceFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.hash = source["hash"];
+	    }
+	}
+	export class ReplaceFilePreview {
+	    path: string;
+	    hash: string;
+	    matches: number;
+	    diff?: FileDiff;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReplaceFilePreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.hash = source["hash"];
+	        this.matches = source["matches"];
+	        this.diff = this.convertValues(source["diff"], FileDiff);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ReplacePreview {
+	    files: ReplaceFilePreview[];
+	    matches: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReplacePreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.files = this.convertValues(source["files"], ReplaceFilePreview);
+	        this.matches = source["matches"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ReplaceResult {
+	    operationId: number;
+	    files: string[];
+	    skipped: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ReplaceResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.operationId = source["operationId"];
+	        this.files = source["files"];
+	        this.skipped = source["skipped"];
+	    }
+	}
+	export class SearchOptions {
+	    regex: boolean;
+	    caseSensitive: boolean;
+	    wholeWord: boolean;
+	    include: string[];
+	    exclude: string[];
+	    contextLines: number;
+	    maxResults: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.regex = source["regex"];
+	        this.caseSensitive = source["caseSensitive"];
+	        this.wholeWord = source["wholeWord"];
+	        this.include = source["include"];
+	        this.exclude = source["exclude"];
+	        this.contextLines = source["contextLines"];
+	        this.maxResults = source["maxResults"];
+	    }
+	}
+	export class SearchSummary {
+	    searchId: number;
+	    matches: number;
+	    files: number;
+	    searched: number;
+	    truncated: boolean;
+	    cancelled: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.searchId = source["searchId"];
+	        this.matches = source["matches"];
+	        this.files = source["files"];
+	        this.searched = source["searched"];
+	        this.truncated = source["truncated"];
+	        this.cancelled = source["cancelled"];
+	    }
+	}
+	export class StashEntry {
+	    index: number;
+	    hash: string;
+	    message: string;
+	    branch: string;
+	    // Go type: time
+	    date: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new StashEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.hash = source["hash"];
+	        this.message = source["message"];
+	        this.branch = source["branch"];
+	        this.date = this.convertValues(source["date"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class StashOptions {
+	    message: string;
+	    includeUntracked: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new StashOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.message = source["message"];
+	        this.includeUntracked = source["includeUntracked"];
+	    }
+	}
+	export class StatusOptions {
+	    worktreeRenames: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new StatusOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.worktreeRenames = source["worktreeRenames"];
+	    }
+	}
+	export class SubmoduleInfo {
+	    name: string;
+	    path: string;
+	    url: string;
+	    branch: string;
+	    commit: string;
+	    head: string;
+	    initialized: boolean;
+	    modified: boolean;
+	    dirty: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SubmoduleInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.url = source["url"];
+	        this.branch = source["branch"];
+	        this.commit = source["commit"];
+	        this.head = source["head"];
+	        this.initialized = source["initialized"];
+	        this.modified = source["modified"];
+	        this.dirty = source["dirty"];
+	    }
+	}
+	export class TagInfo {
+	    name: string;
+	    hash: string;
+	    annotated: boolean;
+	    message: string;
+	    tagger: string;
+	    taggerEmail: string;
+	    // Go type: time
+	    date: any;
+	    signed: boolean;
+	    verified: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TagInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.hash = source["hash"];
+	        this.annotated = source["annotated"];
+	        this.message = source["message"];
+	        this.tagger = source["tagger"];
+	        this.taggerEmail = source["taggerEmail"];
+	        this.date = this.convertValues(source["date"], null);
+	        this.signed = source["signed"];
+	        this.verified = source["verified"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class WorktreeInfo {
+	    path: string;
+	    name: string;
+	    branch: string;
+	    head: string;
+	    isMain: boolean;
+	    isCurrent: boolean;
+	    locked: boolean;
+	    prunable: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new WorktreeInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.name = source["name"];
+	        this.branch = source["branch"];
+	        this.head = source["head"];
+	        this.isMain = source["isMain"];
+	        this.isCurrent = source["isCurrent"];
+	        this.locked = source["locked"];
+	        this.prunable = source["prunable"];
 	    }
 	}
 
@@ -1,6 +1,7 @@
 import { writable, get } from 'svelte/store';
 import type { service } from '@/lib/wailsjs/go/models';
-import { GetProjectFiles, GetFileContent, SaveFile, CreateFile, CreateDirectory, RenameFile, DeleteFile, LoadDirectoryContents } from '@/lib/wailsjs/go/main/App';
+import { GetProjectFiles, GetFileContent, SaveFile, CreateFile, CreateDirectory, RenameFile, DeleteFile, LoadDirectoryContents, UnwatchProject, RecordFileOpen } from '@/lib/wailsjs/go/main/App';
+import { EventsOn } from '@/lib/wailsjs/runtime/runtime';
 import { getLanguageFromPath } from '@/lib/utils/languageMap';
 
 type FileNode = service.FileNode;
@@ -15,6 +16,17 @@ interface OpenFile {
     stats?: DiffStats;
 }
 
+// Changes to the open project, received as a files:changed event.
+// Directories created, deleted or renamed are reported once, not file by file.
+interface FileChangeEvent {
+    projectPath: string;
+    created: string[];
+    modified: string[];
+    deleted: string[];
+    renamed: { from: string; to: string }[];
+    rescanned: boolean;
+}
+
 interface FileState {
     fileTree: FileNode[] | null;
     activeFilePath: string | null;
@@ -52,7 +64,52 @@ function createFileStore() {
         localStorage.setItem('fileState', JSON.stringify(serializedState));
     });
 
-    return {
+    // The backend watches the open project and patches its cached tree, so reloading it is cheap
+    EventsOn('files:changed', async (event: FileChangeEvent) => {
+        const state = get({ subscribe });
+        if (event.projectPath !== state.currentProjectPath) return;
+
+        try {
+            const rootNode = await GetProjectFiles(event.projectPath);
+            update(state => ({ ...state, fileTree: rootNode.children || [] }));
+        } catch (err) {
+            update(state => ({
+                ...state,
+                error: err instanceof Error ? err.message : 'Failed to reload project files'
+            }));
+        }
+
+        // Open files follow their renames, and reload when changed on disk without local edits
+        update(state => {
+            const openFiles = new Map(state.openFiles);
+            let activeFilePath = state.activeFilePath;
+            for (const { from, to } of event.renamed) {
+                openFiles.forEach((file, path) => {
+                    if (file.type !== 'file' || (path !== from && !path.startsWith(from + '/'))) return;
+                    const newPath = to + path.slice(from.length);
+                    openFiles.delete(path);
+                    openFiles.set(newPath, { ...file, path: newPath });
+                    if (activeFilePath === path) activeFilePath = newPath;
+                });
+            }
+            return { ...state, openFiles, activeFilePath };
+        });
+
+        for (const path of event.modified) {
+            const file = get({ subscribe }).openFiles.get(path);
+            if (!file || file.type !== 'file' || file.isDirty) continue;
+            try {
+                const content = await GetFileContent(path);
+                if (content !== file.content) {
+                    store.updateFileContent(path, content, false);
+                }
+            } catch {
+                // Gone again since the event, the next one will tell
+            }
+        }
+    });
+
+    const store = {
         subscribe,
         
         // Clear all state and localStorage
@@ -74,6 +131,10 @@ function createFileStore() {
             
             // If changing projects, only keep open files from the new project
             if (projectPath !== state.currentProjectPath) {
+                if (state.currentProjectPath) {
+                    UnwatchProject(state.currentProjectPath);
+                }
+
                 update(state => {
                     const newOpenFiles = new Map();
                     
@@ -149,6 +210,13 @@ function createFileStore() {
                         activeFilePath: path
                     };
                 });
+
+                // The open history ranks the file finder
+                if (state.currentProjectPath) {
+                    RecordFileOpen(state.currentProjectPath, path).catch(err => {
+                        console.error('Failed to record file open:', err);
+                    });
+                }
             } catch (err) {
                 update(state => ({
                     ...state,
@@ -530,6 +598,8 @@ function createFileStore() {
             set(initialState);
         }
     };
+
+    return store;
 }
 
 export const fileStore = createFileStore();
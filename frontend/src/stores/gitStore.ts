@@ -2,29 +2,62 @@ import { writable, get } from 'svelte/store';
 import { 
     IsGitRepository, 
     InitGitRepository, 
-    GetGitStatus, 
+    GetGitStatusWithOptions, 
     StageFile, 
     UnstageFile, 
     DiscardChanges, 
     Commit, 
     ListBranches, 
     GetCurrentBranch,
+    CheckoutBranch,
+    StashSave,
+    AmendCommit,
+    Reset,
+    RevertCommit,
+    CherryPick,
+    RebasePlan,
+    RebaseExecute,
+    RebaseContinue,
+    RebaseAbort,
+    GetRebaseState,
+    GetIdentity,
+    SetIdentity,
+    ListTags,
+    CreateTag,
+    DeleteTag,
+    PushTag,
+    ListSubmodules,
+    ListWorktrees,
+    AddWorktree,
+    RemoveWorktree,
+    StashPop,
     ListCommits,
     ListCommitsAfter,
     ListCommitsByBranch,
     ListCommitsByAuthor,
+    FileHistory,
+    CommitGraph,
     SearchCommits,
     GetHeadCommit,
-    GetFileDiff
+    GetCommitDetailsWithOptions,
+    GetFileDiffWithOptions
 } from '@/lib/wailsjs/go/main/App';
+import { EventsOn } from '@/lib/wailsjs/runtime/runtime';
 import { fileStore } from '@/stores/fileStore';
-import type { service } from '@/lib/wailsjs/go/models';
+import { service } from '@/lib/wailsjs/go/models';
+
+// A line printed by a git hook, received as a git:hook-output event
+interface HookOutputLine {
+    hook: string;
+    line: string;
+}
 
 interface GitState {
     gitStatus: service.FileStatus[];
     stagedExpanded: boolean;
     changesExpanded: boolean;
     hierarchicalView: boolean;
+    worktreeRenames: boolean;
     isRepository: boolean;
     isLoading: boolean;
     isQuickRefreshing: boolean;
@@ -32,10 +65,18 @@ interface GitState {
     error: string | null;
     branches: service.BranchInfo[];
     currentBranch: string | null;
+    tags: service.TagInfo[];
+    submodules: service.SubmoduleInfo[];
+    worktrees: service.WorktreeInfo[];
+    rebase: service.RebaseState | null;
     commits: service.CommitInfo[];
     commitsLoading: boolean;
     commitsError: string | null;
+    graphRows: service.GraphRow[];
+    graphLanes: service.GraphLane[];
     HEAD: service.CommitInfo | null;
+    identity: service.GitIdentity | null;
+    hookOutput: HookOutputLine[];
     initialized: boolean;
 }
 
@@ -45,6 +86,7 @@ function createGitStore() {
         stagedExpanded: true,
         changesExpanded: true,
         hierarchicalView: false,
+        worktreeRenames: false,
         isRepository: false,
         isLoading: true,
         isQuickRefreshing: false,
@@ -52,14 +94,36 @@ function createGitStore() {
         error: null,
         branches: [],
         currentBranch: null,
+        tags: [],
+        submodules: [],
+        worktrees: [],
+        rebase: null,
         commits: [],
         commitsLoading: false,
         commitsError: null,
+        graphRows: [],
+        graphLanes: [],
         HEAD: null,
+        identity: null,
+        hookOutput: [],
         initialized: false
     });
 
-    return {
+    // Hooks run while committing, their output is kept until the next commit
+    EventsOn('git:hook-output', (event: HookOutputLine & { projectPath: string }) => {
+        update(state => ({ ...state, hookOutput: [...state.hookOutput, { hook: event.hook, line: event.line }] }));
+    });
+
+    // The backend watches the open project and tells when its status may have changed
+    EventsOn('git:status-changed', (event: { projectPath: string }) => {
+        const state = get({ subscribe });
+        if (event.projectPath !== get(fileStore).currentProjectPath || !state.isRepository) {
+            return;
+        }
+        store.quickRefresh();
+    });
+
+    const store = {
         subscribe,
 
         async checkRepository() {
@@ -85,6 +149,7 @@ function createGitStore() {
                     await Promise.all([
                         this.refreshStatus(),
                         this.refreshBranches(),
+                        this.refreshRebase(),
                         this.loadInitialCommits()
                     ]);
                 }
@@ -176,7 +241,7 @@ function createGitStore() {
                     update(state => ({ ...state, isLoading: true, error: null }));
                 }
 
-                const status = await GetGitStatus(projectPath);
+                const status = await GetGitStatusWithOptions(projectPath, { worktreeRenames: get({ subscribe }).worktreeRenames });
 
                 // Only update once we have the new data
                 update(state => ({
@@ -228,7 +293,7 @@ function createGitStore() {
                     IsGitRepository(projectPath),
                     ListBranches(projectPath),
                     GetCurrentBranch(projectPath),
-                    GetGitStatus(projectPath)
+                    GetGitStatusWithOptions(projectPath, { worktreeRenames: get({ subscribe }).worktreeRenames })
                 ]);
 
                 update(state => ({ 
@@ -264,6 +329,12 @@ function createGitStore() {
             hierarchicalView: !state.hierarchicalView
         })),
 
+        // Whether a missing file and a similar untracked one show as a rename before staging
+        async toggleWorktreeRenames() {
+            update(state => ({ ...state, worktreeRenames: !state.worktreeRenames }));
+            await this.refreshStatus(true);
+        },
+
         setGitStatus: (status: service.FileStatus[]) => update(state => ({
             ...state,
             gitStatus: status
@@ -276,6 +347,8 @@ function createGitStore() {
                     return;
                 }
 
+                const renamed = get({ subscribe }).gitStatus.find(item => item.file === file && !item.staged && item.origFile && item.status === 'R');
+
                 // Add to loading set but keep existing status
                 update(state => ({
                     ...state,
@@ -299,6 +372,12 @@ function createGitStore() {
                 });
 
                 await StageFile(projectPath, file);
+
+                // Staging a rename also stages the removal of the original
+                if (renamed) {
+                    await StageFile(projectPath, renamed.origFile);
+                    await this.refreshStatus(true);
+                }
                 // No need to refresh if the operation succeeded
             } catch (error) {
                 update(state => ({
@@ -328,6 +407,8 @@ function createGitStore() {
                     loadingFiles: new Set([...state.loadingFiles, file])
                 }));
 
+                const renamed = get({ subscribe }).gitStatus.find(item => item.file === file && item.staged && item.origFile && item.status === 'R');
+
                 // Optimistically update the UI by moving the file to unstaged
                 update(state => ({
                     ...state,
@@ -339,6 +420,12 @@ function createGitStore() {
                 }));
 
                 await UnstageFile(projectPath, file);
+
+                // Unstaging a rename also restores the original in the index
+                if (renamed) {
+                    await UnstageFile(projectPath, renamed.origFile);
+                    await this.refreshStatus(true);
+                }
                 // No need to refresh if the operation succeeded
             } catch (error) {
                 update(state => ({
@@ -397,12 +484,174 @@ function createGitStore() {
                 return;
             }
 
+            update(state => ({ ...state, hookOutput: [], error: null }));
             try {
                 await Commit(projectPath, message);
                 await this.getCommits(); // This will also update HEAD
                 await this.refreshStatus();
             } catch (error) {
                 console.error('Failed to commit:', error);
+                // A rejecting hook's output is part of the error
+                update(state => ({ ...state, error: error instanceof Error ? error.message : String(error) }));
+            }
+        },
+
+        async amendCommit(message = ''): Promise<void> {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            update(state => ({ ...state, hookOutput: [], error: null }));
+            try {
+                await AmendCommit(projectPath, message);
+                await this.getCommits();
+                await this.refreshStatus();
+            } catch (error) {
+                console.error('Failed to amend commit:', error);
+                update(state => ({ ...state, error: error instanceof Error ? error.message : String(error) }));
+            }
+        },
+
+        async loadIdentity() {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            try {
+                const identity = await GetIdentity(projectPath);
+                update(state => ({ ...state, identity }));
+            } catch (error) {
+                console.error('Failed to load git identity:', error);
+            }
+        },
+
+        async setIdentity(identity: service.GitIdentity) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            try {
+                await SetIdentity(projectPath, identity);
+                await this.loadIdentity();
+            } catch (error) {
+                update(state => ({ ...state, error: error instanceof Error ? error.message : 'Failed to set git identity' }));
+            }
+        },
+
+        async reset(mode: 'soft' | 'mixed' | 'hard', target = '') {
+            await this.rewriteHistory(projectPath => Reset(projectPath, mode, target), 'reset');
+        },
+
+        async revertCommit(hash: string) {
+            await this.rewriteHistory(projectPath => RevertCommit(projectPath, hash), 'revert commit');
+        },
+
+        async cherryPick(hash: string) {
+            await this.rewriteHistory(projectPath => CherryPick(projectPath, hash), 'cherry-pick');
+        },
+
+        // Runs an operation that moves HEAD, then reloads the commits and status.
+        // Conflicts are reported as errors and left in the status to resolve.
+        async rewriteHistory(operation: (projectPath: string) => Promise<string>, label: string) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            update(state => ({ ...state, isLoading: true, error: null }));
+            try {
+                await operation(projectPath);
+            } catch (error) {
+                update(state => ({
+                    ...state,
+                    error: `Failed to ${label}: ${error}`
+                }));
+            } finally {
+                await this.getCommits();
+                await this.refreshStatus();
+                update(state => ({ ...state, isLoading: false }));
+            }
+        },
+
+        async refreshRebase() {
+            try {
+                const projectPath = get(fileStore).currentProjectPath;
+                if (!projectPath) {
+                    return;
+                }
+
+                const rebase = await GetRebaseState(projectPath);
+                update(state => ({ ...state, rebase }));
+            } catch (error) {
+                update(state => ({
+                    ...state,
+                    error: `Failed to get rebase state: ${error}`
+                }));
+            }
+        },
+
+        // Returns the commits a rebase onto a branch, tag or commit would replay, all picked.
+        // Change their actions or order, then pass the plan to executeRebase.
+        async planRebase(onto: string): Promise<service.RebasePlan | null> {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return null;
+            }
+
+            try {
+                return await RebasePlan(projectPath, onto);
+            } catch (error) {
+                update(state => ({ ...state, error: `Failed to plan rebase: ${error}` }));
+                return null;
+            }
+        },
+
+        async executeRebase(plan: service.RebasePlan) {
+            return this.runRebase(projectPath => RebaseExecute(projectPath, plan), 'rebase');
+        },
+
+        async continueRebase() {
+            return this.runRebase(projectPath => RebaseContinue(projectPath), 'continue rebase');
+        },
+
+        async abortRebase() {
+            await this.runRebase(async projectPath => {
+                await RebaseAbort(projectPath);
+                return null;
+            }, 'abort rebase');
+        },
+
+        // Runs a rebase operation, then reloads the rebase state, commits, branches and status.
+        // A rebase stopped at a conflict is not an error, its state says what to resolve.
+        async runRebase(
+            operation: (projectPath: string) => Promise<service.RebaseResult | null>,
+            label: string
+        ): Promise<service.RebaseResult | null> {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return null;
+            }
+
+            update(state => ({ ...state, isLoading: true, error: null }));
+            try {
+                return await operation(projectPath);
+            } catch (error) {
+                update(state => ({
+                    ...state,
+                    error: `Failed to ${label}: ${error}`
+                }));
+                return null;
+            } finally {
+                await Promise.all([
+                    this.refreshRebase(),
+                    this.refreshBranches(),
+                    this.getCommits(),
+                    this.refreshStatus()
+                ]);
+                update(state => ({ ...state, isLoading: false }));
             }
         },
 
@@ -432,6 +681,193 @@ function createGitStore() {
             }
         },
 
+        async refreshTags() {
+            try {
+                const projectPath = get(fileStore).currentProjectPath;
+                if (!projectPath) {
+                    return;
+                }
+
+                const tags = await ListTags(projectPath);
+                update(state => ({ ...state, tags }));
+            } catch (error) {
+                update(state => ({
+                    ...state,
+                    error: `Failed to get tags: ${error}`
+                }));
+            }
+        },
+
+        async createTag(name: string, target = '', message = '', sign = false) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            try {
+                await CreateTag(projectPath, name, target, message, sign);
+                await this.refreshTags();
+            } catch (error) {
+                update(state => ({ ...state, error: `Failed to create tag: ${error}` }));
+            }
+        },
+
+        async deleteTag(name: string) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            try {
+                await DeleteTag(projectPath, name);
+                await this.refreshTags();
+            } catch (error) {
+                update(state => ({ ...state, error: `Failed to delete tag: ${error}` }));
+            }
+        },
+
+        async pushTag(name: string, opts: service.RemoteOptions = new service.RemoteOptions({})) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            try {
+                await PushTag(projectPath, name, opts);
+            } catch (error) {
+                update(state => ({ ...state, error: `Failed to push tag: ${error}` }));
+            }
+        },
+
+        async refreshSubmodules() {
+            try {
+                const projectPath = get(fileStore).currentProjectPath;
+                if (!projectPath) {
+                    return;
+                }
+
+                const submodules = await ListSubmodules(projectPath);
+                update(state => ({ ...state, submodules }));
+            } catch (error) {
+                update(state => ({
+                    ...state,
+                    error: `Failed to get submodules: ${error}`
+                }));
+            }
+        },
+
+        async refreshWorktrees() {
+            try {
+                const projectPath = get(fileStore).currentProjectPath;
+                if (!projectPath) {
+                    return;
+                }
+
+                const worktrees = await ListWorktrees(projectPath);
+                update(state => ({ ...state, worktrees }));
+            } catch (error) {
+                update(state => ({
+                    ...state,
+                    error: `Failed to get worktrees: ${error}`
+                }));
+            }
+        },
+
+        // Returns the new working tree, open its path as a project to work in it
+        async addWorktree(path: string, branch = '', newBranch = false): Promise<service.WorktreeInfo | null> {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return null;
+            }
+
+            try {
+                const worktree = await AddWorktree(projectPath, path, branch, newBranch);
+                await Promise.all([this.refreshWorktrees(), this.refreshBranches()]);
+                return worktree;
+            } catch (error) {
+                update(state => ({ ...state, error: `Failed to add worktree: ${error}` }));
+                return null;
+            }
+        },
+
+        async removeWorktree(path: string, force = false) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            try {
+                await RemoveWorktree(projectPath, path, force);
+                await this.refreshWorktrees();
+            } catch (error) {
+                update(state => ({ ...state, error: `Failed to remove worktree: ${error}` }));
+            }
+        },
+
+        async switchBranch(branch: string) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            update(state => ({ ...state, isLoading: true, error: null }));
+            try {
+                await CheckoutBranch(projectPath, branch);
+                await Promise.all([
+                    this.refreshBranches(),
+                    this.refreshStatus(),
+                    this.getCommits()
+                ]);
+            } catch (error) {
+                update(state => ({
+                    ...state,
+                    error: `Failed to switch branch: ${error}`
+                }));
+            } finally {
+                update(state => ({ ...state, isLoading: false }));
+            }
+        },
+
+        async stashChanges(message = '', includeUntracked = false) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            update(state => ({ ...state, isLoading: true, error: null }));
+            try {
+                await StashSave(projectPath, { message, includeUntracked });
+                await this.refreshStatus();
+            } catch (error) {
+                update(state => ({
+                    ...state,
+                    error: `Failed to stash changes: ${error}`
+                }));
+            } finally {
+                update(state => ({ ...state, isLoading: false }));
+            }
+        },
+
+        async popStash(index = 0) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            update(state => ({ ...state, isLoading: true, error: null }));
+            try {
+                await StashPop(projectPath, index);
+                await this.refreshStatus();
+            } catch (error) {
+                update(state => ({
+                    ...state,
+                    error: `Failed to apply stash: ${error}`
+                }));
+            } finally {
+                update(state => ({ ...state, isLoading: false }));
+            }
+        },
+
         async getCommits(filter: service.CommitFilter = { limit: 20 }) {
             const projectPath = get(fileStore).currentProjectPath;
             if (!projectPath) {
@@ -470,6 +906,51 @@ function createGitStore() {
             }
         },
 
+        async getFileHistory(file: string, filter: service.CommitFilter = { limit: 20 }) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            update(state => ({ ...state, commitsLoading: true, commitsError: null }));
+            try {
+                const commits = await FileHistory(projectPath, file, filter);
+                // A filter with an offset loads the next page of the same history
+                update(state => ({
+                    ...state,
+                    commits: filter.offsetHash ? [...state.commits, ...commits] : commits
+                }));
+            } catch (error) {
+                update(state => ({ ...state, commitsError: error instanceof Error ? error.message : 'Failed to load file history' }));
+            } finally {
+                update(state => ({ ...state, commitsLoading: false }));
+            }
+        },
+
+        async getCommitGraph(filter: service.CommitFilter = { limit: 50 }) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            update(state => ({ ...state, commitsLoading: true, commitsError: null }));
+            try {
+                // The next page continues the lanes where the previous one ended
+                const lanes = filter.offsetHash ? get({ subscribe }).graphLanes : [];
+                const graph = await CommitGraph(projectPath, filter, lanes);
+                update(state => ({
+                    ...state,
+                    commits: filter.offsetHash ? [...state.commits, ...graph.commits] : graph.commits,
+                    graphRows: filter.offsetHash ? [...state.graphRows, ...graph.rows] : graph.rows,
+                    graphLanes: graph.lanes
+                }));
+            } catch (error) {
+                update(state => ({ ...state, commitsError: error instanceof Error ? error.message : 'Failed to load commit graph' }));
+            } finally {
+                update(state => ({ ...state, commitsLoading: false }));
+            }
+        },
+
         async getBranchCommits(branch: string, limit: number) {
             const projectPath = get(fileStore).currentProjectPath;
             if (!projectPath) {
@@ -553,6 +1034,24 @@ function createGitStore() {
             }
         },
 
+        async getCommitDetails(hash: string, parent = 0): Promise<service.CommitDetails | null> {
+            try {
+                const projectPath = get(fileStore).currentProjectPath;
+                if (!projectPath) {
+                    throw new Error('No project path');
+                }
+
+                return await GetCommitDetailsWithOptions(
+                    projectPath,
+                    hash,
+                    service.CommitDetailsOptions.createFrom({ parent, diff: {} })
+                );
+            } catch (error) {
+                console.error(`Failed to load commit details: ${error}`);
+                return null;
+            }
+        },
+
         async getDiff(file: string, staged: boolean) {
             try {
                 const projectPath = get(fileStore).currentProjectPath;
@@ -560,7 +1059,7 @@ function createGitStore() {
                     throw new Error('No project path');
                 }
 
-                const diff = await GetFileDiff(projectPath, file, staged);
+                const diff = await GetFileDiffWithOptions(projectPath, file, staged, { worktreeRenames: get({ subscribe }).worktreeRenames });
                 const virtualPath = `[diff] ${file}`;
                 
                 if (diff.isBinary) {
@@ -590,6 +1089,7 @@ function createGitStore() {
                 stagedExpanded: true,
                 changesExpanded: true,
                 hierarchicalView: false,
+                worktreeRenames: false,
                 isRepository: false,
                 isLoading: false,
                 isQuickRefreshing: false,
@@ -597,14 +1097,24 @@ function createGitStore() {
                 error: null,
                 branches: [],
                 currentBranch: null,
+                tags: [],
+                submodules: [],
+                worktrees: [],
+                rebase: null,
                 commits: [],
                 commitsLoading: false,
                 commitsError: null,
+                graphRows: [],
+                graphLanes: [],
                 HEAD: null,
+                identity: null,
+                hookOutput: [],
                 initialized: false
             });
         }
     };
+
+    return store;
 }
 
 export const gitStore = createGitStore();
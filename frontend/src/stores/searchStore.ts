@@ -0,0 +1,179 @@
+import { writable, get } from 'svelte/store';
+import { SearchContent, CancelSearch, PreviewReplace, ApplyReplace, UndoReplace } from '@/lib/wailsjs/go/main/App';
+import { EventsOn } from '@/lib/wailsjs/runtime/runtime';
+import { fileStore } from '@/stores/fileStore';
+import { service } from '@/lib/wailsjs/go/models';
+
+// A match of a content search, received in batches as search:results events
+export interface SearchMatch {
+    path: string;
+    line: number;
+    column: number;
+    length: number;
+    text: string;
+    before: string[];
+    after: string[];
+}
+
+interface SearchState {
+    query: string;
+    options: service.SearchOptions;
+    searchId: number | null;
+    matches: SearchMatch[];
+    summary: service.SearchSummary | null;
+    searching: boolean;
+    replacement: string;
+    preview: service.ReplacePreview | null;
+    lastReplace: service.ReplaceResult | null;
+    error: string | null;
+}
+
+const defaultOptions = new service.SearchOptions({
+    regex: false,
+    caseSensitive: false,
+    wholeWord: false,
+    include: [],
+    exclude: [],
+    contextLines: 2,
+    maxResults: 0
+});
+
+function createSearchStore() {
+    const { subscribe, set, update } = writable<SearchState>({
+        query: '',
+        options: defaultOptions,
+        searchId: null,
+        matches: [],
+        summary: null,
+        searching: false,
+        replacement: '',
+        preview: null,
+        lastReplace: null,
+        error: null
+    });
+
+    // Results stream in batches, only those of the latest search are kept
+    EventsOn('search:started', (event: { searchId: number }) => {
+        update(state => ({ ...state, searchId: event.searchId, matches: [], summary: null }));
+    });
+
+    EventsOn('search:results', (event: { searchId: number; matches: SearchMatch[] }) => {
+        update(state => {
+            if (event.searchId !== state.searchId) return state;
+            return { ...state, matches: [...state.matches, ...event.matches] };
+        });
+    });
+
+    return {
+        subscribe,
+
+        // Searches the contents of the project, a new search cancels the previous one
+        async search(query: string, options: Partial<service.SearchOptions> = {}) {
+            const projectPath = get(fileStore).currentProjectPath;
+            if (!projectPath) {
+                return;
+            }
+
+            const searchOptions = new service.SearchOptions({ ...get({ subscribe }).options, ...options });
+            if (!query) {
+                await this.cancel();
+                update(state => ({ ...state, query, options: searchOptions, matches: [], summary: null, error: null }));
+                return;
+            }
+
+            update(state => ({ ...state, query, options: searchOptions, searching: true, error: null }));
+            try {
+                const summary = await SearchContent(projectPath, query, searchOptions);
+                update(state => {
+                    // A newer search has taken over
+                    if (state.searchId !== null && summary.searchId < state.searchId) return state;
+                    return { ...state, summary, searching: false };
+                });
+            } catch (error) {
+                update(state => ({
+                    ...state,
+                    matches: [],
+                    searching: false,
+                    error: `Failed to search: ${error}`
+                }));
+            }
+        },
+
+        // Shows what replacing the matches of the current search would change, nothing is written
+        async previewReplace(replacement: string) {
+            const projectPath = get(fileStore).currentProjectPath;
+            const { query, options } = get({ subscribe });
+            if (!projectPath || !query) {
+                return;
+            }
+
+            update(state => ({ ...state, replacement, error: null }));
+            try {
+                const preview = await PreviewReplace(projectPath, query, replacement, options);
+                update(state => ({ ...state, preview }));
+            } catch (error) {
+                update(state => ({ ...state, preview: null, error: `Failed to preview replace: ${error}` }));
+            }
+        },
+
+        // Applies the previewed replace to the given files, all of them by default.
+        // Open files pick up the new contents through the file watcher.
+        async applyReplace(paths?: string[]) {
+            const projectPath = get(fileStore).currentProjectPath;
+            const { query, options, replacement, preview } = get({ subscribe });
+            if (!projectPath || !preview) {
+                return;
+            }
+
+            const files = preview.files
+                .filter(file => !paths || paths.includes(file.path))
+                .map(file => new service.ReplaceFile({ path: file.path, hash: file.hash }));
+            try {
+                const result = await ApplyReplace(projectPath, query, replacement, options, files);
+                update(state => ({ ...state, preview: null, lastReplace: result, error: null }));
+                return result;
+            } catch (error) {
+                update(state => ({ ...state, error: `Failed to replace: ${error}` }));
+            }
+        },
+
+        // Reverts the last replace, files edited since are left untouched
+        async undoReplace() {
+            const { lastReplace } = get({ subscribe });
+            if (!lastReplace?.operationId) {
+                return;
+            }
+
+            try {
+                const result = await UndoReplace(lastReplace.operationId);
+                update(state => ({ ...state, lastReplace: null, error: null }));
+                return result;
+            } catch (error) {
+                update(state => ({ ...state, error: `Failed to undo replace: ${error}` }));
+            }
+        },
+
+        async cancel() {
+            await CancelSearch();
+            update(state => ({ ...state, searching: false }));
+        },
+
+        reset() {
+            CancelSearch();
+            set({
+                query: '',
+                options: defaultOptions,
+                searchId: null,
+                matches: [],
+                summary: null,
+                searching: false,
+                replacement: '',
+                preview: null,
+                lastReplace: null,
+                error: null
+            });
+        }
+    };
+}
+
+export const searchStore = createSearchStore();
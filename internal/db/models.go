@@ -8,6 +8,14 @@ import (
 	"database/sql"
 )
 
+type FileOpen struct {
+	ID         int64
+	ProjectID  int64
+	Path       string
+	OpenCount  int64
+	LastOpened sql.NullTime
+}
+
 type Project struct {
 	ID         int64
 	Name       string
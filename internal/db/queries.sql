@@ -15,4 +15,17 @@ WHERE id = ?;
 -- name: ListRecentProjects :many
 SELECT * FROM projects
 ORDER BY last_opened DESC
+LIMIT ?;
+
+-- name: RecordFileOpen :exec
+INSERT INTO file_opens (project_id, path)
+VALUES (?, ?)
+ON CONFLICT (project_id, path) DO UPDATE
+SET open_count = open_count + 1,
+    last_opened = CURRENT_TIMESTAMP;
+
+-- name: ListFileOpens :many
+SELECT * FROM file_opens
+WHERE project_id = ?
+ORDER BY last_opened DESC
 LIMIT ?;
\ No newline at end of file
@@ -53,6 +53,47 @@ func (q *Queries) GetProject(ctx context.Context, path string) (Project, error)
 	return i, err
 }
 
+const listFileOpens = `-- name: ListFileOpens :many
+SELECT id, project_id, path, open_count, last_opened FROM file_opens
+WHERE project_id = ?
+ORDER BY last_opened DESC
+LIMIT ?
+`
+
+type ListFileOpensParams struct {
+	ProjectID int64
+	Limit     int64
+}
+
+func (q *Queries) ListFileOpens(ctx context.Context, arg ListFileOpensParams) ([]FileOpen, error) {
+	rows, err := q.db.QueryContext(ctx, listFileOpens, arg.ProjectID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FileOpen
+	for rows.Next() {
+		var i FileOpen
+		if err := rows.Scan(
+			&i.ID,
+			&i.ProjectID,
+			&i.Path,
+			&i.OpenCount,
+			&i.LastOpened,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listRecentProjects = `-- name: ListRecentProjects :many
 SELECT id, name, path, last_opened, created_at, updated_at FROM projects
 ORDER BY last_opened DESC
@@ -89,6 +130,24 @@ func (q *Queries) ListRecentProjects(ctx context.Context, limit int64) ([]Projec
 	return items, nil
 }
 
+const recordFileOpen = `-- name: RecordFileOpen :exec
+INSERT INTO file_opens (project_id, path)
+VALUES (?, ?)
+ON CONFLICT (project_id, path) DO UPDATE
+SET open_count = open_count + 1,
+    last_opened = CURRENT_TIMESTAMP
+`
+
+type RecordFileOpenParams struct {
+	ProjectID int64
+	Path      string
+}
+
+func (q *Queries) RecordFileOpen(ctx context.Context, arg RecordFileOpenParams) error {
+	_, err := q.db.ExecContext(ctx, recordFileOpen, arg.ProjectID, arg.Path)
+	return err
+}
+
 const updateProjectLastOpened = `-- name: UpdateProjectLastOpened :exec
 UPDATE projects
 SET last_opened = CURRENT_TIMESTAMP
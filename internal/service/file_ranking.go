@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/edit4i/editor/internal/db"
+)
+
+// maxFileHistory is how many of the latest opened files of a project are ranked
+const maxFileHistory = 500
+
+// Frecency boosts: a file opened recently is boosted more than one opened long ago, and
+// each doubling of how often it was opened adds as much, up to maxFrequency doublings.
+// That is up to 60 for a file opened often in the last hours, plus 20 when git shows it
+// changed, about as much as five opens this week. The file search caps the boosts by the
+// length of the query, so they only reorder matches of similar quality.
+const (
+	frecencyWeight = 0.15
+	maxFrequency   = 4
+	modifiedBoost  = 20 // Files git shows changed
+)
+
+// FileRanker weighs files in file searches beyond how well their paths match
+type FileRanker interface {
+	// FileBoosts returns the scores to add to files of a project, by path relative to it
+	FileBoosts(projectPath string) map[string]int
+}
+
+// FileRankingService ranks the files of the file finder by how recently and how often
+// they were opened in a project, and by whether git shows them changed
+type FileRankingService struct {
+	queries *db.Queries
+	git     *GitService
+	// Changed files of each project, refreshed in the background when git reports a change
+	modified     map[string]*modifiedFiles
+	modifiedLock sync.Mutex
+}
+
+// modifiedFiles is the last known set of changed files of a project
+type modifiedFiles struct {
+	paths      map[string]bool
+	loaded     chan struct{} // Closed once paths was first set
+	stale      bool
+	refreshing bool
+}
+
+// NewFileRankingService creates a new file ranking service
+func NewFileRankingService(dbConn *sql.DB, git *GitService) *FileRankingService {
+	return &FileRankingService{
+		queries:  db.New(dbConn),
+		git:      git,
+		modified: make(map[string]*modifiedFiles),
+	}
+}
+
+// RecordFileOpen adds an open of a file to the history of its project. Files of projects
+// that were never added aren't recorded.
+func (s *FileRankingService) RecordFileOpen(projectPath, filePath string) error {
+	ctx := context.Background()
+
+	project, err := s.queries.GetProject(ctx, projectPath)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get project: %w", err)
+	}
+
+	relPath, err := projectRelPath(projectPath, filePath)
+	if err != nil {
+		return err
+	}
+
+	err = s.queries.RecordFileOpen(ctx, db.RecordFileOpenParams{
+		ProjectID: project.ID,
+		Path:      relPath,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record file open: %w", err)
+	}
+	return nil
+}
+
+// FileBoosts returns the frecency boost of the files opened in a project, plus a boost for
+// the files git shows changed
+func (s *FileRankingService) FileBoosts(projectPath string) map[string]int {
+	boosts := make(map[string]int)
+
+	history, err := s.fileHistory(projectPath)
+	if err != nil {
+		log.Printf("[FileRankingService] Failed to load the file history of %s: %v", projectPath, err)
+	}
+	now := time.Now()
+	for _, open := range history {
+		boosts[open.Path] += frecencyBoost(open.OpenCount, open.LastOpened.Time, now)
+	}
+
+	for path := range s.modifiedFiles(projectPath) {
+		boosts[path] += modifiedBoost
+	}
+
+	return boosts
+}
+
+// InvalidateModified marks the changed files of a project as out of date, after git
+// reported a change
+func (s *FileRankingService) InvalidateModified(projectPath string) {
+	s.modifiedLock.Lock()
+	defer s.modifiedLock.Unlock()
+
+	if modified, ok := s.modified[projectPath]; ok {
+		modified.stale = true
+	}
+}
+
+// fileHistory returns the latest opened files of a project, none if it was never added
+func (s *FileRankingService) fileHistory(projectPath string) ([]db.FileOpen, error) {
+	ctx := context.Background()
+
+	project, err := s.queries.GetProject(ctx, projectPath)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queries.ListFileOpens(ctx, db.ListFileOpensParams{
+		ProjectID: project.ID,
+		Limit:     maxFileHistory,
+	})
+}
+
+// modifiedFiles returns the last known changed files of a project. Getting the status of
+// a large repository takes a while, so only the first search of a project waits for it;
+// later it is refreshed in the background when out of date and searches use what is
+// known meanwhile.
+func (s *FileRankingService) modifiedFiles(projectPath string) map[string]bool {
+	s.modifiedLock.Lock()
+	modified, ok := s.modified[projectPath]
+	if !ok {
+		modified = &modifiedFiles{loaded: make(chan struct{}), stale: true}
+		s.modified[projectPath] = modified
+	}
+	refresh := modified.stale && !modified.refreshing
+	if refresh {
+		modified.stale = false
+		modified.refreshing = true
+	}
+	s.modifiedLock.Unlock()
+
+	if refresh && !ok {
+		s.refreshModified(projectPath, modified)
+	} else if refresh {
+		go s.refreshModified(projectPath, modified)
+	}
+	<-modified.loaded
+
+	s.modifiedLock.Lock()
+	defer s.modifiedLock.Unlock()
+	return modified.paths
+}
+
+// refreshModified gets the changed files of a project from its git status
+func (s *FileRankingService) refreshModified(projectPath string, modified *modifiedFiles) {
+	paths := make(map[string]bool)
+	statuses, err := s.git.GetStatus(projectPath)
+	if err == nil {
+		for _, status := range statuses {
+			if status.Status != "D" {
+				paths[status.File] = true
+			}
+		}
+	}
+
+	s.modifiedLock.Lock()
+	defer s.modifiedLock.Unlock()
+
+	modified.paths = paths
+	modified.refreshing = false
+	select {
+	case <-modified.loaded:
+	default:
+		close(modified.loaded)
+	}
+}
+
+// frecencyBoost weighs how recently and how often a file was opened
+func frecencyBoost(count int64, lastOpened, now time.Time) int {
+	var recency float64
+	switch age := now.Sub(lastOpened); {
+	case age < 4*time.Hour:
+		recency = 100
+	case age < 24*time.Hour:
+		recency = 70
+	case age < 7*24*time.Hour:
+		recency = 50
+	case age < 30*24*time.Hour:
+		recency = 30
+	default:
+		recency = 10
+	}
+
+	frequency := min(math.Log2(float64(count)+1), maxFrequency)
+	return int(math.Round(recency * frequency * frecencyWeight))
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"testing"
+)
+
+// TestModifiedFilesFirstSearch checks the first lookup of the changed files of a project
+// already knows them
+func TestModifiedFilesFirstSearch(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.txt", "a\n")
+	writeTestFile(t, dir, "b.txt", "b\n")
+	commitAll(t, dir, "Add files")
+	writeTestFile(t, dir, "a.txt", "a changed\n")
+	writeTestFile(t, dir, "c.txt", "c\n")
+
+	s := NewFileRankingService(nil, NewGitService(nil))
+	modified := s.modifiedFiles(dir)
+	if len(modified) != 2 || !modified["a.txt"] || !modified["c.txt"] {
+		t.Errorf("expected a.txt and c.txt to be modified, got %v", modified)
+	}
+}
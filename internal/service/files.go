@@ -11,8 +11,7 @@ import (
 	"time"
 
 	ignore "github.com/sabhiram/go-gitignore"
-	"github.com/sahilm/fuzzy"
-	"io/fs"
+	"log"
 )
 
 // FileNode represents a file or directory in the project
@@ -31,15 +30,41 @@ type FileService struct {
 	// Cache file trees with expiration
 	cache     map[string]*FileNode
 	cacheLock sync.RWMutex
-	// TODO: Add file watcher
-	ignores map[string]*ignore.GitIgnore
+	// Loaded gitignore rules by directory, nil for directories without a .gitignore
+	ignores     map[string]*ignore.GitIgnore
+	ignoresLock sync.Mutex
+	// Watchers keep the cached trees of open projects current
+	watchers     map[string]*projectWatcher
+	watchersLock sync.Mutex
+	// Where file indexes are saved between sessions, if anywhere
+	indexDir string
+	ranker   FileRanker
+	// Only one content search runs at a time, a new one cancels it
+	search     *contentSearch
+	searchID   int
+	searchLock sync.Mutex
+	// Applied replaces, most recent last, kept to be undone
+	replaces    []replaceOperation
+	replaceID   int
+	replaceLock sync.Mutex
+	onEvent     func(name string, data interface{})
 }
 
-// NewFileService creates a new file service instance
-func NewFileService() *FileService {
+// NewFileService creates a new file service instance.
+// onEvent is called for change notifications that should reach the frontend.
+func NewFileService(onEvent func(name string, data interface{})) *FileService {
 	return &FileService{
-		cache:   make(map[string]*FileNode),
-		ignores: make(map[string]*ignore.GitIgnore),
+		cache:    make(map[string]*FileNode),
+		ignores:  make(map[string]*ignore.GitIgnore),
+		watchers: make(map[string]*projectWatcher),
+		onEvent:  onEvent,
+	}
+}
+
+// emit forwards an event to the frontend if an event handler is set
+func (s *FileService) emit(name string, data interface{}) {
+	if s.onEvent != nil {
+		s.onEvent(name, data)
 	}
 }
 
@@ -66,6 +91,11 @@ func (s *FileService) GetProjectFiles(projectPath string) (*FileNode, error) {
 	s.cache[projectPath] = root
 	s.cacheLock.Unlock()
 
+	// Keep the tree current while the project is open
+	if err := s.WatchProject(projectPath); err != nil {
+		log.Printf("[FileService] Failed to watch %s: %v", projectPath, err)
+	}
+
 	return root, nil
 }
 
@@ -274,133 +304,123 @@ func (s *FileService) invalidateNode(node *FileNode, targetPath string) bool {
 	return false
 }
 
-// loadGitIgnore loads the gitignore file for a directory if it exists
+// loadGitIgnore loads the gitignore file for a directory if it exists. Directories without
+// one are remembered too, the watcher forgets them when a .gitignore shows up.
 func (s *FileService) loadGitIgnore(dirPath string) *ignore.GitIgnore {
+	s.ignoresLock.Lock()
+	defer s.ignoresLock.Unlock()
+
 	if ig, ok := s.ignores[dirPath]; ok {
 		return ig
 	}
 
 	gitignorePath := filepath.Join(dirPath, ".gitignore")
-	if ig, err := ignore.CompileIgnoreFile(gitignorePath); err == nil {
-		s.ignores[dirPath] = ig
-		return ig
+	ig, err := ignore.CompileIgnoreFile(gitignorePath)
+	if err != nil {
+		ig = nil
 	}
-	return nil
+	s.ignores[dirPath] = ig
+	return ig
 }
 
-// isIgnored checks if a path should be ignored based on gitignore rules
-func (s *FileService) isIgnored(rootPath, path string) bool {
+// forgetGitIgnore drops the loaded gitignore rules of a directory, after its .gitignore changed
+func (s *FileService) forgetGitIgnore(dirPath string) {
+	s.ignoresLock.Lock()
+	delete(s.ignores, dirPath)
+	s.ignoresLock.Unlock()
+}
+
+// forgetGitIgnores drops the loaded gitignore rules of a project's directories
+func (s *FileService) forgetGitIgnores(projectPath string) {
+	prefix := projectPath + string(filepath.Separator)
+
+	s.ignoresLock.Lock()
+	defer s.ignoresLock.Unlock()
+
+	for dir := range s.ignores {
+		if dir == projectPath || strings.HasPrefix(dir, prefix) {
+			delete(s.ignores, dir)
+		}
+	}
+}
+
+// isIgnored checks if a path should be ignored based on gitignore rules.
+// Patterns ending with a slash only match directories.
+func (s *FileService) isIgnored(rootPath, path string, isDir bool) bool {
 	// Always ignore .git directory
 	if strings.Contains(path, "/.git/") || strings.HasSuffix(path, "/.git") {
 		return true
 	}
 
-	// Check each parent directory for .gitignore rules
-	dir := filepath.Dir(path)
-	for dir != "/" && strings.HasPrefix(path, rootPath) {
+	// Only the .gitignore files of the project apply, not those of directories above it
+	rootPath = filepath.Clean(rootPath)
+	prefix := rootPath
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+
+	// Check each parent directory for .gitignore rules, up to the root
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
 		if ig := s.loadGitIgnore(dir); ig != nil {
 			relPath, err := filepath.Rel(dir, path)
+			if err == nil && isDir {
+				relPath += "/"
+			}
 			if err == nil && ig.MatchesPath(relPath) {
 				return true
 			}
 		}
-		dir = filepath.Dir(dir)
+		if dir == rootPath || dir == filepath.Dir(dir) {
+			break
+		}
 	}
 	return false
 }
 
-// SearchFiles performs a fuzzy search on files in a directory
-func (s *FileService) SearchFiles(ctx context.Context, dirPath, query string) ([]*FileNode, error) {
-	var allFiles []*FileNode
-	var searchPaths []string
-
-	// Walk the directory tree
-	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Check for cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		// Skip if ignored
-		if s.isIgnored(dirPath, path) {
-			if d.IsDir() {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-
-		// Skip directories in the results
-		if d.IsDir() {
-			return nil
-		}
-
-		// Get file info
-		info, err := d.Info()
-		if err != nil {
-			return nil
-		}
-
-		// Create file node
-		node := &FileNode{
-			Name:         d.Name(),
-			Path:         path,
-			Type:         "file",
-			Size:         info.Size(),
-			LastModified: info.ModTime(),
-			IsLoaded:     true,
-		}
-
-		// Get relative path for search
-		relPath, err := filepath.Rel(dirPath, path)
-		if err == nil {
-			searchPaths = append(searchPaths, relPath)
-			allFiles = append(allFiles, node)
-		}
-		return nil
-	})
+// SearchFiles fuzzy matches a query against the paths of the files of a project and returns
+// the best matches first, at most limit of them, or defaultFileSearchLimit when limit isn't
+// positive. The ranker, if set, boosts recent and changed files, which are also what an
+// empty query returns. It answers from the project's file index, built when the project is
+// first watched.
+func (s *FileService) SearchFiles(ctx context.Context, dirPath, query string, limit int) ([]*FileNode, error) {
+	if limit <= 0 {
+		limit = defaultFileSearchLimit
+	}
 
+	index, err := s.projectIndex(dirPath)
 	if err != nil {
 		return nil, err
 	}
-
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
+	if err := index.wait(ctx); err != nil {
+		return nil, err
 	}
 
-	// If no query, return first 10 files
-	if query == "" || len(allFiles) == 0 {
-		maxResults := 10
-		if len(allFiles) < maxResults {
-			maxResults = len(allFiles)
-		}
-		return allFiles[:maxResults], nil
+	var boosts map[string]int
+	if s.ranker != nil {
+		boosts = s.ranker.FileBoosts(dirPath)
 	}
 
-	// Use fuzzy library to search
-	matches := fuzzy.Find(query, searchPaths)
-
-	// Sort matches by score (already done by fuzzy.Find)
-	results := make([]*FileNode, 0, len(matches))
-	seen := make(map[string]bool)
-
-	for _, match := range matches {
-		if len(results) >= 10 {
-			break
-		}
-
-		// Skip if we've already added this file
-		if seen[allFiles[match.Index].Path] {
+	paths := index.search(query, limit, boosts)
+	results := make([]*FileNode, 0, len(paths))
+	for _, relPath := range paths {
+		path := filepath.Join(dirPath, filepath.FromSlash(relPath))
+		info, err := os.Stat(path)
+		if err != nil {
+			// Gone since it was indexed, the watcher will catch up
 			continue
 		}
 
-		results = append(results, allFiles[match.Index])
-		seen[allFiles[match.Index].Path] = true
+		results = append(results, &FileNode{
+			Name:         info.Name(),
+			Path:         path,
+			Type:         "file",
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+			IsLoaded:     true,
+		})
 	}
 
 	return results, nil
@@ -515,3 +535,39 @@ func (s *FileService) DeleteFile(path string) error {
 	s.InvalidateCache(filepath.Dir(path))
 	return nil
 }
+
+// projectRelPath returns the slash-separated path of a file relative to its project. It fails
+// when the file is outside the project, symlinks resolved, so a link can't lead out of it.
+// A file that doesn't exist yet is resolved through its directory.
+func projectRelPath(projectPath, path string) (string, error) {
+	outside := fmt.Errorf("file %s is not in project %s", path, projectPath)
+
+	relPath, err := filepath.Rel(projectPath, path)
+	if err != nil || isOutside(relPath) {
+		return "", outside
+	}
+
+	root, err := filepath.EvalSymlinks(projectPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve project path: %w", err)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		var dir string
+		dir, err = filepath.EvalSymlinks(filepath.Dir(path))
+		resolved = filepath.Join(dir, filepath.Base(path))
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	if resolvedRel, err := filepath.Rel(root, resolved); err != nil || isOutside(resolvedRel) {
+		return "", outside
+	}
+
+	return filepath.ToSlash(relPath), nil
+}
+
+// isOutside reports whether a relative path leads out of the directory it is relative to
+func isOutside(relPath string) bool {
+	return relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,447 @@
+package service
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// defaultFileSearchLimit is how many files SearchFiles returns when no limit is given
+const defaultFileSearchLimit = 10
+
+// searchChunkSize is how many files each CPU matches at least, smaller indexes aren't split
+const searchChunkSize = 10000
+
+// fileIndexVersion changes whenever the format of saved indexes does, older ones are ignored
+const fileIndexVersion = 1
+
+// Fuzzy scoring: every matched character scores, more so at the start of a word or after
+// the previous match, and characters skipped between matches cost a little. Matches ending
+// a word score more.
+const (
+	scoreMatch          = 16
+	bonusBoundary       = 8
+	bonusCamelCase      = 7
+	bonusConsecutive    = 4
+	bonusFileName       = 24 // The whole query matched within the file name
+	bonusWordEnd        = 20
+	penaltyGapStart     = 3
+	penaltyGapExtension = 1
+)
+
+// fileIndex lists the files of a project for the file finder. The project watcher keeps it
+// current, so searches never walk the project.
+type fileIndex struct {
+	lock      sync.RWMutex
+	files     []indexedFile
+	positions map[string]int // Position of each path in files
+	ready     chan struct{}  // Closed once the index holds the project's files
+	readyOnce sync.Once
+}
+
+// indexedFile is a file of the index, with its path lowered once for matching
+type indexedFile struct {
+	path  string // Relative to the project, with forward slashes
+	lower []byte
+	chars uint64 // Set of the characters of the path, to skip most files without a scan
+}
+
+// savedFileIndex is an index as saved to disk between sessions
+type savedFileIndex struct {
+	Version int
+	Root    string
+	Paths   []string
+}
+
+// fileMatch is a file matching a search, with how well it matches
+type fileMatch struct {
+	path  string
+	score int
+}
+
+// newFileIndex returns an empty index, not ready until filled
+func newFileIndex() *fileIndex {
+	return &fileIndex{
+		positions: make(map[string]int),
+		ready:     make(chan struct{}),
+	}
+}
+
+// add indexes a file, unless it already is
+func (x *fileIndex) add(path string) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	if _, ok := x.positions[path]; ok {
+		return
+	}
+	x.positions[path] = len(x.files)
+	x.files = append(x.files, newIndexedFile(path))
+}
+
+// remove drops a file from the index
+func (x *fileIndex) remove(path string) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	i, ok := x.positions[path]
+	if !ok {
+		return
+	}
+
+	// Order doesn't matter, the last file takes the place of the removed one
+	last := len(x.files) - 1
+	x.files[i] = x.files[last]
+	x.positions[x.files[i].path] = i
+	x.files = x.files[:last]
+	delete(x.positions, path)
+}
+
+// replace sets the whole content of the index
+func (x *fileIndex) replace(paths []string) {
+	files := make([]indexedFile, 0, len(paths))
+	positions := make(map[string]int, len(paths))
+	for _, path := range paths {
+		if _, ok := positions[path]; ok {
+			continue
+		}
+		positions[path] = len(files)
+		files = append(files, newIndexedFile(path))
+	}
+
+	x.lock.Lock()
+	x.files = files
+	x.positions = positions
+	x.lock.Unlock()
+}
+
+// paths returns the paths of the indexed files
+func (x *fileIndex) paths() []string {
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	paths := make([]string, len(x.files))
+	for i, file := range x.files {
+		paths[i] = file.path
+	}
+	return paths
+}
+
+// markReady lets searches through, once the index holds the project's files
+func (x *fileIndex) markReady() {
+	x.readyOnce.Do(func() { close(x.ready) })
+}
+
+// wait blocks until the index is ready or the context is done
+func (x *fileIndex) wait(ctx context.Context) error {
+	select {
+	case <-x.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// search returns the paths best matching a query, at most limit of them, with the boosts
+// of files added to how well they match. Without a query only boosted files are returned,
+// the most boosted first. Large indexes are split between the CPUs.
+func (x *fileIndex) search(query string, limit int, boosts map[string]int) []string {
+	lowerQuery := []byte(strings.ToLower(query))
+
+	x.lock.RLock()
+	defer x.lock.RUnlock()
+
+	parts := min(runtime.NumCPU(), len(x.files)/searchChunkSize+1)
+	chunk := (len(x.files) + parts - 1) / max(parts, 1)
+	found := make([]*fileMatches, parts)
+	var workers sync.WaitGroup
+	for part := range found {
+		found[part] = &fileMatches{}
+		files := x.files[min(part*chunk, len(x.files)):min((part+1)*chunk, len(x.files))]
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			matchFiles(files, lowerQuery, limit, boosts, found[part])
+		}()
+	}
+	workers.Wait()
+
+	best := found[0]
+	for _, matches := range found[1:] {
+		for _, match := range *matches {
+			best.keep(match, limit)
+		}
+	}
+
+	paths := make([]string, best.Len())
+	for i := len(paths) - 1; i >= 0; i-- {
+		paths[i] = heap.Pop(best).(fileMatch).path
+	}
+	return paths
+}
+
+// matchFiles keeps the files best matching a lowered query in best, at most limit of them
+func matchFiles(files []indexedFile, query []byte, limit int, boosts map[string]int, best *fileMatches) {
+	queryChars := charSet(query)
+	for i := range files {
+		file := &files[i]
+		if file.chars&queryChars != queryChars {
+			continue
+		}
+		boost, boosted := boosts[file.path]
+		if len(query) == 0 && !boosted {
+			continue
+		}
+		if len(query) > 0 {
+			// Boosts only reorder matches of similar quality: they are capped at half of
+			// what the characters of the query score, below the gap to a much better match
+			boost = min(boost, len(query)*scoreMatch/2)
+		}
+		if score, ok := fuzzyScore(query, file.path, file.lower); ok {
+			best.keep(fileMatch{path: file.path, score: score + boost}, limit)
+		}
+	}
+}
+
+// newIndexedFile prepares a path for matching
+func newIndexedFile(path string) indexedFile {
+	lower := []byte(strings.ToLower(path))
+	return indexedFile{path: path, lower: lower, chars: charSet(lower)}
+}
+
+// charSet returns the set of characters in a lowered text, letters and digits each with
+// their own bit and other characters sharing the rest
+func charSet(text []byte) uint64 {
+	var set uint64
+	for _, c := range text {
+		switch {
+		case 'a' <= c && c <= 'z':
+			set |= 1 << (c - 'a')
+		case '0' <= c && c <= '9':
+			set |= 1 << (26 + c - '0')
+		default:
+			set |= 1 << (36 + c%28)
+		}
+	}
+	return set
+}
+
+// betterThan orders matches by score, then shorter paths first, then by path
+func (m fileMatch) betterThan(other fileMatch) bool {
+	if m.score != other.score {
+		return m.score > other.score
+	}
+	if len(m.path) != len(other.path) {
+		return len(m.path) < len(other.path)
+	}
+	return m.path < other.path
+}
+
+// fileMatches is a heap of matches with the worst one on top, to keep the best ones
+type fileMatches []fileMatch
+
+func (h fileMatches) Len() int           { return len(h) }
+func (h fileMatches) Less(i, j int) bool { return h[j].betterThan(h[i]) }
+func (h fileMatches) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *fileMatches) Push(x any)        { *h = append(*h, x.(fileMatch)) }
+func (h *fileMatches) Pop() any {
+	old := *h
+	match := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return match
+}
+
+// keep adds a match if there are fewer than limit, or if it beats the worst one kept
+func (h *fileMatches) keep(match fileMatch, limit int) {
+	if h.Len() < limit {
+		heap.Push(h, match)
+	} else if match.betterThan((*h)[0]) {
+		(*h)[0] = match
+		heap.Fix(h, 0)
+	}
+}
+
+// fuzzyScore scores how well a lowered query matches a path, whose characters must all
+// appear in order. Matches within the file name are preferred over the rest of the path.
+func fuzzyScore(query []byte, path string, lower []byte) (int, bool) {
+	if len(query) == 0 {
+		return 0, true
+	}
+
+	start, end, ok := matchWindow(query, lower, 0)
+	if !ok {
+		return 0, false
+	}
+
+	name := strings.LastIndexByte(path, '/') + 1
+	if start >= name {
+		return scoreWindow(query, path, lower, start, end) + bonusFileName, true
+	}
+	if start, end, ok := matchWindow(query, lower, name); ok {
+		return scoreWindow(query, path, lower, start, end) + bonusFileName, true
+	}
+	return scoreWindow(query, path, lower, start, end), true
+}
+
+// matchWindow finds the first end of a match of the query in text from an offset, then
+// walks back from it to the latest start, for the shortest window holding the match
+func matchWindow(query, text []byte, from int) (int, int, bool) {
+	end := from
+	for _, c := range query {
+		i := bytes.IndexByte(text[end:], c)
+		if i < 0 {
+			return 0, 0, false
+		}
+		end += i + 1
+	}
+
+	start := end - 1
+	for q := len(query) - 1; q >= 0; start-- {
+		if text[start] == query[q] {
+			q--
+		}
+	}
+	return start + 1, end, true
+}
+
+// scoreWindow scores a match of the query within a window of the path
+func scoreWindow(query []byte, path string, lower []byte, start, end int) int {
+	score := 0
+	consecutive := 0
+	q := 0
+	for i := start; i < end; i++ {
+		if q < len(query) && lower[i] == query[q] {
+			score += scoreMatch + boundaryBonus(path, i) + consecutive*bonusConsecutive
+			consecutive++
+			q++
+			continue
+		}
+		if consecutive > 0 {
+			score -= penaltyGapStart
+		} else {
+			score -= penaltyGapExtension
+		}
+		consecutive = 0
+	}
+
+	// A match ending a word, like main in main.go, beats one stopping short of it
+	if end == len(path) || isWordSeparator(path[end]) || boundaryBonus(path, end) > 0 {
+		score += bonusWordEnd
+	}
+	return score
+}
+
+// boundaryBonus rewards matching the first character of a word of a path
+func boundaryBonus(path string, i int) int {
+	if i == 0 {
+		return bonusBoundary
+	}
+	prev, c := path[i-1], path[i]
+	switch {
+	case isWordSeparator(prev):
+		return bonusBoundary
+	case 'a' <= prev && prev <= 'z' && 'A' <= c && c <= 'Z':
+		return bonusCamelCase
+	}
+	return 0
+}
+
+// isWordSeparator reports whether a character of a path separates words
+func isWordSeparator(c byte) bool {
+	return c == '/' || c == '_' || c == '-' || c == '.' || c == ' '
+}
+
+// SetIndexDir sets the directory file indexes are saved to, so reopening a project finds its
+// files at once while they are indexed again. Without it indexes are only kept in memory.
+func (s *FileService) SetIndexDir(dir string) {
+	s.indexDir = dir
+}
+
+// SetRanker sets what weighs files in file searches beyond how well their paths match
+func (s *FileService) SetRanker(ranker FileRanker) {
+	s.ranker = ranker
+}
+
+// projectIndex returns the file index of a project, watching the project if it isn't yet
+func (s *FileService) projectIndex(projectPath string) (*fileIndex, error) {
+	if err := s.WatchProject(projectPath); err != nil {
+		return nil, err
+	}
+
+	s.watchersLock.Lock()
+	defer s.watchersLock.Unlock()
+
+	w, ok := s.watchers[projectPath]
+	if !ok {
+		return nil, fmt.Errorf("project %s was closed", projectPath)
+	}
+	return w.index, nil
+}
+
+// indexFile returns where the index of a project is saved, or an empty path when indexes
+// aren't saved
+func (s *FileService) indexFile(projectPath string) string {
+	if s.indexDir == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(projectPath))
+	return filepath.Join(s.indexDir, hex.EncodeToString(sum[:8])+".gob")
+}
+
+// loadIndex returns the paths saved in the index of a project, nil when there is none
+func (s *FileService) loadIndex(projectPath string) []string {
+	path := s.indexFile(projectPath)
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("[FileService] Failed to load the index of %s: %v", projectPath, err)
+		}
+		return nil
+	}
+	defer file.Close()
+
+	var saved savedFileIndex
+	if err := gob.NewDecoder(file).Decode(&saved); err != nil {
+		log.Printf("[FileService] Failed to load the index of %s: %v", projectPath, err)
+		return nil
+	}
+	if saved.Version != fileIndexVersion || saved.Root != projectPath {
+		return nil
+	}
+	return saved.Paths
+}
+
+// saveIndex saves the index of a project for the next session
+func (s *FileService) saveIndex(projectPath string, index *fileIndex) error {
+	path := s.indexFile(projectPath)
+	if path == "" {
+		return nil
+	}
+
+	var data bytes.Buffer
+	saved := savedFileIndex{Version: fileIndexVersion, Root: projectPath, Paths: index.paths()}
+	if err := gob.NewEncoder(&data).Encode(saved); err != nil {
+		return fmt.Errorf("failed to encode index: %w", err)
+	}
+
+	if err := os.MkdirAll(s.indexDir, 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	return writeFileAtomic(path, data.Bytes(), 0644)
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sync"
+)
+
+// maxReplaceUndo is how many replace operations can be undone, the oldest are forgotten
+const maxReplaceUndo = 20
+
+// ReplaceFile is a file picked from a preview to apply a replace to
+type ReplaceFile struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"` // Hash of the contents the preview was made from
+}
+
+// ReplaceFilePreview is what a replace would change in one file
+type ReplaceFilePreview struct {
+	Path    string    `json:"path"`
+	Hash    string    `json:"hash"`    // Hash of the current contents, to pass back to ApplyReplace
+	Matches int       `json:"matches"` // Replacements in the file
+	Diff    *FileDiff `json:"diff"`
+}
+
+// ReplacePreview is what a replace would change in a project, without writing anything
+type ReplacePreview struct {
+	Files   []ReplaceFilePreview `json:"files"`
+	Matches int                  `json:"matches"`
+}
+
+// ReplaceResult lists the files a replace, or its undo, wrote
+type ReplaceResult struct {
+	OperationID int      `json:"operationId"` // Pass to UndoReplace to revert the replace
+	Files       []string `json:"files"`
+	Skipped     []string `json:"skipped"` // Files changed since the preview or the replace, left untouched
+}
+
+// replaceOperation is an applied replace that can be undone
+type replaceOperation struct {
+	id    int
+	files []replacedFile
+}
+
+// replacedFile is a file written by a replace, with what is needed to revert it
+type replacedFile struct {
+	path      string
+	original  []byte
+	mode      os.FileMode
+	writtenAs string // Hash of the contents written
+}
+
+// PreviewReplace returns the diff each file of a project would get if the matches of a
+// search were replaced. With a regular expression, $1 or ${name} in the replacement stand
+// for the groups of each match. Nothing is written.
+func (s *FileService) PreviewReplace(ctx context.Context, projectPath string, query string, replacement string, opts SearchOptions) (*ReplacePreview, error) {
+	pattern, include, exclude, err := compileSearch(query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	err = s.walkSearchFiles(ctx, projectPath, include, exclude, func(path string) {
+		paths = append(paths, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make([]*ReplaceFilePreview, len(paths))
+	work := make(chan int)
+	var workers sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i := range work {
+				// Unreadable files or files gone since the walk have nothing to preview
+				previews[i], _ = previewReplaceFile(projectPath, paths[i], pattern, replacement, opts.Regex)
+			}
+		}()
+	}
+	for i := range paths {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- i
+	}
+	close(work)
+	workers.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	preview := &ReplacePreview{Files: []ReplaceFilePreview{}}
+	for _, file := range previews {
+		if file != nil {
+			preview.Files = append(preview.Files, *file)
+			preview.Matches += file.Matches
+		}
+	}
+
+	return preview, nil
+}
+
+// previewReplaceFile returns the diff a replace would make to a file, nil when it has no match
+func previewReplaceFile(projectPath, path string, pattern *regexp.Regexp, replacement string, expand bool) (*ReplaceFilePreview, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if isContentBinary(content) {
+		return nil, nil
+	}
+
+	replaced, count := replaceMatches(content, pattern, replacement, expand)
+	if count == 0 {
+		return nil, nil
+	}
+
+	relPath, err := filepath.Rel(projectPath, path)
+	if err != nil {
+		return nil, err
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	return &ReplaceFilePreview{
+		Path:    path,
+		Hash:    contentHash(content),
+		Matches: count,
+		Diff:    generateDiff(diffSide{content: string(content), exists: true}, diffSide{content: string(replaced), exists: true}, relPath, relPath, DiffOptions{}),
+	}, nil
+}
+
+// ApplyReplace replaces the matches of a search in the files picked from a preview. Each
+// file is written atomically. Files changed since the preview are skipped, so a replace never
+// overwrites edits made in between. The whole replace can be reverted with UndoReplace.
+// Nothing is written when a file is outside the project.
+func (s *FileService) ApplyReplace(projectPath string, query string, replacement string, opts SearchOptions, files []ReplaceFile) (*ReplaceResult, error) {
+	pattern, _, _, err := compileSearch(query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		if _, err := projectRelPath(projectPath, file.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ReplaceResult{Files: []string{}, Skipped: []string{}}
+	var replaced []replacedFile
+
+	for _, file := range files {
+		// Renaming over a symlink would replace the link rather than its target
+		info, err := os.Lstat(file.Path)
+		if err != nil || !info.Mode().IsRegular() {
+			result.Skipped = append(result.Skipped, file.Path)
+			continue
+		}
+
+		content, err := os.ReadFile(file.Path)
+		if err != nil {
+			return nil, s.abortReplace(replaced, fmt.Errorf("failed to read %s: %w", file.Path, err))
+		}
+		if contentHash(content) != file.Hash {
+			result.Skipped = append(result.Skipped, file.Path)
+			continue
+		}
+
+		newContent, count := replaceMatches(content, pattern, replacement, opts.Regex)
+		if count == 0 {
+			continue
+		}
+
+		if err := writeFileAtomic(file.Path, newContent, info.Mode().Perm()); err != nil {
+			return nil, s.abortReplace(replaced, err)
+		}
+
+		replaced = append(replaced, replacedFile{
+			path:      file.Path,
+			original:  content,
+			mode:      info.Mode().Perm(),
+			writtenAs: contentHash(newContent),
+		})
+		result.Files = append(result.Files, file.Path)
+	}
+
+	if len(replaced) > 0 {
+		result.OperationID = s.recordReplace(replaced)
+	}
+
+	return result, nil
+}
+
+// abortReplace reverts the files a failed replace already wrote and returns its error
+func (s *FileService) abortReplace(replaced []replacedFile, err error) error {
+	for _, file := range replaced {
+		if restoreErr := writeFileAtomic(file.path, file.original, file.mode); restoreErr != nil {
+			return fmt.Errorf("%w, and failed to restore %s: %v", err, file.path, restoreErr)
+		}
+	}
+	return err
+}
+
+// UndoReplace reverts the files written by a replace. Files edited since are skipped.
+func (s *FileService) UndoReplace(operationID int) (*ReplaceResult, error) {
+	s.replaceLock.Lock()
+	defer s.replaceLock.Unlock()
+
+	index := -1
+	for i, operation := range s.replaces {
+		if operation.id == operationID {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, fmt.Errorf("replace %d can't be undone anymore", operationID)
+	}
+	operation := s.replaces[index]
+
+	result := &ReplaceResult{OperationID: operationID, Files: []string{}, Skipped: []string{}}
+	for _, file := range operation.files {
+		content, err := os.ReadFile(file.path)
+		if err != nil || contentHash(content) != file.writtenAs {
+			result.Skipped = append(result.Skipped, file.path)
+			continue
+		}
+		if err := writeFileAtomic(file.path, file.original, file.mode); err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, file.path)
+	}
+
+	s.replaces = append(s.replaces[:index], s.replaces[index+1:]...)
+	return result, nil
+}
+
+// recordReplace keeps what is needed to undo a replace and returns its id
+func (s *FileService) recordReplace(files []replacedFile) int {
+	s.replaceLock.Lock()
+	defer s.replaceLock.Unlock()
+
+	s.replaceID++
+	s.replaces = append(s.replaces, replaceOperation{id: s.replaceID, files: files})
+	if len(s.replaces) > maxReplaceUndo {
+		s.replaces = s.replaces[len(s.replaces)-maxReplaceUndo:]
+	}
+	return s.replaceID
+}
+
+// compileSearch compiles the pattern and globs of a search
+func compileSearch(query string, opts SearchOptions) (*regexp.Regexp, []*regexp.Regexp, []*regexp.Regexp, error) {
+	pattern, err := compileSearchPattern(query, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	include, err := compileGlobs(opts.Include)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	exclude, err := compileGlobs(opts.Exclude)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return pattern, include, exclude, nil
+}
+
+// replaceMatches replaces the non-empty matches of a pattern and returns how many it
+// replaced. When expand is set, $1 and ${name} in the replacement stand for groups.
+func replaceMatches(content []byte, pattern *regexp.Regexp, replacement string, expand bool) ([]byte, int) {
+	matches := pattern.FindAllSubmatchIndex(content, -1)
+
+	var result []byte
+	count := 0
+	last := 0
+	for _, match := range matches {
+		if match[0] == match[1] {
+			// Like search, empty matches don't count
+			continue
+		}
+		result = append(result, content[last:match[0]]...)
+		if expand {
+			result = pattern.Expand(result, []byte(replacement), content, match)
+		} else {
+			result = append(result, replacement...)
+		}
+		last = match[1]
+		count++
+	}
+	if count == 0 {
+		return content, 0
+	}
+
+	return append(result, content[last:]...), count
+}
+
+// contentHash identifies the contents of a file
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomic replaces a file by writing a temporary file next to it and renaming it
+// over the file, so readers never see it half written
+func writeFileAtomic(path string, content []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
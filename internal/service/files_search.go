@@ -0,0 +1,471 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Defaults and limits of a content search
+const (
+	defaultSearchMaxResults = 10000
+	maxSearchFileSize       = 10 << 20 // Larger files are skipped, they are rarely source
+	searchBatchSize         = 200
+	searchBatchDelay        = 50 * time.Millisecond // Longest a match waits before being sent
+)
+
+// SearchOptions configures a content search
+type SearchOptions struct {
+	Regex         bool     `json:"regex"`         // The query is a regular expression rather than literal text
+	CaseSensitive bool     `json:"caseSensitive"` // Match case, searches ignore it by default
+	WholeWord     bool     `json:"wholeWord"`     // Only match whole words
+	Include       []string `json:"include"`       // Globs of the files to search, all files when empty
+	Exclude       []string `json:"exclude"`       // Globs of the files and directories to skip
+	ContextLines  int      `json:"contextLines"`  // Lines to return before and after each match
+	MaxResults    int      `json:"maxResults"`    // Stop after this many matches, defaults to 10000
+}
+
+// SearchMatch is one match of a content search
+type SearchMatch struct {
+	Path   string   `json:"path"`
+	Line   int      `json:"line"`   // 1-based
+	Column int      `json:"column"` // 1-based, in characters
+	Length int      `json:"length"` // In characters
+	Text   string   `json:"text"`   // The matching line
+	Before []string `json:"before"` // Context lines before the match, closest last
+	After  []string `json:"after"`  // Context lines after the match
+}
+
+// SearchResultsEvent is emitted as "search:results" with a batch of matches
+type SearchResultsEvent struct {
+	SearchID int           `json:"searchId"`
+	Matches  []SearchMatch `json:"matches"`
+}
+
+// SearchStartedEvent is emitted as "search:started" before any result of a search.
+// Results of other searches arriving after it are stale.
+type SearchStartedEvent struct {
+	SearchID    int    `json:"searchId"`
+	ProjectPath string `json:"projectPath"`
+	Query       string `json:"query"`
+}
+
+// SearchSummary describes a finished search, it is also emitted as "search:done"
+type SearchSummary struct {
+	SearchID  int  `json:"searchId"`
+	Matches   int  `json:"matches"`
+	Files     int  `json:"files"`     // Files with at least one match
+	Searched  int  `json:"searched"`  // Files searched
+	Truncated bool `json:"truncated"` // Stopped at MaxResults
+	Cancelled bool `json:"cancelled"` // Stopped by CancelSearch or a newer search
+}
+
+// contentSearch is the search in progress
+type contentSearch struct {
+	id     int
+	cancel context.CancelFunc
+}
+
+// SearchContent searches the contents of the files of a project, skipping ignored and binary
+// files. Files are searched in parallel and matches are emitted in batches as they are found;
+// the returned summary comes once the search is over. Starting a search cancels the
+// previous one, whose events stop at once.
+func (s *FileService) SearchContent(ctx context.Context, projectPath string, query string, opts SearchOptions) (*SearchSummary, error) {
+	pattern, include, exclude, err := compileSearch(query, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	maxResults := opts.MaxResults
+	if maxResults <= 0 {
+		maxResults = defaultSearchMaxResults
+	}
+
+	ctx, search := s.startSearch(ctx)
+	defer s.endSearch(search)
+
+	summary := &SearchSummary{SearchID: search.id}
+	s.emitSearch(search.id, "search:started", SearchStartedEvent{SearchID: search.id, ProjectPath: projectPath, Query: query})
+
+	paths := make(chan string, 256)
+	results := make(chan []SearchMatch, 64)
+	var walkErr error
+
+	// Walk the project, handing files to the workers
+	go func() {
+		defer close(paths)
+		walkErr = s.walkSearchFiles(ctx, projectPath, include, exclude, func(path string) {
+			select {
+			case paths <- path:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for path := range paths {
+				if ctx.Err() != nil {
+					continue
+				}
+				matches, err := searchFile(path, pattern, opts.ContextLines)
+				if err != nil {
+					// Unreadable or gone since the walk
+					matches = nil
+				}
+				select {
+				case results <- matches:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(results)
+	}()
+
+	// Gather the matches into batches, sent when full or after searchBatchDelay
+	var batch []SearchMatch
+	flush := func() {
+		if len(batch) > 0 {
+			s.emitSearch(search.id, "search:results", SearchResultsEvent{SearchID: search.id, Matches: batch})
+			batch = nil
+		}
+	}
+	ticker := time.NewTicker(searchBatchDelay)
+	defer ticker.Stop()
+
+	for done := false; !done; {
+		select {
+		case matches, ok := <-results:
+			if !ok {
+				done = true
+				break
+			}
+			summary.Searched++
+			if len(matches) == 0 || summary.Truncated {
+				break
+			}
+			if remaining := maxResults - summary.Matches; len(matches) >= remaining {
+				matches = matches[:remaining]
+				summary.Truncated = true
+				search.cancel()
+			}
+			summary.Matches += len(matches)
+			summary.Files++
+			batch = append(batch, matches...)
+			if len(batch) >= searchBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+	flush()
+
+	if walkErr != nil && !errors.Is(walkErr, context.Canceled) {
+		return nil, walkErr
+	}
+	summary.Cancelled = ctx.Err() != nil && !summary.Truncated
+
+	s.emitSearch(search.id, "search:done", summary)
+	return summary, nil
+}
+
+// CancelSearch stops the search in progress, if any
+func (s *FileService) CancelSearch() {
+	s.searchLock.Lock()
+	defer s.searchLock.Unlock()
+
+	if s.search != nil {
+		s.search.cancel()
+	}
+}
+
+// startSearch cancels the search in progress and registers a new one
+func (s *FileService) startSearch(ctx context.Context) (context.Context, *contentSearch) {
+	s.searchLock.Lock()
+	defer s.searchLock.Unlock()
+
+	if s.search != nil {
+		s.search.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s.searchID++
+	s.search = &contentSearch{id: s.searchID, cancel: cancel}
+	return ctx, s.search
+}
+
+// endSearch releases a finished search
+func (s *FileService) endSearch(search *contentSearch) {
+	s.searchLock.Lock()
+	defer s.searchLock.Unlock()
+
+	search.cancel()
+	if s.search == search {
+		s.search = nil
+	}
+}
+
+// emitSearch emits an event of a search unless a newer search started since
+func (s *FileService) emitSearch(id int, name string, data interface{}) {
+	s.searchLock.Lock()
+	defer s.searchLock.Unlock()
+
+	if id == s.searchID {
+		s.emit(name, data)
+	}
+}
+
+// compileSearchPattern turns a query into a regular expression according to the options
+func compileSearchPattern(query string, opts SearchOptions) (*regexp.Regexp, error) {
+	if query == "" {
+		return nil, errors.New("search query is empty")
+	}
+
+	expr := query
+	if !opts.Regex {
+		expr = regexp.QuoteMeta(query)
+	} else if _, err := regexp.Compile(query); err != nil {
+		return nil, fmt.Errorf("invalid search pattern: %w", err)
+	}
+
+	if opts.WholeWord {
+		// A literal only gets word boundaries on the sides that are part of a word
+		first, _ := utf8.DecodeRuneInString(query)
+		last, _ := utf8.DecodeLastRuneInString(query)
+		if opts.Regex || isWordRune(first) {
+			expr = `\b(?:` + expr + `)`
+		}
+		if opts.Regex || isWordRune(last) {
+			expr = `(?:` + expr + `)\b`
+		}
+	}
+
+	if !opts.CaseSensitive {
+		expr = "(?i)" + expr
+	}
+
+	pattern, err := regexp.Compile("(?m)" + expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search pattern: %w", err)
+	}
+	return pattern, nil
+}
+
+// isWordRune reports whether a rune is part of a word for \b
+func isWordRune(r rune) bool {
+	return r == '_' || (r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+}
+
+// compileGlobs compiles include or exclude globs, see globPattern
+func compileGlobs(globs []string) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(globs))
+	for _, glob := range globs {
+		glob = strings.TrimSpace(glob)
+		if glob == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(globPattern(glob))
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// globPattern turns a glob into a regular expression matching slash separated paths relative
+// to the project. * and ? don't cross directories, ** does, and {a,b} matches either. A glob
+// without a slash matches at any depth, and a glob matching a directory matches everything
+// under it.
+func globPattern(glob string) string {
+	glob = strings.TrimPrefix(strings.TrimSuffix(filepath.ToSlash(glob), "/"), "./")
+	if !strings.Contains(glob, "/") {
+		glob = "**/" + glob
+	}
+	glob = strings.TrimPrefix(glob, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	inBraces := false
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case c == '*' && strings.HasPrefix(glob[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '{':
+			inBraces = true
+			expr.WriteString("(?:")
+		case c == '}' && inBraces:
+			inBraces = false
+			expr.WriteString(")")
+		case c == ',' && inBraces:
+			expr.WriteString("|")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("(?:/.*)?$")
+	return expr.String()
+}
+
+// matchesAnyGlob reports whether a relative path matches one of the globs
+func matchesAnyGlob(patterns []*regexp.Regexp, relPath string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkSearchFiles calls found for each file of the project to search: not ignored, not
+// excluded, included when there are include globs, and not too large
+func (s *FileService) walkSearchFiles(ctx context.Context, projectPath string, include, exclude []*regexp.Regexp, found func(path string)) error {
+	return filepath.WalkDir(projectPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path != projectPath {
+				// Unreadable directories and files removed while walking are skipped
+				return nil
+			}
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if path == projectPath {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(projectPath, path)
+		if err != nil {
+			return nil
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if d.Name() == ".git" || s.isIgnored(projectPath, path, d.IsDir()) || matchesAnyGlob(exclude, relPath) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		if len(include) > 0 && !matchesAnyGlob(include, relPath) {
+			return nil
+		}
+
+		if info, err := d.Info(); err != nil || info.Size() > maxSearchFileSize {
+			return nil
+		}
+
+		found(path)
+		return nil
+	})
+}
+
+// searchFile returns the matches of a pattern in a file, none for binary files
+func searchFile(path string, pattern *regexp.Regexp, contextLines int) ([]SearchMatch, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if isContentBinary(content) {
+		return nil, nil
+	}
+
+	locations := pattern.FindAllIndex(content, -1)
+	if len(locations) == 0 {
+		return nil, nil
+	}
+
+	// Offsets at which each line starts, to find the line of a match and its context
+	lineStarts := []int{0}
+	for i, c := range content {
+		if c == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	lineText := func(line int) string {
+		start := lineStarts[line]
+		end := len(content)
+		if line+1 < len(lineStarts) {
+			end = lineStarts[line+1] - 1
+		}
+		return string(bytes.TrimSuffix(content[start:end], []byte("\r")))
+	}
+
+	matches := make([]SearchMatch, 0, len(locations))
+	line := 0
+	for _, location := range locations {
+		start, end := location[0], location[1]
+		if start == end {
+			// Empty matches, like ^ alone, would match every line
+			continue
+		}
+		for line+1 < len(lineStarts) && lineStarts[line+1] <= start {
+			line++
+		}
+
+		// A match over several lines is reported on its first line
+		matchEnd := end
+		if lineEnd := bytes.IndexByte(content[start:end], '\n'); lineEnd >= 0 {
+			matchEnd = start + lineEnd
+		}
+
+		match := SearchMatch{
+			Path:   path,
+			Line:   line + 1,
+			Column: utf8.RuneCount(content[lineStarts[line]:start]) + 1,
+			Length: utf8.RuneCount(content[start:matchEnd]),
+			Text:   lineText(line),
+			Before: []string{},
+			After:  []string{},
+		}
+		for i := max(line-contextLines, 0); i < line; i++ {
+			match.Before = append(match.Before, lineText(i))
+		}
+		for i := line + 1; i <= line+contextLines && i < len(lineStarts); i++ {
+			// No empty line after a final newline
+			if i == len(lineStarts)-1 && lineStarts[i] == len(content) {
+				break
+			}
+			match.After = append(match.After, lineText(i))
+		}
+
+		matches = append(matches, match)
+	}
+
+	return matches, nil
+}
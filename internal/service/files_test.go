@@ -0,0 +1,40 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestProjectRelPath checks which paths are in a project, with a symlinked directory
+// that leads out of it
+func TestProjectRelPath(t *testing.T) {
+	outside := t.TempDir()
+	project := t.TempDir()
+	writeTestFile(t, project, "..env.sample", "KEY=\n")
+	writeTestFile(t, project, "src/main.go", "package main\n")
+	writeTestFile(t, outside, "secret.txt", "secret\n")
+	if err := os.Symlink(outside, filepath.Join(project, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]string{
+		filepath.Join(project, "..env.sample"):       "..env.sample",
+		filepath.Join(project, "src", "main.go"):     "src/main.go",
+		filepath.Join(project, "src", "new.go"):      "src/new.go",
+		filepath.Join(project, "link", "secret.txt"): "",
+		filepath.Join(project, "..", "secret.txt"):   "",
+		filepath.Join(outside, "secret.txt"):         "",
+	} {
+		relPath, err := projectRelPath(project, path)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", path, relPath)
+			}
+			continue
+		}
+		if err != nil || relPath != expected {
+			t.Errorf("%s: expected %s, got %q (%v)", path, expected, relPath, err)
+		}
+	}
+}
@@ -0,0 +1,712 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the watcher waits for a burst of changes to settle before
+// reporting them, and watchMaxDelay how long a burst that doesn't settle is held at most
+const (
+	watchDebounce = 150 * time.Millisecond
+	watchMaxDelay = time.Second
+)
+
+// FileRename is a file or directory that was moved
+type FileRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// FileChangeEvent is emitted as "files:changed" with the changes to a watched project.
+// Directories created, deleted or renamed are reported once, not file by file.
+type FileChangeEvent struct {
+	ProjectPath string       `json:"projectPath"`
+	Created     []string     `json:"created"`
+	Modified    []string     `json:"modified"`
+	Deleted     []string     `json:"deleted"`
+	Renamed     []FileRename `json:"renamed"`
+	Rescanned   bool         `json:"rescanned"` // Changes were lost, reload the whole tree
+}
+
+// GitStatusChangedEvent is emitted as "git:status-changed" when a change in a watched
+// project may have changed its git status
+type GitStatusChangedEvent struct {
+	ProjectPath string `json:"projectPath"`
+}
+
+// projectWatcher follows the changes to the files of a project
+type projectWatcher struct {
+	root    string
+	gitDir  string
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+	stopped sync.Once
+
+	// Paths of the project that aren't ignored, and whether they are directories.
+	// Only touched by the watch loop, which also keeps the files in index.
+	paths map[string]bool
+	index *fileIndex
+	// Set once adding a watch failed, so the failure is only logged once
+	watchFailed bool
+}
+
+// watchBatch gathers the events of a burst of changes
+type watchBatch struct {
+	touched    map[string]bool // Paths with events, in order
+	order      []string
+	renames    []FileRename
+	ignores    map[string]bool // Directories whose .gitignore changed
+	gitChanged bool
+	rescan     bool
+}
+
+// WatchProject starts following changes to the files of a project. The cached tree and the
+// file index are patched as they happen, and "files:changed" and "git:status-changed" events
+// are emitted.
+// Ignored files and the contents of .git are not watched, only the files in .git that make
+// up the state of the repository.
+func (s *FileService) WatchProject(projectPath string) error {
+	s.watchersLock.Lock()
+	defer s.watchersLock.Unlock()
+
+	if _, ok := s.watchers[projectPath]; ok {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	w := &projectWatcher{
+		root:    projectPath,
+		gitDir:  watchedGitDir(projectPath),
+		watcher: watcher,
+		done:    make(chan struct{}),
+		paths:   make(map[string]bool),
+		index:   newFileIndex(),
+	}
+	s.watchers[projectPath] = w
+
+	go s.runWatcher(w)
+	return nil
+}
+
+// UnwatchProject stops following changes to a project and drops its cached tree and
+// gitignore rules, which would otherwise go stale. Its file index is saved for next time.
+func (s *FileService) UnwatchProject(projectPath string) {
+	s.watchersLock.Lock()
+	w, ok := s.watchers[projectPath]
+	delete(s.watchers, projectPath)
+	s.watchersLock.Unlock()
+
+	if ok {
+		w.stop()
+		if err := s.saveIndex(w.root, w.index); err != nil {
+			log.Printf("[FileService] Failed to save the index of %s: %v", w.root, err)
+		}
+	}
+
+	s.cacheLock.Lock()
+	delete(s.cache, projectPath)
+	s.cacheLock.Unlock()
+
+	s.forgetGitIgnores(projectPath)
+}
+
+// stop ends the watch loop and releases the watches
+func (w *projectWatcher) stop() {
+	w.stopped.Do(func() {
+		close(w.done)
+		w.watcher.Close()
+	})
+}
+
+// watchedGitDir returns the git directory of a project, following the .git file of linked
+// worktrees and submodules. It returns an empty path when the project isn't a repository.
+func watchedGitDir(projectPath string) string {
+	dotGit := filepath.Join(projectPath, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		return dotGit
+	}
+
+	data, err := os.ReadFile(dotGit)
+	if err != nil {
+		return ""
+	}
+	dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	dir = strings.TrimSpace(dir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(projectPath, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// runWatcher adds the watches of a project and indexes its files, then reports its changes
+// in debounced batches until the watcher is stopped. A saved index answers searches while
+// the project is walked.
+func (s *FileService) runWatcher(w *projectWatcher) {
+	if saved := s.loadIndex(w.root); saved != nil {
+		w.index.replace(saved)
+		w.index.markReady()
+	}
+	if err := s.addWatches(w, w.root, nil); err != nil {
+		log.Printf("[FileService] Failed to watch %s: %v", w.root, err)
+	}
+	w.index.replace(w.files())
+	w.index.markReady()
+	if err := s.saveIndex(w.root, w.index); err != nil {
+		log.Printf("[FileService] Failed to save the index of %s: %v", w.root, err)
+	}
+	if w.gitDir != "" {
+		// The index, HEAD and the reflog of HEAD change with every operation that changes the status
+		for _, dir := range []string{w.gitDir, filepath.Join(w.gitDir, "logs")} {
+			if err := w.watcher.Add(dir); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				log.Printf("[FileService] Failed to watch %s: %v", dir, err)
+			}
+		}
+	}
+
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	batch := newWatchBatch()
+	var started time.Time
+	var pendingRename *fsnotify.Event
+
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			// A move arrives as a Rename of the old path directly followed by a Create of the new one
+			if pendingRename != nil {
+				from := pendingRename.Name
+				pendingRename = nil
+				if event.Has(fsnotify.Create) {
+					s.addRename(w, batch, from, event.Name)
+					break
+				}
+				s.addEvent(w, batch, fsnotify.Event{Name: from, Op: fsnotify.Remove})
+			}
+			if event.Has(fsnotify.Rename) {
+				pendingRename = &event
+			} else {
+				s.addEvent(w, batch, event)
+			}
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				log.Printf("[FileService] Watcher error for %s: %v", w.root, err)
+				continue
+			}
+			batch.rescan = true
+
+		case <-timer.C:
+			if pendingRename != nil {
+				s.addEvent(w, batch, fsnotify.Event{Name: pendingRename.Name, Op: fsnotify.Remove})
+				pendingRename = nil
+			}
+			s.flushChanges(w, batch)
+			batch = newWatchBatch()
+			started = time.Time{}
+			continue
+		}
+
+		if batch.empty() && pendingRename == nil {
+			continue
+		}
+
+		// Wait for the burst to settle, but not longer than watchMaxDelay since it started
+		if started.IsZero() {
+			started = time.Now()
+		}
+		wait := min(watchDebounce, watchMaxDelay-time.Since(started))
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(max(wait, 0))
+	}
+}
+
+// newWatchBatch returns an empty batch
+func newWatchBatch() *watchBatch {
+	return &watchBatch{touched: make(map[string]bool), ignores: make(map[string]bool)}
+}
+
+// empty reports whether the batch has nothing to report
+func (b *watchBatch) empty() bool {
+	return len(b.order) == 0 && len(b.renames) == 0 && len(b.ignores) == 0 && !b.gitChanged && !b.rescan
+}
+
+// touch records an event for a path
+func (b *watchBatch) touch(path string) {
+	if !b.touched[path] {
+		b.touched[path] = true
+		b.order = append(b.order, path)
+	}
+}
+
+// addEvent records an event in the batch, unless it is for an ignored path
+func (s *FileService) addEvent(w *projectWatcher, batch *watchBatch, event fsnotify.Event) {
+	// Attribute changes alone don't change contents
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+
+	if w.gitDir != "" && (event.Name == w.gitDir || strings.HasPrefix(event.Name, w.gitDir+string(filepath.Separator))) {
+		if !strings.HasSuffix(event.Name, ".lock") {
+			batch.gitChanged = true
+		}
+		return
+	}
+
+	if !s.watchesPath(w, event.Name, w.isDir(event.Name)) {
+		return
+	}
+
+	if filepath.Base(event.Name) == ".gitignore" {
+		s.forgetGitIgnore(filepath.Dir(event.Name))
+		batch.ignores[filepath.Dir(event.Name)] = true
+	}
+
+	batch.touch(event.Name)
+	batch.gitChanged = true
+}
+
+// addRename records a move in the batch. A move out of the project or into an ignored
+// path is a deletion, a move from an ignored path a creation.
+func (s *FileService) addRename(w *projectWatcher, batch *watchBatch, from, to string) {
+	fromWatched := s.watchesPath(w, from, w.isDir(from))
+	toWatched := s.watchesPath(w, to, w.isDir(to))
+
+	switch {
+	case fromWatched && toWatched:
+		batch.renames = append(batch.renames, FileRename{From: from, To: to})
+		batch.gitChanged = true
+	case fromWatched:
+		s.addEvent(w, batch, fsnotify.Event{Name: from, Op: fsnotify.Remove})
+	default:
+		// Also how git replaces the files in .git, through a lock file
+		s.addEvent(w, batch, fsnotify.Event{Name: to, Op: fsnotify.Create})
+	}
+}
+
+// watchesPath reports whether a path is part of the project and not ignored
+func (s *FileService) watchesPath(w *projectWatcher, path string, isDir bool) bool {
+	if !strings.HasPrefix(path, w.root+string(filepath.Separator)) {
+		return false
+	}
+	if filepath.Base(path) == ".git" {
+		return false
+	}
+	return !s.isIgnored(w.root, path, isDir)
+}
+
+// setPath records a path of the project, and indexes it if it is a file
+func (w *projectWatcher) setPath(path string, isDir bool) {
+	w.paths[path] = isDir
+	if !isDir {
+		w.index.add(w.relPath(path))
+	}
+}
+
+// deletePath forgets a path of the project
+func (w *projectWatcher) deletePath(path string) {
+	if isDir, known := w.paths[path]; known && !isDir {
+		w.index.remove(w.relPath(path))
+	}
+	delete(w.paths, path)
+}
+
+// relPath returns the path of a file of the project as the index has it
+func (w *projectWatcher) relPath(path string) string {
+	return filepath.ToSlash(strings.TrimPrefix(path, w.root+string(filepath.Separator)))
+}
+
+// files returns the known files of the project as the index has them
+func (w *projectWatcher) files() []string {
+	files := make([]string, 0, len(w.paths))
+	for path, isDir := range w.paths {
+		if !isDir {
+			files = append(files, w.relPath(path))
+		}
+	}
+	return files
+}
+
+// isDir reports whether a path is a directory, as last known or as on disk
+func (w *projectWatcher) isDir(path string) bool {
+	if isDir, known := w.paths[path]; known {
+		return isDir
+	}
+	info, err := os.Lstat(path)
+	return err == nil && info.IsDir()
+}
+
+// addWatches watches a directory and the directories under it that aren't ignored, and
+// records their paths. New paths are passed to found, if set. Directories that can't be
+// watched, past the system's limit on watches, are still recorded.
+func (s *FileService) addWatches(w *projectWatcher, dir string, found func(path string)) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Removed while walking, its events will tell
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if path != w.root {
+			if !s.watchesPath(w, path, d.IsDir()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if _, known := w.paths[path]; !known && found != nil {
+				found(path)
+			}
+			w.setPath(path, d.IsDir())
+		}
+
+		if d.IsDir() {
+			if err := w.watcher.Add(path); err != nil && !errors.Is(err, fs.ErrNotExist) && !w.watchFailed {
+				log.Printf("[FileService] Failed to watch %s: %v", path, err)
+				w.watchFailed = true
+			}
+		}
+		return nil
+	})
+}
+
+// removeWatches forgets a path and everything under it
+func (s *FileService) removeWatches(w *projectWatcher, path string) {
+	prefix := path + string(filepath.Separator)
+	for known, isDir := range w.paths {
+		if known != path && !strings.HasPrefix(known, prefix) {
+			continue
+		}
+		if isDir {
+			// Already gone when the directory was deleted
+			_ = w.watcher.Remove(known)
+		}
+		w.deletePath(known)
+	}
+}
+
+// flushChanges works out what a batch of events changed, by comparing the paths known
+// before it with what is on disk now. It then patches the cached tree and emits the events.
+func (s *FileService) flushChanges(w *projectWatcher, batch *watchBatch) {
+	if batch.rescan {
+		s.rescanProject(w)
+		return
+	}
+
+	changes := FileChangeEvent{
+		ProjectPath: w.root,
+		Created:     []string{},
+		Modified:    []string{},
+		Deleted:     []string{},
+		Renamed:     []FileRename{},
+	}
+	reported := make(map[string]bool)
+
+	for dir := range batch.ignores {
+		s.resyncIgnored(w, dir)
+	}
+
+	for _, rename := range batch.renames {
+		isDir, known := w.paths[rename.From]
+		if !known || pathExists(rename.From) || !pathExists(rename.To) {
+			// Not a move of a known path after all, settle both ends by what is on disk
+			batch.touch(rename.From)
+			batch.touch(rename.To)
+			continue
+		}
+
+		s.removeWatches(w, rename.From)
+		if isDir {
+			if err := s.addWatches(w, rename.To, nil); err != nil {
+				log.Printf("[FileService] Failed to watch %s: %v", rename.To, err)
+			}
+		} else {
+			w.setPath(rename.To, false)
+		}
+
+		changes.Renamed = append(changes.Renamed, rename)
+		reported[rename.From] = true
+		reported[rename.To] = true
+	}
+
+	// Parents first, so a directory created or deleted with its contents is reported alone
+	sort.Strings(batch.order)
+	for _, path := range batch.order {
+		if reported[path] || reportedParent(reported, path) {
+			continue
+		}
+
+		_, known := w.paths[path]
+		info, err := os.Lstat(path)
+		exists := err == nil
+
+		switch {
+		case known && !exists:
+			s.removeWatches(w, path)
+			changes.Deleted = append(changes.Deleted, path)
+		case !known && exists:
+			if info.IsDir() {
+				if err := s.addWatches(w, path, nil); err != nil {
+					log.Printf("[FileService] Failed to watch %s: %v", path, err)
+				}
+			} else {
+				w.setPath(path, false)
+			}
+			changes.Created = append(changes.Created, path)
+		case known && exists && !info.IsDir():
+			changes.Modified = append(changes.Modified, path)
+		default:
+			continue
+		}
+		reported[path] = true
+	}
+
+	if len(changes.Created)+len(changes.Modified)+len(changes.Deleted)+len(changes.Renamed) > 0 {
+		s.patchTree(w.root, changes)
+		s.emit("files:changed", changes)
+	}
+	if batch.gitChanged && w.gitDir != "" {
+		s.emit("git:status-changed", GitStatusChangedEvent{ProjectPath: w.root})
+	}
+}
+
+// resyncIgnored brings the known paths under a directory in line with its changed .gitignore:
+// paths it now ignores are forgotten and those it no longer ignores are recorded. The tree
+// doesn't hide ignored files, so nothing is reported.
+func (s *FileService) resyncIgnored(w *projectWatcher, dir string) {
+	if dir != w.root {
+		if _, known := w.paths[dir]; !known {
+			return
+		}
+	}
+
+	prefix := dir + string(filepath.Separator)
+	for path, isDir := range w.paths {
+		if strings.HasPrefix(path, prefix) && s.isIgnored(w.root, path, isDir) {
+			s.removeWatches(w, path)
+		}
+	}
+	if err := s.addWatches(w, dir, nil); err != nil {
+		log.Printf("[FileService] Failed to watch %s: %v", dir, err)
+	}
+}
+
+// reportedParent reports whether a directory above a path is already reported, which
+// covers the path too
+func reportedParent(reported map[string]bool, path string) bool {
+	for dir := filepath.Dir(path); dir != path; path, dir = dir, filepath.Dir(dir) {
+		if reported[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// rescanProject starts over after the watcher lost events: every path is watched and
+// recorded again, and the cached tree is dropped so it is built again
+func (s *FileService) rescanProject(w *projectWatcher) {
+	for path, isDir := range w.paths {
+		if isDir {
+			_ = w.watcher.Remove(path)
+		}
+	}
+	w.paths = make(map[string]bool)
+	s.forgetGitIgnores(w.root)
+	if err := s.addWatches(w, w.root, nil); err != nil {
+		log.Printf("[FileService] Failed to watch %s: %v", w.root, err)
+	}
+	w.index.replace(w.files())
+
+	s.cacheLock.Lock()
+	delete(s.cache, w.root)
+	s.cacheLock.Unlock()
+
+	s.emit("files:changed", FileChangeEvent{
+		ProjectPath: w.root,
+		Created:     []string{},
+		Modified:    []string{},
+		Deleted:     []string{},
+		Renamed:     []FileRename{},
+		Rescanned:   true,
+	})
+	if w.gitDir != "" {
+		s.emit("git:status-changed", GitStatusChangedEvent{ProjectPath: w.root})
+	}
+}
+
+// pathExists reports whether a file or directory exists, without following symlinks
+func pathExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// patchTree applies changes to the cached tree of a project. Only directories already
+// loaded are patched, the others load their current contents when opened.
+func (s *FileService) patchTree(projectPath string, changes FileChangeEvent) {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	root, ok := s.cache[projectPath]
+	if !ok {
+		return
+	}
+
+	dirty := make(map[*FileNode]bool)
+
+	for _, path := range changes.Deleted {
+		if parent := s.findNode(root, filepath.Dir(path)); parent != nil {
+			removeChild(parent, path)
+		}
+	}
+
+	for _, rename := range changes.Renamed {
+		var node *FileNode
+		if parent := s.findNode(root, filepath.Dir(rename.From)); parent != nil {
+			node = removeChild(parent, rename.From)
+		}
+
+		parent := s.findNode(root, filepath.Dir(rename.To))
+		if parent == nil || !parent.IsLoaded || isHiddenName(filepath.Base(rename.To)) {
+			continue
+		}
+		if node == nil {
+			if node = newFileNode(rename.To); node == nil {
+				continue
+			}
+		}
+		movePath(node, rename.From, rename.To)
+		node.Name = filepath.Base(rename.To)
+		removeChild(parent, rename.To)
+		parent.Children = append(parent.Children, node)
+		dirty[parent] = true
+	}
+
+	for _, path := range changes.Created {
+		parent := s.findNode(root, filepath.Dir(path))
+		if parent == nil || !parent.IsLoaded || isHiddenName(filepath.Base(path)) {
+			continue
+		}
+		node := newFileNode(path)
+		if node == nil {
+			continue
+		}
+		removeChild(parent, path)
+		parent.Children = append(parent.Children, node)
+		dirty[parent] = true
+	}
+
+	for _, path := range changes.Modified {
+		node := s.findNode(root, path)
+		if node == nil {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			node.Size = info.Size()
+			node.LastModified = info.ModTime()
+		}
+	}
+
+	for parent := range dirty {
+		sortChildren(parent)
+	}
+}
+
+// isHiddenName reports whether the file tree leaves out a file or directory name
+func isHiddenName(name string) bool {
+	return name[0] == '.'
+}
+
+// newFileNode describes a path the way the tree does, directories with their contents not loaded
+func newFileNode(path string) *FileNode {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	node := &FileNode{
+		Name:         filepath.Base(path),
+		Path:         path,
+		LastModified: info.ModTime(),
+	}
+	if info.IsDir() {
+		node.Type = "directory"
+		node.Children = []*FileNode{}
+	} else {
+		node.Type = "file"
+		node.Size = info.Size()
+		node.IsLoaded = true
+	}
+	return node
+}
+
+// removeChild removes the child with the given path from a directory node and returns it
+func removeChild(parent *FileNode, path string) *FileNode {
+	for i, child := range parent.Children {
+		if child.Path == path {
+			parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+			return child
+		}
+	}
+	return nil
+}
+
+// movePath rewrites the paths of a node and its children after it moved
+func movePath(node *FileNode, from, to string) {
+	node.Path = to + strings.TrimPrefix(node.Path, from)
+	for _, child := range node.Children {
+		movePath(child, from, to)
+	}
+}
+
+// sortChildren sorts the children of a directory node like sortFileTree, directories first
+func sortChildren(node *FileNode) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		if node.Children[i].Type != node.Children[j].Type {
+			return node.Children[i].Type == "directory"
+		}
+		return node.Children[i].Name < node.Children[j].Name
+	})
+}
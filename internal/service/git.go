@@ -1,17 +1,22 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/utils/diff"
 	"github.com/sergi/go-diff/diffmatchpatch"
@@ -19,16 +24,30 @@ import (
 
 // FileStatus represents the status of a file in the Git repository
 type FileStatus struct {
-	File   string `json:"file"`   // File path relative to repository root
-	Status string `json:"status"` // Status code: "M" for modified, "A" for added, "D" for deleted and "?" for untracked
-	Staged bool   `json:"staged"` // Whether the file is staged
+	File       string `json:"file"`       // File path relative to repository root
+	Status     string `json:"status"`     // Status code: "M" for modified, "A" for added, "D" for deleted, "R" for renamed, "C" for copied, "?" for untracked and "U" for unmerged
+	Staged     bool   `json:"staged"`     // Whether the file is staged
+	OrigFile   string `json:"origFile"`   // Path the file was renamed or copied from
+	Similarity int    `json:"similarity"` // How similar a renamed or copied file is to the original, in percent
+}
+
+// StatusOptions controls how GetStatusWithOptions reports changes
+type StatusOptions struct {
+	// Report a tracked file missing from the working tree and a similar untracked file as
+	// an unstaged rename. git status only reports renames once both sides are staged.
+	WorktreeRenames bool `json:"worktreeRenames"`
 }
 
 // BranchInfo represents information about a Git branch
 type BranchInfo struct {
-	Name     string `json:"name"`
-	IsRemote bool   `json:"isRemote"`
-	IsHead   bool   `json:"isHead"`
+	Name             string    `json:"name"`
+	IsRemote         bool      `json:"isRemote"`
+	IsHead           bool      `json:"isHead"`
+	Upstream         string    `json:"upstream"`         // Upstream branch, e.g. "origin/main" (local branches only)
+	Ahead            int       `json:"ahead"`            // Commits on the branch that are not on its upstream
+	Behind           int       `json:"behind"`           // Commits on the upstream that are not on the branch
+	LastCommitDate   time.Time `json:"lastCommitDate"`   // Author date of the branch tip
+	LastCommitAuthor string    `json:"lastCommitAuthor"` // Author of the branch tip
 }
 
 // CommitInfo represents information about a Git commit
@@ -39,12 +58,16 @@ type CommitInfo struct {
 	AuthorEmail  string    `json:"authorEmail"`
 	Date         time.Time `json:"date"`
 	ParentHashes []string  `json:"parentHashes"`
-	HasMore      bool      `json:"hasMore"` // Indicates if there are more commits after this one
+	Path         string    `json:"path"`     // Path of the file in this commit, set by FileHistory
+	Signed       bool      `json:"signed"`   // Whether the commit has a GPG, X.509 or SSH signature
+	Verified     *bool     `json:"verified"` // Whether the signature is good and made by a trusted key, nil unless checked: only GetHeadCommit checks it, see VerifyCommits
+	HasMore      bool      `json:"hasMore"`  // Indicates if there are more commits after this one
 }
 
 // CommitFilter contains options for filtering commits
 type CommitFilter struct {
 	Branch      string    `json:"branch"`      // Branch to list commits from
+	Tag         string    `json:"tag"`         // Tag to list commits from, used when no branch is given
 	StartHash   string    `json:"startHash"`   // Start listing from this commit
 	Limit       int       `json:"limit"`       // Max number of commits to return
 	Offset      int       `json:"offset"`      // Skip this many commits (numeric offset)
@@ -58,26 +81,110 @@ type CommitFilter struct {
 // FileDiff represents the diff information for a file
 type FileDiff struct {
 	Path     string    `json:"path"`     // File path
+	OldPath  string    `json:"oldPath"`  // Previous path when the file was renamed
+	Status   string    `json:"status"`   // "A" for added, "M" for modified, "D" for deleted and "R" for renamed
 	Content  string    `json:"content"`  // Diff content in unified format
+	Hunks    []Hunk    `json:"hunks"`    // Structured hunks of the diff
 	Stats    DiffStats `json:"stats"`    // Statistics about the changes
 	IsBinary bool      `json:"isBinary"` // Whether the file is binary
 }
 
+// Hunk represents a block of changes in a unified diff
+type Hunk struct {
+	OldStart int        `json:"oldStart"` // First line of the hunk in the old file
+	OldLines int        `json:"oldLines"` // Number of old file lines in the hunk
+	NewStart int        `json:"newStart"` // First line of the hunk in the new file
+	NewLines int        `json:"newLines"` // Number of new file lines in the hunk
+	Header   string     `json:"header"`   // Hunk header, e.g. "@@ -1,3 +1,4 @@"
+	Lines    []DiffLine `json:"lines"`    // Lines of the hunk, including context
+}
+
+// DiffLine represents a single line in a hunk
+type DiffLine struct {
+	Type      string `json:"type"`      // "context", "add" or "delete"
+	Content   string `json:"content"`   // Line content without the newline
+	OldLine   int    `json:"oldLine"`   // Line number in the old file, 0 for added lines
+	NewLine   int    `json:"newLine"`   // Line number in the new file, 0 for deleted lines
+	NoNewline bool   `json:"noNewline"` // Whether the line is the last one and lacks a newline
+}
+
+// DefaultDiffContextLines is the number of unchanged lines shown around changes
+const DefaultDiffContextLines = 3
+
+// DiffOptions contains options for generating diffs
+type DiffOptions struct {
+	ContextLines    *int `json:"contextLines"`    // Unchanged lines around each change, defaults to 3
+	WorktreeRenames bool `json:"worktreeRenames"` // Diff an untracked file against the missing file it was renamed from, see StatusOptions
+}
+
+// contextLines returns the number of context lines to use
+func (o DiffOptions) contextLines() int {
+	if o.ContextLines == nil || *o.ContextLines < 0 {
+		return DefaultDiffContextLines
+	}
+	return *o.ContextLines
+}
+
 // DiffStats contains statistics about changes in a diff
 type DiffStats struct {
 	Added    int `json:"added"`    // Number of added lines
 	Deleted  int `json:"deleted"`  // Number of deleted lines
-	Modified int `json:"modified"` // Number of modified lines
+	Modified int `json:"modified"` // Number of modified lines (deletions replaced by additions)
 }
 
+// StatusUnmerged is the status code of files with unresolved merge conflicts
+const StatusUnmerged = "U"
+
 // GitService handles Git operations for projects
 type GitService struct {
-	// We might want to add a cache of repositories later
+	onEvent func(name string, data interface{})
+
+	reposMu sync.Mutex
+	repos   map[string]*cachedRepository // Open repositories by absolute project path
+
+	indexesMu sync.Mutex
+	indexes   map[string]*cachedIndex // Decoded indexes by index file path
+
+	signaturesMu sync.Mutex
+	signatures   map[plumbing.Hash]bool // Whether the signature of a commit or tag verifies, by object hash
+	verifyFailed map[string]bool        // Verifier programs that failed to run, logged once
+
+	graphsMu sync.Mutex
+	graphs   map[string]*graphOrder // Order of the last commit graph of each project, by project path
 }
 
-// NewGitService creates a new Git service instance
-func NewGitService() *GitService {
-	return &GitService{}
+// cachedRepository is an open repository and the state of its packs when it was opened
+type cachedRepository struct {
+	repo      *git.Repository
+	packsTime time.Time
+}
+
+// cachedIndex is a decoded index and the stat data and checksum of its file when it was read
+type cachedIndex struct {
+	idx      *index.Index
+	modTime  time.Time
+	size     int64
+	checksum plumbing.Hash
+}
+
+// NewGitService creates a new Git service instance.
+// onEvent is called for progress and change notifications that should reach the frontend.
+func NewGitService(onEvent func(name string, data interface{})) *GitService {
+	return &GitService{
+		onEvent:      onEvent,
+		repos:        make(map[string]*cachedRepository),
+		indexes:      make(map[string]*cachedIndex),
+		signatures:   make(map[plumbing.Hash]bool),
+		verifyFailed: make(map[string]bool),
+		graphs:       make(map[string]*graphOrder),
+	}
+}
+
+// emit forwards an event to the frontend if an event handler is set
+func (s *GitService) emit(name string, data interface{}) {
+	if s.onEvent != nil {
+		s.onEvent(name, data)
+	}
 }
 
 // IsGitRepository checks if the given directory is a Git repository
@@ -91,7 +198,7 @@ func (s *GitService) IsGitRepository(projectPath string) (bool, error) {
 	}
 
 	// Try to open the repository
-	_, err = git.PlainOpen(absPath)
+	_, err = git.PlainOpenWithOptions(absPath, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
 	if err != nil {
 		if errors.Is(err, git.ErrRepositoryNotExists) {
 			// Not a Git repository, but not an error
@@ -133,16 +240,16 @@ func (s *GitService) InitRepository(projectPath string) error {
 // GetStatus returns the current Git status of the repository
 // Returns two slices: staged files and unstaged files
 func (s *GitService) GetStatus(projectPath string) ([]FileStatus, error) {
-	// Ensure we have an absolute path
-	absPath, err := filepath.Abs(projectPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %w", err)
-	}
+	return s.GetStatusWithOptions(projectPath, StatusOptions{})
+}
 
+// GetStatusWithOptions is GetStatus with control over rename detection. Staged renames,
+// and copies with status.renames set to "copies", are detected as git status does.
+func (s *GitService) GetStatusWithOptions(projectPath string, opts StatusOptions) ([]FileStatus, error) {
 	// Open the repository
-	repo, err := git.PlainOpen(absPath)
+	repo, err := s.openRepository(projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open repository: %w", err)
+		return nil, err
 	}
 
 	// Get the working tree
@@ -152,14 +259,51 @@ func (s *GitService) GetStatus(projectPath string) ([]FileStatus, error) {
 	}
 
 	// Get the status
-	status, err := worktree.Status()
+	status, err := s.status(repo, worktree)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get status: %w", err)
 	}
 
+	unmerged, err := s.unmergedPaths(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Renames replace the deletion of the original and the addition of the new file
+	renames, copies, err := s.renameConfig(repo)
+	if err != nil {
+		return nil, err
+	}
+	var staged, unstaged []renamePair
+	if renames {
+		if staged, err = s.stagedRenames(repo, status, copies); err != nil {
+			return nil, err
+		}
+	}
+	if opts.WorktreeRenames {
+		if unstaged, err = s.worktreeRenames(repo, worktree, status); err != nil {
+			return nil, err
+		}
+	}
+	stagedFrom, stagedTo := renamesByPath(staged)
+	unstagedFrom, unstagedTo := renamesByPath(unstaged)
+
 	// Convert status to our format
 	var files []FileStatus
+	for file := range unmerged {
+		files = append(files, FileStatus{
+			File:   file,
+			Staged: false,
+			Status: StatusUnmerged,
+		})
+	}
+
 	for file, fileStatus := range status {
+		// Unmerged files are reported once, whatever their stages look like
+		if unmerged[file] {
+			continue
+		}
+
 		// Skip unmodified files
 		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified {
 			continue
@@ -167,16 +311,31 @@ func (s *GitService) GetStatus(projectPath string) ([]FileStatus, error) {
 
 		// For untracked files
 		if fileStatus.Worktree == git.Untracked {
-			files = append(files, FileStatus{
-				File:   file,
-				Staged: false,
-				Status: string(git.Untracked),
-			})
+			if pair, ok := unstagedTo[file]; ok {
+				files = append(files, renamedStatus(pair, false))
+			} else {
+				files = append(files, FileStatus{
+					File:   file,
+					Staged: false,
+					Status: string(git.Untracked),
+				})
+			}
+
+			// A file removed from the index but kept on disk is also a staged deletion
+			if fileStatus.Staging == git.Deleted && !stagedFrom[file] {
+				files = append(files, FileStatus{
+					File:   file,
+					Staged: true,
+					Status: string(git.Deleted),
+				})
+			}
 			continue
 		}
 
 		// Handle staged changes
-		if fileStatus.Staging != git.Unmodified {
+		if pair, ok := stagedTo[file]; ok {
+			files = append(files, renamedStatus(pair, true))
+		} else if fileStatus.Staging != git.Unmodified && !stagedFrom[file] {
 			files = append(files, FileStatus{
 				File:   file,
 				Staged: true,
@@ -185,7 +344,7 @@ func (s *GitService) GetStatus(projectPath string) ([]FileStatus, error) {
 		}
 
 		// Handle unstaged changes
-		if fileStatus.Worktree != git.Unmodified {
+		if fileStatus.Worktree != git.Unmodified && !unstagedFrom[file] {
 			files = append(files, FileStatus{
 				File:   file,
 				Staged: false,
@@ -197,11 +356,113 @@ func (s *GitService) GetStatus(projectPath string) ([]FileStatus, error) {
 	return files, nil
 }
 
+// renamesByPath indexes renames by their target, and lists the files moved away from.
+// The source of a copy is still there, so it isn't listed.
+func renamesByPath(pairs []renamePair) (map[string]bool, map[string]renamePair) {
+	from := make(map[string]bool, len(pairs))
+	to := make(map[string]renamePair, len(pairs))
+	for _, pair := range pairs {
+		if !pair.copied {
+			from[pair.from] = true
+		}
+		to[pair.to] = pair
+	}
+	return from, to
+}
+
+// renamedStatus returns the status of the target of a rename or copy
+func renamedStatus(pair renamePair, staged bool) FileStatus {
+	status := git.Renamed
+	if pair.copied {
+		status = git.Copied
+	}
+	return FileStatus{
+		File:       pair.to,
+		Staged:     staged,
+		Status:     string(status),
+		OrigFile:   pair.from,
+		Similarity: pair.similarity,
+	}
+}
+
+// openRepository is a helper function that opens the repository for a given project path.
+// Repositories are cached and opened again once git changed their packs, e.g. after a gc.
+func (s *GitService) openRepository(projectPath string) (*git.Repository, error) {
+	absPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	s.reposMu.Lock()
+	cached, ok := s.repos[absPath]
+	s.reposMu.Unlock()
+
+	if ok {
+		packsTime, err := s.packsModTime(cached.repo)
+		if err == nil && packsTime.Equal(cached.packsTime) {
+			return cached.repo, nil
+		}
+	}
+
+	// Linked worktrees keep their HEAD and index apart from the shared repository
+	repo, err := git.PlainOpenWithOptions(absPath, &git.PlainOpenOptions{EnableDotGitCommonDir: true})
+	if err != nil {
+		s.InvalidateRepository(absPath)
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	packsTime, err := s.packsModTime(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the pack indexes now, they are loaded lazily and not safe to load concurrently
+	if err := repo.Storer.HasEncodedObject(plumbing.ZeroHash); err != nil && !errors.Is(err, plumbing.ErrObjectNotFound) {
+		return nil, fmt.Errorf("failed to load repository objects: %w", err)
+	}
+
+	s.reposMu.Lock()
+	s.repos[absPath] = &cachedRepository{repo: repo, packsTime: packsTime}
+	s.reposMu.Unlock()
+
+	return repo, nil
+}
+
+// InvalidateRepository drops the cached repository of a project, so the next call opens it again
+func (s *GitService) InvalidateRepository(projectPath string) {
+	absPath, err := filepath.Abs(projectPath)
+	if err != nil {
+		return
+	}
+
+	s.reposMu.Lock()
+	delete(s.repos, absPath)
+	s.reposMu.Unlock()
+}
+
+// packsModTime returns when the pack directory of a repository last changed
+func (s *GitService) packsModTime(repo *git.Repository) (time.Time, error) {
+	dir, err := s.commonDir(repo)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "objects", "pack"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, fmt.Errorf("failed to stat packs: %w", err)
+	}
+
+	return info.ModTime(), nil
+}
+
 // getWorktree is a helper function that returns the worktree for a given project path
 func (s *GitService) getWorktree(projectPath string) (*git.Worktree, error) {
-	repo, err := git.PlainOpen(projectPath)
+	repo, err := s.openRepository(projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open repository: %w", err)
+		return nil, err
 	}
 
 	worktree, err := repo.Worktree()
@@ -214,10 +475,29 @@ func (s *GitService) getWorktree(projectPath string) (*git.Worktree, error) {
 
 // StageFile adds a file to the staging area
 func (s *GitService) StageFile(projectPath string, file string) error {
-	worktree, err := s.getWorktree(projectPath)
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	// Staging a conflicted file marks it as resolved, like git add
+	unmerged, err := s.unmergedPaths(repo)
 	if err != nil {
 		return err
 	}
+	if unmerged[file] {
+		return s.resolveConflict(repo, worktree, file, ConflictResolution{Choice: ResolveWorktree})
+	}
+
+	// go-git doesn't stage submodules, their entry records the checked out commit
+	if staged, err := s.stageSubmodule(repo, worktree, file); err != nil || staged {
+		return err
+	}
 
 	_, err = worktree.Add(file)
 	if err != nil {
@@ -244,9 +524,9 @@ func (s *GitService) UnstageFile(projectPath string, file string) error {
 // DiscardChanges discards changes in an unstaged file, reverting it to the last commit
 func (s *GitService) DiscardChanges(projectPath string, file string) error {
 	// Open the repository
-	repo, err := git.PlainOpen(projectPath)
+	repo, err := s.openRepository(projectPath)
 	if err != nil {
-		return fmt.Errorf("failed to open repository: %w", err)
+		return err
 	}
 
 	// Get worktree to check file status
@@ -256,12 +536,11 @@ func (s *GitService) DiscardChanges(projectPath string, file string) error {
 	}
 
 	// Check if file is untracked
-	status, err := worktree.Status()
+	fileStatus, err := s.fileStatus(repo, worktree, file)
 	if err != nil {
 		return fmt.Errorf("failed to get status: %w", err)
 	}
 
-	fileStatus := status.File(file)
 	if fileStatus.Staging == git.Untracked {
 		fullPath := filepath.Join(projectPath, file)
 		if err := os.Remove(fullPath); err != nil {
@@ -324,27 +603,123 @@ func (s *GitService) DiscardChanges(projectPath string, file string) error {
 	return nil
 }
 
-// Commit creates a new commit with the staged changes
+// Commit creates a new commit with the staged changes.
+// While a merge is in progress it concludes the merge, once all conflicts are resolved.
+// The pre-commit, prepare-commit-msg and commit-msg hooks run first, and a hook exiting
+// with a non-zero status aborts the commit with a *HookError.
 func (s *GitService) Commit(projectPath string, message string) error {
-	worktree, err := s.getWorktree(projectPath)
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	unmerged, err := s.unmergedPaths(repo)
+	if err != nil {
+		return err
+	}
+	if len(unmerged) > 0 {
+		return errors.New("cannot commit with unresolved conflicts")
+	}
+
+	mergeHead, merging, err := s.mergeHead(repo)
+	if err != nil {
+		return err
+	}
+
+	pickName, pickHash, picking, err := s.pickHead(repo)
 	if err != nil {
 		return err
 	}
 
+	opts, err := s.commitOptions(repo)
+	if err != nil {
+		return err
+	}
+
+	// Where the message comes from, as passed to the prepare-commit-msg hook
+	source := "message"
+
+	if picking {
+		if message == "" {
+			if message, err = s.mergeMessageFromState(repo); err != nil {
+				return err
+			}
+			source = "merge"
+		}
+
+		// A cherry-pick keeps the author of the original commit
+		if pickName == cherryPickHead {
+			picked, err := repo.CommitObject(pickHash)
+			if err != nil {
+				return fmt.Errorf("failed to get cherry-picked commit: %w", err)
+			}
+			opts.Author = &picked.Author
+		}
+	}
+	if merging {
+		head, err := repo.Head()
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		opts.Parents = []plumbing.Hash{head.Hash(), mergeHead}
+
+		if message == "" {
+			if message, err = s.mergeMessageFromState(repo); err != nil {
+				return err
+			}
+			source = "merge"
+		}
+	}
+
+	if message, err = s.runCommitHooks(repo, worktree, message, source); err != nil {
+		return err
+	}
+
+	old := plumbing.ZeroHash
+	if head, err := repo.Head(); err == nil {
+		old = head.Hash()
+	}
+
 	// Create the commit
-	_, err = worktree.Commit(message, &git.CommitOptions{})
+	hash, err := worktree.Commit(commitMessage(message), opts)
 	if err != nil {
 		return fmt.Errorf("failed to create commit: %w", err)
 	}
 
+	reflogMessage := "commit: "
+	switch {
+	case old.IsZero():
+		reflogMessage = "commit (initial): "
+	case merging:
+		reflogMessage = "commit (merge): "
+	case picking && pickName == cherryPickHead:
+		reflogMessage = "commit (cherry-pick): "
+	}
+	if err := s.logHeadUpdate(repo, old, hash, reflogMessage+firstLine(message)); err != nil {
+		return err
+	}
+
+	if merging || picking {
+		if err := s.clearMergeState(repo); err != nil {
+			return err
+		}
+	}
+
+	s.runPostCommitHook(repo, worktree)
+
 	return nil
 }
 
 // ListBranches returns a list of all branches in the repository
 func (s *GitService) ListBranches(projectPath string) ([]BranchInfo, error) {
-	repo, err := git.PlainOpen(projectPath)
+	repo, err := s.openRepository(projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open repository: %w", err)
+		return nil, err
 	}
 
 	branches := []BranchInfo{}
@@ -356,6 +731,11 @@ func (s *GitService) ListBranches(projectPath string) ([]BranchInfo, error) {
 	}
 	currentBranchName := head.Name().Short()
 
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository config: %w", err)
+	}
+
 	// List local branches
 	branchIter, err := repo.Branches()
 	if err != nil {
@@ -364,39 +744,46 @@ func (s *GitService) ListBranches(projectPath string) ([]BranchInfo, error) {
 
 	err = branchIter.ForEach(func(ref *plumbing.Reference) error {
 		branchName := ref.Name().Short()
-		branches = append(branches, BranchInfo{
+		branch := BranchInfo{
 			Name:     branchName,
 			IsRemote: false,
 			IsHead:   branchName == currentBranchName,
-		})
+		}
+		if err := s.fillBranchTracking(repo, cfg, ref, &branch); err != nil {
+			return err
+		}
+		branches = append(branches, branch)
 		return nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to iterate branches: %w", err)
 	}
 
-	// List remote branches
-	remotes, err := repo.Remotes()
+	// List remote branches from the local remote-tracking refs, so no network call is needed
+	refIter, err := repo.References()
 	if err != nil {
-		return nil, fmt.Errorf("failed to list remotes: %w", err)
+		return nil, fmt.Errorf("failed to list references: %w", err)
 	}
 
-	for _, remote := range remotes {
-		refs, err := remote.List(&git.ListOptions{})
-		if err != nil {
-			continue // Skip this remote if we can't list its refs
+	err = refIter.ForEach(func(ref *plumbing.Reference) error {
+		// Skip refs/remotes/<remote>/HEAD, it only points at another remote branch
+		if !ref.Name().IsRemote() || ref.Type() != plumbing.HashReference {
+			return nil
 		}
 
-		for _, ref := range refs {
-			if ref.Name().IsBranch() {
-				branchName := ref.Name().Short()
-				branches = append(branches, BranchInfo{
-					Name:     branchName,
-					IsRemote: true,
-					IsHead:   false,
-				})
-			}
+		branch := BranchInfo{
+			Name:     ref.Name().Short(),
+			IsRemote: true,
+			IsHead:   false,
+		}
+		if err := s.fillLastCommit(repo, ref.Hash(), &branch); err != nil {
+			return err
 		}
+		branches = append(branches, branch)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to iterate remote branches: %w", err)
 	}
 
 	return branches, nil
@@ -404,9 +791,9 @@ func (s *GitService) ListBranches(projectPath string) ([]BranchInfo, error) {
 
 // GetCurrentBranch returns the name of the current branch
 func (s *GitService) GetCurrentBranch(projectPath string) (string, error) {
-	repo, err := git.PlainOpen(projectPath)
+	repo, err := s.openRepository(projectPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open repository: %w", err)
+		return "", err
 	}
 
 	head, err := repo.Head()
@@ -419,27 +806,56 @@ func (s *GitService) GetCurrentBranch(projectPath string) (string, error) {
 
 // ListCommits returns a list of commits based on the provided filters
 func (s *GitService) ListCommits(projectPath string, filter CommitFilter) ([]CommitInfo, error) {
-	// Ensure we have an absolute path
-	absPath, err := filepath.Abs(projectPath)
+	// Open the repository
+	repo, err := s.openRepository(projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		return nil, err
 	}
 
-	// Open the repository
-	repo, err := git.PlainOpen(absPath)
+	startRef, err := s.logStart(repo, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open repository: %w", err)
+		return nil, err
+	}
+
+	// Create log options
+	logOptions := &git.LogOptions{
+		From:  startRef,
+		Order: git.LogOrderCommitterTime,
 	}
 
+	// Get the commit iterator
+	commitIter, err := repo.Log(logOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit iterator: %w", err)
+	}
+	defer commitIter.Close()
+
+	commits, err := s.collectCommits(commitIter, filter, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+// logStart returns the commit a log starts from: the filter's branch, tag or start hash, or HEAD
+func (s *GitService) logStart(repo *git.Repository, filter CommitFilter) (plumbing.Hash, error) {
 	// Get the reference to start from (branch or commit)
 	var startRef plumbing.Hash
 	if filter.Branch != "" {
 		// If branch is specified, use it
 		ref, err := repo.Reference(plumbing.NewBranchReferenceName(filter.Branch), true)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get branch reference: %w", err)
+			return plumbing.ZeroHash, fmt.Errorf("failed to get branch reference: %w", err)
 		}
 		startRef = ref.Hash()
+	} else if filter.Tag != "" {
+		// An annotated tag is followed to the commit it tags
+		hash, err := s.tagCommit(repo, filter.Tag)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		startRef = hash
 	} else if filter.StartHash != "" {
 		// If start hash is specified, use it
 		hash := plumbing.NewHash(filter.StartHash)
@@ -450,30 +866,36 @@ func (s *GitService) ListCommits(projectPath string, filter CommitFilter) ([]Com
 		// Default to HEAD
 		ref, err := repo.Head()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get HEAD reference: %w", err)
+			return plumbing.ZeroHash, fmt.Errorf("failed to get HEAD reference: %w", err)
 		}
 		startRef = ref.Hash()
 	}
 
-	// Create log options
-	logOptions := &git.LogOptions{
-		From:  startRef,
-		Order: git.LogOrderCommitterTime,
-	}
-
-	// Get the commit iterator
-	commitIter, err := repo.Log(logOptions)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get commit iterator: %w", err)
-	}
-	defer commitIter.Close()
+	return startRef, nil
+}
 
+// collectCommits applies the filter and pagination of a CommitFilter to a log.
+// If touches is set, only commits it accepts are listed, with the path it returns.
+func (s *GitService) collectCommits(commitIter object.CommitIter, filter CommitFilter, touches func(c *object.Commit) (string, bool, error)) ([]CommitInfo, error) {
 	var commits []CommitInfo
 	var skipped int
 	var foundOffsetHash bool = filter.OffsetHash == "" // If no offset hash specified, we start collecting immediately
 	var hasMoreCommits bool = false
 
-	err = commitIter.ForEach(func(c *object.Commit) error {
+	err := commitIter.ForEach(func(c *object.Commit) error {
+		// Every commit has to be seen, so the path can be followed through the skipped ones
+		var path string
+		if touches != nil {
+			touchedPath, touched, err := touches(c)
+			if err != nil {
+				return err
+			}
+			if !touched {
+				return nil
+			}
+			path = touchedPath
+		}
+
 		// Handle hash-based offset
 		if !foundOffsetHash {
 			if c.Hash.String() == filter.OffsetHash {
@@ -512,9 +934,8 @@ func (s *GitService) ListCommits(projectPath string, filter CommitFilter) ([]Com
 			parentHashes[i] = hash.String()
 		}
 
-		// Check if we've reached one before the limit
-		if filter.Limit > 0 && len(commits) == filter.Limit-1 {
-			// We found one more commit, so there are more after the current batch
+		// A commit past the limit only tells there are more after the current batch
+		if filter.Limit > 0 && len(commits) == filter.Limit {
 			hasMoreCommits = true
 			return errors.New("stop iteration")
 		}
@@ -527,14 +948,11 @@ func (s *GitService) ListCommits(projectPath string, filter CommitFilter) ([]Com
 			AuthorEmail:  c.Author.Email,
 			Date:         c.Author.When,
 			ParentHashes: parentHashes,
+			Path:         path,
+			Signed:       c.PGPSignature != "",
 			HasMore:      true, // Will be updated after the loop
 		})
 
-		// Check if we've reached the limit
-		if filter.Limit > 0 && len(commits) >= filter.Limit {
-			return errors.New("stop iteration")
-		}
-
 		return nil
 	})
 
@@ -588,9 +1006,9 @@ func (s *GitService) SearchCommits(projectPath string, query string, limit int)
 
 // GetHeadCommit returns the current HEAD commit
 func (s *GitService) GetHeadCommit(projectPath string) (*CommitInfo, error) {
-	repo, err := git.PlainOpen(projectPath)
+	repo, err := s.openRepository(projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open repository: %w", err)
+		return nil, err
 	}
 
 	head, err := repo.Head()
@@ -608,6 +1026,7 @@ func (s *GitService) GetHeadCommit(projectPath string) (*CommitInfo, error) {
 		parentHashes[i] = hash.String()
 	}
 
+	verified := s.verifyCommit(repo, commit)
 	return &CommitInfo{
 		Hash:         commit.Hash.String(),
 		Message:      commit.Message,
@@ -615,6 +1034,8 @@ func (s *GitService) GetHeadCommit(projectPath string) (*CommitInfo, error) {
 		AuthorEmail:  commit.Author.Email,
 		Date:         commit.Author.When,
 		ParentHashes: parentHashes,
+		Signed:       commit.PGPSignature != "",
+		Verified:     &verified,
 	}, nil
 }
 
@@ -622,9 +1043,14 @@ func (s *GitService) GetHeadCommit(projectPath string) (*CommitInfo, error) {
 // If staged is true, returns the diff between HEAD and staged changes
 // If staged is false, returns the diff between staged/HEAD and working directory
 func (s *GitService) GetFileDiff(projectPath string, filePath string, staged bool) (*FileDiff, error) {
-	repo, err := git.PlainOpen(projectPath)
+	return s.GetFileDiffWithOptions(projectPath, filePath, staged, DiffOptions{})
+}
+
+// GetFileDiffWithOptions is GetFileDiff with control over the generated diff, e.g. the number of context lines
+func (s *GitService) GetFileDiffWithOptions(projectPath string, filePath string, staged bool, opts DiffOptions) (*FileDiff, error) {
+	repo, err := s.openRepository(projectPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open repository: %w", err)
+		return nil, err
 	}
 
 	worktree, err := repo.Worktree()
@@ -632,311 +1058,529 @@ func (s *GitService) GetFileDiff(projectPath string, filePath string, staged boo
 		return nil, fmt.Errorf("failed to get worktree: %w", err)
 	}
 
-	// Get file status to check if it's untracked or binary
-	status, err := worktree.Status()
+	// Get file status to check if it's untracked
+	fileStatus, err := s.fileStatus(repo, worktree, filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get status: %w", err)
 	}
 
-	fileStatus := status.File(filePath)
 	if fileStatus.Staging == git.Untracked && staged {
 		return nil, fmt.Errorf("cannot get staged diff for untracked file")
 	}
 
-	// Handle deleted files
-	if fileStatus.Worktree == git.Deleted || fileStatus.Staging == git.Deleted {
-		head, err := repo.Head()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get HEAD: %w", err)
-		}
+	// A renamed file is diffed against its original
+	rename, renamed, err := s.fileRename(repo, worktree, filePath, fileStatus, staged, opts)
+	if err != nil {
+		return nil, err
+	}
 
-		commit, err := repo.CommitObject(head.Hash())
-		if err != nil {
-			return nil, fmt.Errorf("failed to get commit: %w", err)
+	var oldSide, newSide diffSide
+	switch {
+	case renamed && staged:
+		oldSide, err = s.headSide(repo, rename.from)
+		if err == nil {
+			newSide, err = s.indexSide(repo, filePath)
 		}
-
-		tree, err := commit.Tree()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get tree: %w", err)
+	case renamed:
+		oldSide, err = s.indexSide(repo, rename.from)
+		if err == nil {
+			newSide, err = s.worktreeSide(worktree, filePath)
 		}
+	case staged:
+		// Get diff between HEAD and index
+		oldSide, newSide, err = s.getStagedDiff(repo, filePath)
+	default:
+		// Get diff between index/HEAD and working directory
+		oldSide, newSide, err = s.getWorkingDiff(repo, worktree, filePath, fileStatus.Staging == git.Untracked)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !renamed {
+		return generateDiff(oldSide, newSide, filePath, filePath, opts), nil
+	}
 
-		file, err := tree.File(filePath)
+	diff := generateDiff(oldSide, newSide, rename.from, filePath, opts)
+	if rename.copied {
+		diff.Status = string(git.Copied)
+	}
+	return diff, nil
+}
+
+// fileRename returns the rename or copy that GetStatus reports for a file, if any
+func (s *GitService) fileRename(repo *git.Repository, worktree *git.Worktree, filePath string, fileStatus *git.FileStatus, staged bool, opts DiffOptions) (renamePair, bool, error) {
+	var pairs []renamePair
+	switch {
+	case staged && fileStatus.Staging == git.Added:
+		renames, copies, err := s.renameConfig(repo)
+		if err != nil || !renames {
+			return renamePair{}, false, err
+		}
+		status, err := s.status(repo, worktree)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get file from HEAD: %w", err)
+			return renamePair{}, false, fmt.Errorf("failed to get status: %w", err)
 		}
-
-		content, err := file.Contents()
+		if pairs, err = s.stagedRenames(repo, status, copies); err != nil {
+			return renamePair{}, false, err
+		}
+	case !staged && opts.WorktreeRenames && fileStatus.Worktree == git.Untracked:
+		status, err := s.status(repo, worktree)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get file contents: %w", err)
+			return renamePair{}, false, fmt.Errorf("failed to get status: %w", err)
 		}
+		if pairs, err = s.worktreeRenames(repo, worktree, status); err != nil {
+			return renamePair{}, false, err
+		}
+	}
 
-		diff, stats, err := s.generateDiff(content, "", filePath)
-		if err != nil {
-			return nil, err
+	for _, pair := range pairs {
+		if pair.to == filePath {
+			return pair, true, nil
 		}
+	}
+	return renamePair{}, false, nil
+}
 
-		return &FileDiff{
-			Path:     filePath,
-			Content:  diff,
-			Stats:    stats,
-			IsBinary: false,
-		}, nil
+// diffSide is one version of a file in a diff.
+// A side that doesn't exist is shown as /dev/null, like for added or deleted files.
+type diffSide struct {
+	content string
+	exists  bool
+}
+
+// readBlob returns the contents of a blob
+func (s *GitService) readBlob(repo *git.Repository, hash plumbing.Hash) (string, error) {
+	obj, err := repo.BlobObject(hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to get blob object: %w", err)
 	}
 
-	// Check if file is binary (only for non-deleted files)
-	fullPath := filepath.Join(projectPath, filePath)
-	isBinary, err := isFileBinary(fullPath)
+	reader, err := obj.Reader()
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if file is binary: %w", err)
+		return "", fmt.Errorf("failed to get blob reader: %w", err)
 	}
-	if isBinary {
-		return &FileDiff{
-			Path:     filePath,
-			IsBinary: true,
-		}, nil
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to read blob content: %w", err)
 	}
 
-	var diff string
-	var stats DiffStats
+	return string(content), nil
+}
 
-	if staged {
-		// Get diff between HEAD and index
-		diff, stats, err = s.getStagedDiff(repo, worktree, filePath)
-	} else {
-		// Get diff between index/HEAD and working directory
-		diff, stats, err = s.getWorkingDiff(repo, worktree, filePath, fileStatus.Staging == git.Untracked)
+// headSide returns the version of a file in HEAD
+func (s *GitService) headSide(repo *git.Repository, filePath string) (diffSide, error) {
+	tree, err := s.headTree(repo)
+	if err != nil || tree == nil {
+		// No HEAD (new repo), compare with empty tree
+		return diffSide{}, err
 	}
 
+	if entry, err := tree.FindEntry(filePath); err == nil && entry.Mode == filemode.Submodule {
+		return diffSide{content: subprojectCommit(entry.Hash, false), exists: true}, nil
+	}
+
+	headFile, err := tree.File(filePath)
 	if err != nil {
-		return nil, err
+		return diffSide{}, nil
 	}
 
-	return &FileDiff{
-		Path:     filePath,
-		Content:  diff,
-		Stats:    stats,
-		IsBinary: false,
-	}, nil
+	content, err := headFile.Contents()
+	if err != nil {
+		return diffSide{}, fmt.Errorf("failed to get HEAD file contents: %w", err)
+	}
+
+	return diffSide{content: content, exists: true}, nil
 }
 
-// getStagedDiff returns the diff between HEAD and index
-func (s *GitService) getStagedDiff(repo *git.Repository, worktree *git.Worktree, filePath string) (string, DiffStats, error) {
-	head, err := repo.Head()
+// indexSide returns the version of a file in the index
+func (s *GitService) indexSide(repo *git.Repository, filePath string) (diffSide, error) {
+	idx, _, err := s.readIndex(repo)
 	if err != nil {
-		if err == plumbing.ErrReferenceNotFound {
-			// If no HEAD (new repo), compare with empty tree
-			return s.getDiffWithEmpty(worktree, filePath, true)
-		}
-		return "", DiffStats{}, fmt.Errorf("failed to get HEAD: %w", err)
+		return diffSide{}, err
 	}
 
-	commit, err := repo.CommitObject(head.Hash())
+	entry, err := idx.Entry(filePath)
 	if err != nil {
-		return "", DiffStats{}, fmt.Errorf("failed to get commit: %w", err)
+		return diffSide{}, nil
 	}
 
-	// Get the tree for HEAD
-	headTree, err := commit.Tree()
+	if entry.Mode == filemode.Submodule {
+		return diffSide{content: subprojectCommit(entry.Hash, false), exists: true}, nil
+	}
+
+	content, err := s.readBlob(repo, entry.Hash)
 	if err != nil {
-		return "", DiffStats{}, fmt.Errorf("failed to get tree: %w", err)
+		return diffSide{}, err
 	}
 
-	var oldContent string
+	return diffSide{content: content, exists: true}, nil
+}
 
-	// Get old content from HEAD
-	if headFile, err := headTree.File(filePath); err == nil {
-		oldContent, err = headFile.Contents()
-		if err != nil {
-			return "", DiffStats{}, fmt.Errorf("failed to get HEAD file contents: %w", err)
-		}
+// worktreeSide returns the version of a file in the working directory
+func (s *GitService) worktreeSide(worktree *git.Worktree, filePath string) (diffSide, error) {
+	fullPath := filepath.Join(worktree.Filesystem.Root(), filePath)
+	if info, err := os.Stat(fullPath); err == nil && info.IsDir() {
+		// A directory in place of a file is a submodule
+		return s.submoduleSide(fullPath)
 	}
 
-	// Get index content using the underlying index
-	idx, err := repo.Storer.Index()
+	content, err := s.getFileContents(fullPath)
 	if err != nil {
-		return "", DiffStats{}, fmt.Errorf("failed to get index: %w", err)
+		if errors.Is(err, os.ErrNotExist) {
+			return diffSide{}, nil
+		}
+		return diffSide{}, fmt.Errorf("failed to get working file contents: %w", err)
 	}
 
-	// Find the entry in the index
-	var newContent string
-	for _, entry := range idx.Entries {
-		if entry.Name == filePath {
-			// Get the object from the repository
-			obj, err := repo.BlobObject(entry.Hash)
-			if err != nil {
-				return "", DiffStats{}, fmt.Errorf("failed to get blob object: %w", err)
-			}
+	return diffSide{content: content, exists: true}, nil
+}
 
-			// Read the blob content
-			reader, err := obj.Reader()
-			if err != nil {
-				return "", DiffStats{}, fmt.Errorf("failed to get blob reader: %w", err)
-			}
-			defer reader.Close()
+// getStagedDiff returns both sides of the diff between HEAD and index
+func (s *GitService) getStagedDiff(repo *git.Repository, filePath string) (diffSide, diffSide, error) {
+	oldSide, err := s.headSide(repo, filePath)
+	if err != nil {
+		return diffSide{}, diffSide{}, err
+	}
 
-			content, err := io.ReadAll(reader)
-			if err != nil {
-				return "", DiffStats{}, fmt.Errorf("failed to read blob content: %w", err)
-			}
-			newContent = string(content)
-			break
-		}
+	newSide, err := s.indexSide(repo, filePath)
+	if err != nil {
+		return diffSide{}, diffSide{}, err
 	}
 
-	return s.generateDiff(oldContent, newContent, filePath)
+	return oldSide, newSide, nil
 }
 
-// getWorkingDiff returns the diff between index/HEAD and working directory
-func (s *GitService) getWorkingDiff(repo *git.Repository, worktree *git.Worktree, filePath string, isUntracked bool) (string, DiffStats, error) {
-	if isUntracked {
-		return s.getDiffWithEmpty(worktree, filePath, false)
+// getWorkingDiff returns both sides of the diff between index/HEAD and working directory
+func (s *GitService) getWorkingDiff(repo *git.Repository, worktree *git.Worktree, filePath string, isUntracked bool) (diffSide, diffSide, error) {
+	newSide, err := s.worktreeSide(worktree, filePath)
+	if err != nil {
+		return diffSide{}, diffSide{}, err
 	}
 
-	var oldContent string
+	// Untracked files are compared with an empty file
+	if isUntracked {
+		return diffSide{}, newSide, nil
+	}
 
 	// Try to get content from index first
-	idx, err := repo.Storer.Index()
+	oldSide, err := s.indexSide(repo, filePath)
 	if err != nil {
-		return "", DiffStats{}, fmt.Errorf("failed to get index: %w", err)
+		return diffSide{}, diffSide{}, err
 	}
 
-	foundInIndex := false
-	for _, entry := range idx.Entries {
-		if entry.Name == filePath {
-			// Get the object from the repository
-			obj, err := repo.BlobObject(entry.Hash)
-			if err != nil {
-				return "", DiffStats{}, fmt.Errorf("failed to get blob object: %w", err)
-			}
+	// If not in index, try HEAD
+	if !oldSide.exists {
+		oldSide, err = s.headSide(repo, filePath)
+		if err != nil {
+			return diffSide{}, diffSide{}, err
+		}
+	}
 
-			// Read the blob content
-			reader, err := obj.Reader()
-			if err != nil {
-				return "", DiffStats{}, fmt.Errorf("failed to get blob reader: %w", err)
-			}
-			defer reader.Close()
+	return oldSide, newSide, nil
+}
 
-			content, err := io.ReadAll(reader)
-			if err != nil {
-				return "", DiffStats{}, fmt.Errorf("failed to read blob content: %w", err)
-			}
-			oldContent = string(content)
-			foundInIndex = true
-			break
+// diffLineOp is a single line of a line-based edit script
+type diffLineOp struct {
+	kind      byte // ' ' for context, '-' for deleted and '+' for added lines
+	text      string
+	noNewline bool
+}
+
+// splitLines splits content into lines, reporting whether the last one lacks a trailing newline
+func splitLines(content string) ([]string, bool) {
+	if content == "" {
+		return nil, false
+	}
+
+	noNewline := !strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	return lines, noNewline
+}
+
+// diffLines computes the line-based edit script that turns oldContent into newContent
+func diffLines(oldContent, newContent string) []diffLineOp {
+	var ops []diffLineOp
+	for _, d := range diff.Do(oldContent, newContent) {
+		var kind byte
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			kind = '-'
+		case diffmatchpatch.DiffInsert:
+			kind = '+'
+		default:
+			kind = ' '
+		}
+
+		// Chunks always hold whole lines, only the last line of a file may lack its newline
+		lines, noNewline := splitLines(d.Text)
+		for i, line := range lines {
+			ops = append(ops, diffLineOp{
+				kind:      kind,
+				text:      line,
+				noNewline: noNewline && i == len(lines)-1,
+			})
 		}
 	}
+	return ops
+}
 
-	// If not in index, try HEAD
-	if !foundInIndex {
-		head, err := repo.Head()
-		if err != nil {
-			if err == plumbing.ErrReferenceNotFound {
-				return s.getDiffWithEmpty(worktree, filePath, false)
-			}
-			return "", DiffStats{}, fmt.Errorf("failed to get HEAD: %w", err)
+// buildHunks groups an edit script into hunks with the given number of context lines
+func buildHunks(ops []diffLineOp, contextLines int) []Hunk {
+	// Line numbers (1-based) of each op on both sides, and the lines before it
+	oldBefore := make([]int, len(ops)+1)
+	newBefore := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldBefore[i+1] = oldBefore[i]
+		newBefore[i+1] = newBefore[i]
+		if op.kind != '+' {
+			oldBefore[i+1]++
+		}
+		if op.kind != '-' {
+			newBefore[i+1]++
 		}
+	}
 
-		commit, err := repo.CommitObject(head.Hash())
-		if err != nil {
-			return "", DiffStats{}, fmt.Errorf("failed to get commit: %w", err)
+	var hunks []Hunk
+	prevEnd := 0
+	i := 0
+	for i < len(ops) {
+		// Find the next change
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
 		}
 
-		tree, err := commit.Tree()
-		if err != nil {
-			return "", DiffStats{}, fmt.Errorf("failed to get tree: %w", err)
+		// Never reach back into the previous hunk
+		start := max(i-contextLines, prevEnd)
+
+		// Extend the hunk while the next change is close enough to share context
+		end := i
+		for {
+			for end < len(ops) && ops[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next < len(ops) && next-end <= 2*contextLines {
+				end = next
+				continue
+			}
+			end = min(end+contextLines, len(ops))
+			break
 		}
 
-		if headFile, err := tree.File(filePath); err == nil {
-			oldContent, err = headFile.Contents()
-			if err != nil {
-				return "", DiffStats{}, fmt.Errorf("failed to get HEAD file contents: %w", err)
+		var hunk Hunk
+		for k := start; k < end; k++ {
+			op := ops[k]
+			line := DiffLine{Content: op.text, NoNewline: op.noNewline}
+			switch op.kind {
+			case '-':
+				line.Type = "delete"
+				line.OldLine = oldBefore[k] + 1
+				hunk.OldLines++
+			case '+':
+				line.Type = "add"
+				line.NewLine = newBefore[k] + 1
+				hunk.NewLines++
+			default:
+				line.Type = "context"
+				line.OldLine = oldBefore[k] + 1
+				line.NewLine = newBefore[k] + 1
+				hunk.OldLines++
+				hunk.NewLines++
 			}
+			hunk.Lines = append(hunk.Lines, line)
 		}
-	}
 
-	// Get working directory content
-	newContent, err := s.getFileContents(filepath.Join(worktree.Filesystem.Root(), filePath))
-	if err != nil {
-		return "", DiffStats{}, fmt.Errorf("failed to get working file contents: %w", err)
+		// An empty side starts at the line before the hunk, like in git
+		hunk.OldStart = oldBefore[start]
+		if hunk.OldLines > 0 {
+			hunk.OldStart++
+		}
+		hunk.NewStart = newBefore[start]
+		if hunk.NewLines > 0 {
+			hunk.NewStart++
+		}
+		hunk.Header = fmt.Sprintf("@@ -%s +%s @@", hunkRange(hunk.OldStart, hunk.OldLines), hunkRange(hunk.NewStart, hunk.NewLines))
+
+		hunks = append(hunks, hunk)
+		prevEnd = end
+		i = end
 	}
 
-	return s.generateDiff(oldContent, newContent, filePath)
+	return hunks
 }
 
-// getDiffWithEmpty returns a diff comparing with an empty file
-func (s *GitService) getDiffWithEmpty(worktree *git.Worktree, filePath string, staged bool) (string, DiffStats, error) {
-	var content string
-	var err error
+// hunkRange formats one side of a hunk header, omitting the count when it is 1
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
 
-	// Both staged and unstaged cases read from the same location
-	// since we're dealing with a new file
-	content, err = s.getFileContents(filepath.Join(worktree.Filesystem.Root(), filePath))
-	if err != nil {
-		return "", DiffStats{}, fmt.Errorf("failed to get file contents: %w", err)
+// diffStats counts added and deleted lines. Modified counts the lines that were
+// replaced, i.e. the deletions paired with additions in the same block of changes.
+func diffStats(ops []diffLineOp) DiffStats {
+	var stats DiffStats
+	var deleted, added int
+	flush := func() {
+		stats.Modified += min(deleted, added)
+		deleted, added = 0, 0
+	}
+
+	for _, op := range ops {
+		switch op.kind {
+		case '-':
+			stats.Deleted++
+			deleted++
+		case '+':
+			stats.Added++
+			added++
+		default:
+			flush()
+		}
 	}
+	flush()
 
-	return s.generateDiff("", content, filePath)
+	return stats
 }
 
-// generateDiff creates a unified diff from old and new content
-func (s *GitService) generateDiff(oldContent, newContent, filePath string) (string, DiffStats, error) {
-	// For deleted files, show all lines as deleted
-	if newContent == "" && oldContent != "" {
-		lines := strings.Split(strings.TrimSuffix(oldContent, "\n"), "\n")
-		stats := DiffStats{
-			Deleted: len(lines),
+// generateDiff creates a unified diff from the old and new version of a file
+func generateDiff(oldSide, newSide diffSide, oldPath, newPath string, opts DiffOptions) *FileDiff {
+	path := newPath
+	if !newSide.exists {
+		path = oldPath
+	}
+
+	var renamedFrom string
+	status := "M"
+	switch {
+	case !oldSide.exists:
+		status = "A"
+	case !newSide.exists:
+		status = "D"
+	case oldPath != newPath:
+		renamedFrom = oldPath
+		status = "R"
+	}
+
+	if isContentBinary([]byte(oldSide.content)) || isContentBinary([]byte(newSide.content)) {
+		return &FileDiff{
+			Path:     path,
+			OldPath:  renamedFrom,
+			Status:   status,
+			IsBinary: true,
 		}
-		var diffOutput strings.Builder
+	}
 
-		// Write diff header
-		fmt.Fprintf(&diffOutput, "--- a/%s\n+++ b/%s\n", filePath, filePath)
-		fmt.Fprintf(&diffOutput, "@@ -1,%d +0,0 @@\n", len(lines))
+	ops := diffLines(oldSide.content, newSide.content)
+	hunks := buildHunks(ops, opts.contextLines())
 
-		// Show each line as deleted
-		for _, line := range lines {
-			fmt.Fprintf(&diffOutput, "-%s\n", line)
+	var diffOutput strings.Builder
+	if len(hunks) > 0 {
+		// Write diff header, missing sides are /dev/null so the patch creates or deletes the file
+		fromName, toName := "/dev/null", "/dev/null"
+		if oldSide.exists {
+			fromName = "a/" + oldPath
+		}
+		if newSide.exists {
+			toName = "b/" + newPath
+		}
+		fmt.Fprintf(&diffOutput, "--- %s\n+++ %s\n", fromName, toName)
+
+		for _, hunk := range hunks {
+			diffOutput.WriteString(hunk.Header)
+			diffOutput.WriteString("\n")
+			for _, line := range hunk.Lines {
+				switch line.Type {
+				case "add":
+					diffOutput.WriteString("+")
+				case "delete":
+					diffOutput.WriteString("-")
+				default:
+					diffOutput.WriteString(" ")
+				}
+				diffOutput.WriteString(line.Content)
+				diffOutput.WriteString("\n")
+				if line.NoNewline {
+					diffOutput.WriteString("\\ No newline at end of file\n")
+				}
+			}
 		}
+	}
 
-		return diffOutput.String(), stats, nil
+	return &FileDiff{
+		Path:     path,
+		OldPath:  renamedFrom,
+		Status:   status,
+		Content:  diffOutput.String(),
+		Hunks:    hunks,
+		Stats:    diffStats(ops),
+		IsBinary: false,
 	}
+}
 
-	// Normalize line endings and split into lines
-	oldLines := strings.Split(strings.TrimSuffix(oldContent, "\n"), "\n")
-	newLines := strings.Split(strings.TrimSuffix(newContent, "\n"), "\n")
+// entrySide returns the version of a file stored in a tree entry
+func (s *GitService) entrySide(repo *git.Repository, entry object.TreeEntry) (diffSide, error) {
+	if entry.Mode == filemode.Submodule {
+		// Submodules are shown by the commit they point at, like git does
+		return diffSide{content: fmt.Sprintf("Subproject commit %s\n", entry.Hash), exists: true}, nil
+	}
 
-	// Calculate diffs using go-git/go-diff
-	diffs := diff.Do(strings.Join(oldLines, "\n"), strings.Join(newLines, "\n"))
+	content, err := s.readBlob(repo, entry.Hash)
+	if err != nil {
+		return diffSide{}, err
+	}
 
-	stats := DiffStats{}
-	var diffOutput strings.Builder
+	return diffSide{content: content, exists: true}, nil
+}
 
-	// Write diff header
-	fmt.Fprintf(&diffOutput, "--- a/%s\n+++ b/%s\n", filePath, filePath)
+// diffTrees returns the diff of every file changed between two trees, ordered by path.
+// Renamed files are detected and diffed against their old path. A nil tree is treated as empty.
+func (s *GitService) diffTrees(repo *git.Repository, from, to *object.Tree, opts DiffOptions) ([]FileDiff, error) {
+	changes, err := object.DiffTreeWithOptions(context.Background(), from, to, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare trees: %w", err)
+	}
 
-	// Calculate stats and build diff output
-	for _, d := range diffs {
-		switch d.Type {
-		case diffmatchpatch.DiffDelete:
-			lines := strings.Split(strings.TrimSuffix(d.Text, "\n"), "\n")
-			stats.Deleted += len(lines)
-			for _, line := range lines {
-				fmt.Fprintf(&diffOutput, "-%s\n", line)
+	diffs := make([]FileDiff, 0, len(changes))
+	for _, change := range changes {
+		var oldSide, newSide diffSide
+		if change.From.Name != "" {
+			if oldSide, err = s.entrySide(repo, change.From.TreeEntry); err != nil {
+				return nil, err
 			}
-		case diffmatchpatch.DiffInsert:
-			lines := strings.Split(strings.TrimSuffix(d.Text, "\n"), "\n")
-			stats.Added += len(lines)
-			for _, line := range lines {
-				fmt.Fprintf(&diffOutput, "+%s\n", line)
-			}
-		case diffmatchpatch.DiffEqual:
-			lines := strings.Split(strings.TrimSuffix(d.Text, "\n"), "\n")
-			for _, line := range lines {
-				fmt.Fprintf(&diffOutput, " %s\n", line)
+		}
+		if change.To.Name != "" {
+			if newSide, err = s.entrySide(repo, change.To.TreeEntry); err != nil {
+				return nil, err
 			}
 		}
+
+		oldPath, newPath := change.From.Name, change.To.Name
+		if oldPath == "" {
+			oldPath = newPath
+		}
+		if newPath == "" {
+			newPath = oldPath
+		}
+
+		diffs = append(diffs, *generateDiff(oldSide, newSide, oldPath, newPath, opts))
 	}
 
-	return diffOutput.String(), stats, nil
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+
+	return diffs, nil
 }
 
 // getFileContents reads a file's contents
@@ -963,5 +1607,13 @@ func isFileBinary(path string) (bool, error) {
 		return false, err
 	}
 
-	return strings.Contains(http.DetectContentType(buffer[:n]), "application/octet-stream"), nil
+	return isContentBinary(buffer[:n]), nil
+}
+
+// isContentBinary checks if content looks binary, based on its first 512 bytes
+func isContentBinary(content []byte) bool {
+	if len(content) > 512 {
+		content = content[:512]
+	}
+	return strings.Contains(http.DetectContentType(content), "application/octet-stream")
 }
@@ -0,0 +1,420 @@
+package service
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// UncommittedHash is the hash blame reports for lines that are not committed yet, like git does
+const UncommittedHash = "0000000000000000000000000000000000000000"
+
+// BlameLine is the origin of a single line of a file
+type BlameLine struct {
+	Line        int       `json:"line"`        // Line number in the blamed content
+	Content     string    `json:"content"`     // Line content without the newline
+	Hash        string    `json:"hash"`        // Commit that introduced the line
+	Author      string    `json:"author"`      // Author of that commit
+	Date        time.Time `json:"date"`        // Author date of that commit
+	OrigLine    int       `json:"origLine"`    // Line number in the file as of that commit
+	Uncommitted bool      `json:"uncommitted"` // Whether the line only exists in the blamed content
+}
+
+// BlameCommit describes a commit referenced by blamed lines
+type BlameCommit struct {
+	Hash        string    `json:"hash"`
+	Author      string    `json:"author"`
+	AuthorEmail string    `json:"authorEmail"`
+	Date        time.Time `json:"date"`
+	Summary     string    `json:"summary"` // First line of the commit message
+}
+
+// BlameResult maps every line of a file to the commit that introduced it
+type BlameResult struct {
+	Path    string                 `json:"path"`
+	Rev     string                 `json:"rev"`     // Commit the file was blamed at
+	Lines   []BlameLine            `json:"lines"`   // One entry per line, in order
+	Commits map[string]BlameCommit `json:"commits"` // The commits referenced by the lines, by hash
+}
+
+// blameLine is a line still looking for its origin: its index in the result
+// and its line number in the version of the file being examined
+type blameLine struct {
+	index int
+	line  int
+}
+
+// blameTask holds the lines passed to a commit, and the path of the file in that commit
+type blameTask struct {
+	commit *object.Commit
+	path   string
+	lines  []blameLine
+}
+
+// Blame returns the commit that introduced each line of a file at the given
+// revision (HEAD if empty). Lines are followed through all parents of merges
+// and across renames.
+func (s *GitService) Blame(projectPath string, file string, rev string) (*BlameResult, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := s.resolveCommit(repo, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	content, exists, err := s.commitFileContent(repo, commit, file)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%s does not exist in %s", file, commit.Hash.String()[:7])
+	}
+
+	lines, _ := splitLines(content)
+	result := s.newBlameResult(file, commit, lines)
+
+	pending := make([]blameLine, len(lines))
+	for i := range lines {
+		pending[i] = blameLine{index: i, line: i + 1}
+	}
+
+	if err := s.blameLines(repo, commit, file, pending, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// BlameContent blames an unsaved buffer of a file against HEAD. Lines that
+// differ from HEAD are marked as uncommitted, the others are blamed as in HEAD.
+func (s *GitService) BlameContent(projectPath string, file string, content string) (*BlameResult, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if isContentBinary([]byte(content)) {
+		return nil, errors.New("blame is not supported for binary files")
+	}
+
+	lines, _ := splitLines(content)
+
+	head, err := repo.Head()
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	var commit *object.Commit
+	headContent, exists := "", false
+	if head != nil {
+		if commit, err = repo.CommitObject(head.Hash()); err != nil {
+			return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+		}
+		if headContent, exists, err = s.commitFileContent(repo, commit, file); err != nil {
+			return nil, err
+		}
+	}
+
+	result := s.newBlameResult(file, commit, lines)
+
+	// Lines unchanged since HEAD keep looking for their origin, the rest are new
+	var pending []blameLine
+	if exists {
+		for bufferLine, headLine := range mapLines(headContent, content) {
+			if headLine > 0 {
+				pending = append(pending, blameLine{index: bufferLine - 1, line: headLine})
+			}
+		}
+	}
+
+	isPending := make(map[int]bool, len(pending))
+	for _, line := range pending {
+		isPending[line.index] = true
+	}
+	for i := range result.Lines {
+		if !isPending[i] {
+			result.Lines[i].Hash = UncommittedHash
+			result.Lines[i].Author = "Not Committed Yet"
+			result.Lines[i].OrigLine = i + 1
+			result.Lines[i].Uncommitted = true
+		}
+	}
+
+	if len(pending) > 0 {
+		if err := s.blameLines(repo, commit, file, pending, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// newBlameResult creates a result with an unassigned entry for every line
+func (s *GitService) newBlameResult(file string, commit *object.Commit, lines []string) *BlameResult {
+	result := &BlameResult{
+		Path:    file,
+		Lines:   make([]BlameLine, len(lines)),
+		Commits: make(map[string]BlameCommit),
+	}
+	if commit != nil {
+		result.Rev = commit.Hash.String()
+	}
+	for i, line := range lines {
+		result.Lines[i] = BlameLine{Line: i + 1, Content: line}
+	}
+	return result
+}
+
+// blameLines walks the history from start, newest commit first, and assigns each
+// line to the first commit that no parent passes it on to
+func (s *GitService) blameLines(repo *git.Repository, start *object.Commit, file string, lines []blameLine, result *BlameResult) error {
+	// A commit has one task per path it was reached with, which only differ when
+	// branches renamed the file differently
+	tasks := map[plumbing.Hash][]*blameTask{start.Hash: {{commit: start, path: file, lines: lines}}}
+	queue := &commitQueue{start}
+
+	// Contents are shared between commits that didn't change the file
+	contents := make(map[plumbing.Hash]string)
+	blobContent := func(hash plumbing.Hash) (string, error) {
+		if content, ok := contents[hash]; ok {
+			return content, nil
+		}
+		content, err := s.readBlob(repo, hash)
+		if err != nil {
+			return "", err
+		}
+		contents[hash] = content
+		return content, nil
+	}
+
+	for queue.Len() > 0 {
+		commit := heap.Pop(queue).(*object.Commit)
+		commitTasks, ok := tasks[commit.Hash]
+		if !ok {
+			continue
+		}
+		delete(tasks, commit.Hash)
+
+		for _, task := range commitTasks {
+			if err := s.passBlame(queue, tasks, task, blobContent, result); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// passBlame passes the lines of a task on to the parents of its commit that have
+// them, queueing a task for each, and assigns the rest to the commit
+func (s *GitService) passBlame(queue *commitQueue, tasks map[plumbing.Hash][]*blameTask, task *blameTask, blobContent func(plumbing.Hash) (string, error), result *BlameResult) error {
+	commit := task.commit
+	entry, err := s.commitFileEntry(commit, task.path)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("%s does not exist in %s", task.path, commit.Hash.String()[:7])
+	}
+
+	remaining := task.lines
+	for i := 0; i < commit.NumParents() && len(remaining) > 0; i++ {
+		parent, err := commit.Parent(i)
+		if err != nil {
+			return fmt.Errorf("failed to get parent commit: %w", err)
+		}
+
+		parentPath := task.path
+		parentEntry, err := s.commitFileEntry(parent, parentPath)
+		if err != nil {
+			return err
+		}
+		if parentEntry == nil {
+			// The lines may still come from the file the commit renamed
+			parentPath, err = s.renameSource(parent, commit, task.path)
+			if err != nil {
+				return err
+			}
+			if parentPath == "" {
+				continue
+			}
+			parentEntry, err = s.commitFileEntry(parent, parentPath)
+			if err != nil {
+				return err
+			}
+			if parentEntry == nil {
+				continue
+			}
+		}
+
+		// Lines the parent has too are its to explain, the rest stay with this commit
+		candidates := remaining
+		remaining = nil
+		passed := candidates
+		if parentEntry.Hash != entry.Hash {
+			content, err := blobContent(entry.Hash)
+			if err != nil {
+				return err
+			}
+			parentContent, err := blobContent(parentEntry.Hash)
+			if err != nil {
+				return err
+			}
+
+			mapping := mapLines(parentContent, content)
+			passed = nil
+			for _, line := range candidates {
+				if parentLine := mapping[line.line]; parentLine > 0 {
+					passed = append(passed, blameLine{index: line.index, line: parentLine})
+				} else {
+					remaining = append(remaining, line)
+				}
+			}
+		}
+
+		if len(passed) == 0 {
+			continue
+		}
+		queueBlame(queue, tasks, parent, parentPath, passed)
+	}
+
+	if len(remaining) > 0 {
+		s.assignBlame(result, commit, remaining)
+	}
+
+	return nil
+}
+
+// queueBlame adds lines to the task of a commit for a path, queueing the commit
+// if it has no task yet
+func queueBlame(queue *commitQueue, tasks map[plumbing.Hash][]*blameTask, commit *object.Commit, path string, lines []blameLine) {
+	commitTasks := tasks[commit.Hash]
+	for _, task := range commitTasks {
+		if task.path == path {
+			task.lines = append(task.lines, lines...)
+			return
+		}
+	}
+
+	tasks[commit.Hash] = append(commitTasks, &blameTask{commit: commit, path: path, lines: lines})
+	if len(commitTasks) == 0 {
+		heap.Push(queue, commit)
+	}
+}
+
+// assignBlame records a commit as the origin of lines
+func (s *GitService) assignBlame(result *BlameResult, commit *object.Commit, lines []blameLine) {
+	hash := commit.Hash.String()
+	for _, line := range lines {
+		result.Lines[line.index].Hash = hash
+		result.Lines[line.index].Author = commit.Author.Name
+		result.Lines[line.index].Date = commit.Author.When
+		result.Lines[line.index].OrigLine = line.line
+	}
+
+	if _, ok := result.Commits[hash]; !ok {
+		result.Commits[hash] = BlameCommit{
+			Hash:        hash,
+			Author:      commit.Author.Name,
+			AuthorEmail: commit.Author.Email,
+			Date:        commit.Author.When,
+			Summary:     firstLine(commit.Message),
+		}
+	}
+}
+
+// mapLines maps each line number of newContent to its line number in oldContent,
+// or to 0 when the line was added
+func mapLines(oldContent, newContent string) map[int]int {
+	mapping := make(map[int]int)
+	oldLine, newLine := 0, 0
+	for _, op := range diffLines(oldContent, newContent) {
+		switch op.kind {
+		case '-':
+			oldLine++
+		case '+':
+			newLine++
+			mapping[newLine] = 0
+		default:
+			oldLine++
+			newLine++
+			mapping[newLine] = oldLine
+		}
+	}
+	return mapping
+}
+
+// resolveCommit returns the commit a revision points at, HEAD if empty.
+// Reflog revisions like HEAD@{1} are supported.
+func (s *GitService) resolveCommit(repo *git.Repository, rev string) (*object.Commit, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+
+	hash, ok, err := s.reflogRevision(repo, rev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", rev, err)
+	}
+	if !ok {
+		resolved, err := repo.ResolveRevision(plumbing.Revision(rev))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", rev, err)
+		}
+		hash = *resolved
+	}
+
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit: %w", err)
+	}
+
+	return commit, nil
+}
+
+// commitFileEntry returns the tree entry of a file in a commit, or nil if it doesn't exist there
+func (s *GitService) commitFileEntry(commit *object.Commit, file string) (*object.TreeEntry, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+
+	entry, err := tree.FindEntry(file)
+	if err != nil {
+		if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find %s: %w", file, err)
+	}
+	if !entry.Mode.IsFile() {
+		return nil, nil
+	}
+
+	return entry, nil
+}
+
+// commitFileContent returns the content of a file in a commit and whether it exists there
+func (s *GitService) commitFileContent(repo *git.Repository, commit *object.Commit, file string) (string, bool, error) {
+	entry, err := s.commitFileEntry(commit, file)
+	if err != nil || entry == nil {
+		return "", false, err
+	}
+
+	content, err := s.readBlob(repo, entry.Hash)
+	if err != nil {
+		return "", false, err
+	}
+
+	if isContentBinary([]byte(content)) {
+		return "", false, errors.New("blame is not supported for binary files")
+	}
+
+	return content, true, nil
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestBlameAcrossRenames blames a file that was renamed and edited on both sides of
+// the rename and checks every line against git blame
+func TestBlameAcrossRenames(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.txt", "one\ntwo\nthree\nfour\nfive\n")
+	commitAll(t, dir, "Add a")
+	writeTestFile(t, dir, "a.txt", "one\ntwo changed\nthree\nfour\nfive\n")
+	commitAll(t, dir, "Change two")
+	if err := os.Mkdir(filepath.Join(dir, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "mv", "a.txt", "dir/renamed.txt")
+	commitAll(t, dir, "Rename a")
+	writeTestFile(t, dir, "dir/renamed.txt", "one\ntwo changed\nthree\nfour changed\nfive\nsix\n")
+	commitAll(t, dir, "Change four")
+
+	s := NewGitService(nil)
+	result, err := s.Blame(dir, "dir/renamed.txt", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Split(strings.TrimSpace(runGit(t, dir, "blame", "--root", "-l", "-s", "dir/renamed.txt")), "\n")
+	if len(result.Lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(result.Lines))
+	}
+	for i, line := range expected {
+		hash := strings.Fields(line)[0]
+		if result.Lines[i].Hash != hash {
+			t.Errorf("line %d: expected %s, got %s", i+1, hash, result.Lines[i].Hash)
+		}
+	}
+}
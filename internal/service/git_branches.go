@@ -0,0 +1,620 @@
+package service
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrBranchNotMerged is returned when deleting a branch that is not fully merged into HEAD
+var ErrBranchNotMerged = errors.New("branch is not fully merged")
+
+// CheckoutConflictError is returned when switching branches would overwrite local changes
+type CheckoutConflictError struct {
+	Branch string   `json:"branch"` // Branch that was being checked out
+	Files  []string `json:"files"`  // Files with local changes that differ in the target branch
+}
+
+func (e *CheckoutConflictError) Error() string {
+	return fmt.Sprintf("cannot checkout %q: local changes to the following files would be overwritten: %s",
+		e.Branch, strings.Join(e.Files, ", "))
+}
+
+// CheckoutBranch switches the working tree to the given branch.
+// Local changes are carried over as long as they don't touch files that differ
+// between HEAD and the target branch, otherwise a *CheckoutConflictError is returned.
+// Remote branches ("origin/feature", or just "feature" when only a remote has it)
+// are checked out into a new local branch that tracks the remote one.
+func (s *GitService) CheckoutBranch(projectPath string, branch string) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	localName, err := s.resolveCheckoutBranch(repo, branch)
+	if err != nil {
+		return err
+	}
+
+	return s.switchBranch(repo, worktree, localName)
+}
+
+// resolveCheckoutBranch returns the local branch to check out for the given name,
+// creating a tracking branch first when the name refers to a remote branch
+func (s *GitService) resolveCheckoutBranch(repo *git.Repository, branch string) (plumbing.ReferenceName, error) {
+	localName := plumbing.NewBranchReferenceName(branch)
+	if _, err := repo.Reference(localName, true); err == nil {
+		return localName, nil
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", fmt.Errorf("failed to read repository config: %w", err)
+	}
+
+	// "origin/feature" style names point straight at a remote-tracking ref
+	if remoteName, remoteBranch, ok := strings.Cut(branch, "/"); ok {
+		if _, isRemote := cfg.Remotes[remoteName]; isRemote {
+			remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(remoteName, remoteBranch), true)
+			if err == nil {
+				return s.createTrackingBranch(repo, remoteBranch, remoteName, remoteRef)
+			}
+		}
+	}
+
+	// Otherwise look for a single remote that has a branch with this name
+	var matches []*plumbing.Reference
+	var matchRemotes []string
+	for remoteName := range cfg.Remotes {
+		remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(remoteName, branch), true)
+		if err == nil {
+			matches = append(matches, remoteRef)
+			matchRemotes = append(matchRemotes, remoteName)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("branch %q not found", branch)
+	case 1:
+		return s.createTrackingBranch(repo, branch, matchRemotes[0], matches[0])
+	default:
+		sort.Strings(matchRemotes)
+		return "", fmt.Errorf("branch %q exists on multiple remotes (%s), use <remote>/%s", branch, strings.Join(matchRemotes, ", "), branch)
+	}
+}
+
+// createTrackingBranch creates a local branch pointing at a remote-tracking ref and
+// configures it to track that remote branch. An existing local branch is reused as is.
+func (s *GitService) createTrackingBranch(repo *git.Repository, branch, remoteName string, remoteRef *plumbing.Reference) (plumbing.ReferenceName, error) {
+	localName := plumbing.NewBranchReferenceName(branch)
+	if _, err := repo.Reference(localName, true); err == nil {
+		return localName, nil
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(localName, remoteRef.Hash())); err != nil {
+		return "", fmt.Errorf("failed to create branch: %w", err)
+	}
+
+	if err := s.setBranchUpstream(repo, branch, remoteName, plumbing.NewBranchReferenceName(branch)); err != nil {
+		return "", err
+	}
+
+	return localName, nil
+}
+
+// setBranchUpstream records the upstream of a local branch in the repository config
+func (s *GitService) setBranchUpstream(repo *git.Repository, branch, remoteName string, merge plumbing.ReferenceName) error {
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read repository config: %w", err)
+	}
+
+	cfg.Branches[branch] = &config.Branch{
+		Name:   branch,
+		Remote: remoteName,
+		Merge:  merge,
+	}
+
+	if err := repo.Storer.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to write repository config: %w", err)
+	}
+
+	return nil
+}
+
+// switchBranch points HEAD at the given local branch and updates the index and
+// working tree for every file that differs between the current HEAD and the branch
+func (s *GitService) switchBranch(repo *git.Repository, worktree *git.Worktree, branch plumbing.ReferenceName) error {
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD reference: %w", err)
+	}
+	if head.Type() == plumbing.SymbolicReference && head.Target() == branch {
+		return nil
+	}
+
+	target, err := repo.Reference(branch, true)
+	if err != nil {
+		return fmt.Errorf("failed to get branch reference: %w", err)
+	}
+
+	files, err := s.prepareCheckout(repo, worktree, target.Hash(), branch.Short())
+	if err != nil {
+		return err
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+		return fmt.Errorf("failed to update HEAD: %w", err)
+	}
+
+	return s.checkoutFiles(worktree, target.Hash(), files)
+}
+
+// headTree returns the tree of the HEAD commit, or nil when HEAD is unborn
+func (s *GitService) headTree(repo *git.Repository) (*object.Tree, error) {
+	head, err := repo.Head()
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD tree: %w", err)
+	}
+
+	return tree, nil
+}
+
+// prepareCheckout returns the files that differ between HEAD and the target commit.
+// It returns a *CheckoutConflictError when any of those files has local changes,
+// including untracked files that the target would overwrite.
+func (s *GitService) prepareCheckout(repo *git.Repository, worktree *git.Worktree, target plumbing.Hash, label string) ([]string, error) {
+	targetCommit, err := repo.CommitObject(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit: %w", err)
+	}
+
+	targetTree, err := targetCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+
+	headTree, err := s.headTree(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(headTree, targetTree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare trees: %w", err)
+	}
+
+	changed := make(map[string]bool, len(changes))
+	var files []string
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" && !changed[name] {
+				changed[name] = true
+				files = append(files, name)
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	status, err := s.status(repo, worktree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	var conflicts []string
+	for file, fileStatus := range status {
+		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified {
+			continue
+		}
+		if changed[file] {
+			conflicts = append(conflicts, file)
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return nil, &CheckoutConflictError{Branch: label, Files: conflicts}
+	}
+
+	return files, nil
+}
+
+// checkoutFiles resets the index and working tree of the given files to the target
+// commit, moving the current branch to it. Other local changes are left untouched.
+func (s *GitService) checkoutFiles(worktree *git.Worktree, target plumbing.Hash, files []string) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	err := worktree.Reset(&git.ResetOptions{
+		Commit: target,
+		Mode:   git.HardReset,
+		Files:  files,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update working tree: %w", err)
+	}
+
+	return nil
+}
+
+// CreateBranch creates a new local branch starting at startPoint.
+// The start point can be a branch, tag or commit hash; an empty start point means HEAD.
+func (s *GitService) CreateBranch(projectPath string, name string, startPoint string) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	refName := plumbing.NewBranchReferenceName(name)
+	if err := refName.Validate(); err != nil {
+		return fmt.Errorf("invalid branch name %q: %w", name, err)
+	}
+
+	if _, err := repo.Reference(refName, false); err == nil {
+		return fmt.Errorf("a branch named %q already exists", name)
+	}
+
+	var hash plumbing.Hash
+	if startPoint == "" {
+		head, err := repo.Head()
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD reference: %w", err)
+		}
+		hash = head.Hash()
+	} else {
+		resolved, err := repo.ResolveRevision(plumbing.Revision(startPoint))
+		if err != nil {
+			return fmt.Errorf("failed to resolve %q: %w", startPoint, err)
+		}
+		hash = *resolved
+	}
+
+	if _, err := repo.CommitObject(hash); err != nil {
+		return fmt.Errorf("start point %q is not a commit: %w", startPoint, err)
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(refName, hash)); err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteBranch deletes a local branch and its config section.
+// Unless force is set, the branch must be fully merged into HEAD.
+func (s *GitService) DeleteBranch(projectPath string, name string, force bool) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	refName := plumbing.NewBranchReferenceName(name)
+	ref, err := repo.Reference(refName, false)
+	if err != nil {
+		return fmt.Errorf("branch %q not found", name)
+	}
+
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD reference: %w", err)
+	}
+	if head.Type() == plumbing.SymbolicReference && head.Target() == refName {
+		return fmt.Errorf("cannot delete branch %q: it is currently checked out", name)
+	}
+
+	if !force {
+		merged, err := s.isMergedIntoHead(repo, ref.Hash())
+		if err != nil {
+			return err
+		}
+		if !merged {
+			return fmt.Errorf("cannot delete branch %q: %w", name, ErrBranchNotMerged)
+		}
+	}
+
+	if err := repo.Storer.RemoveReference(refName); err != nil {
+		return fmt.Errorf("failed to delete branch: %w", err)
+	}
+
+	if err := repo.DeleteBranch(name); err != nil && !errors.Is(err, git.ErrBranchNotFound) {
+		return fmt.Errorf("failed to remove branch config: %w", err)
+	}
+
+	return nil
+}
+
+// isMergedIntoHead reports whether the given commit is reachable from HEAD
+func (s *GitService) isMergedIntoHead(repo *git.Repository, hash plumbing.Hash) (bool, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return false, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if head.Hash() == hash {
+		return true, nil
+	}
+
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to get commit: %w", err)
+	}
+
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return false, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	merged, err := commit.IsAncestor(headCommit)
+	if err != nil {
+		return false, fmt.Errorf("failed to check merge status: %w", err)
+	}
+
+	return merged, nil
+}
+
+// RenameBranch renames a local branch, moving its config and HEAD along with it
+func (s *GitService) RenameBranch(projectPath string, oldName string, newName string) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	oldRef := plumbing.NewBranchReferenceName(oldName)
+	newRef := plumbing.NewBranchReferenceName(newName)
+	if err := newRef.Validate(); err != nil {
+		return fmt.Errorf("invalid branch name %q: %w", newName, err)
+	}
+
+	ref, err := repo.Reference(oldRef, false)
+	if err != nil {
+		return fmt.Errorf("branch %q not found", oldName)
+	}
+
+	if _, err := repo.Reference(newRef, false); err == nil {
+		return fmt.Errorf("a branch named %q already exists", newName)
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(newRef, ref.Hash())); err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
+	}
+
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD reference: %w", err)
+	}
+	if head.Type() == plumbing.SymbolicReference && head.Target() == oldRef {
+		if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, newRef)); err != nil {
+			return fmt.Errorf("failed to update HEAD: %w", err)
+		}
+	}
+
+	if err := repo.Storer.RemoveReference(oldRef); err != nil {
+		return fmt.Errorf("failed to remove old branch: %w", err)
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read repository config: %w", err)
+	}
+	if branchCfg, ok := cfg.Branches[oldName]; ok {
+		delete(cfg.Branches, oldName)
+		branchCfg.Name = newName
+		cfg.Branches[newName] = branchCfg
+		if err := repo.Storer.SetConfig(cfg); err != nil {
+			return fmt.Errorf("failed to write repository config: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetBranchTracking returns upstream and ahead/behind information for a local branch.
+// An empty branch name means the current branch. Only local refs are read.
+func (s *GitService) GetBranchTracking(projectPath string, branch string) (*BranchInfo, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.currentBranch(repo)
+	if err != nil && branch == "" {
+		return nil, err
+	}
+	if branch == "" {
+		branch = current.Short()
+	}
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return nil, fmt.Errorf("branch %q not found", branch)
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository config: %w", err)
+	}
+
+	info := &BranchInfo{
+		Name:   branch,
+		IsHead: ref.Name() == current,
+	}
+	if err := s.fillBranchTracking(repo, cfg, ref, info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// fillBranchTracking sets the upstream, ahead/behind counts and last commit of a local branch
+func (s *GitService) fillBranchTracking(repo *git.Repository, cfg *config.Config, ref *plumbing.Reference, info *BranchInfo) error {
+	if err := s.fillLastCommit(repo, ref.Hash(), info); err != nil {
+		return err
+	}
+
+	branchCfg, ok := cfg.Branches[ref.Name().Short()]
+	if !ok || branchCfg.Merge == "" {
+		return nil
+	}
+
+	// "." as remote means the upstream is another local branch
+	upstreamRef := plumbing.NewRemoteReferenceName(branchCfg.Remote, branchCfg.Merge.Short())
+	if branchCfg.Remote == localRemote {
+		upstreamRef = branchCfg.Merge
+	}
+	info.Upstream = upstreamRef.Short()
+
+	upstream, err := repo.Reference(upstreamRef, true)
+	if err != nil {
+		// The upstream is gone or was never fetched
+		return nil
+	}
+
+	info.Ahead, info.Behind, err = aheadBehind(repo, ref.Hash(), upstream.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to compare %s with %s: %w", info.Name, info.Upstream, err)
+	}
+
+	return nil
+}
+
+// fillLastCommit sets the date and author of the commit a branch points at
+func (s *GitService) fillLastCommit(repo *git.Repository, hash plumbing.Hash, info *BranchInfo) error {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return fmt.Errorf("failed to get commit for %s: %w", info.Name, err)
+	}
+
+	info.LastCommitDate = commit.Author.When
+	info.LastCommitAuthor = commit.Author.Name
+	return nil
+}
+
+// Flags used by aheadBehind to mark which side reaches a commit
+const (
+	reachLocal    uint8 = 1
+	reachUpstream uint8 = 2
+	reachBoth           = reachLocal | reachUpstream
+)
+
+// commitQueue is a max-heap of commits ordered by committer time
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int            { return len(q) }
+func (q commitQueue) Less(i, j int) bool  { return q[i].Committer.When.After(q[j].Committer.When) }
+func (q commitQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x interface{}) { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// divergentCommits marks the commits reachable from local, upstream or both. Both
+// histories are walked newest first and the walk stops as soon as every pending commit
+// is reachable from both sides, so only the divergent part is read: every commit that
+// only one side reaches is marked, commits both reach may not be.
+func divergentCommits(repo *git.Repository, local, upstream plumbing.Hash) (map[plumbing.Hash]uint8, error) {
+	if local == upstream {
+		return map[plumbing.Hash]uint8{local: reachBoth}, nil
+	}
+
+	flags := map[plumbing.Hash]uint8{}
+	walked := map[plumbing.Hash]uint8{}
+	queue := &commitQueue{}
+
+	for _, start := range []struct {
+		hash plumbing.Hash
+		flag uint8
+	}{{local, reachLocal}, {upstream, reachUpstream}} {
+		commit, err := repo.CommitObject(start.hash)
+		if err != nil {
+			return nil, err
+		}
+		flags[start.hash] |= start.flag
+		heap.Push(queue, commit)
+	}
+
+	for queue.Len() > 0 {
+		pending := false
+		for _, c := range *queue {
+			if flags[c.Hash] != reachBoth {
+				pending = true
+				break
+			}
+		}
+		if !pending {
+			break
+		}
+
+		commit := heap.Pop(queue).(*object.Commit)
+		flag := flags[commit.Hash]
+		if walked[commit.Hash] == flag {
+			continue
+		}
+		walked[commit.Hash] = flag
+
+		for _, parentHash := range commit.ParentHashes {
+			if flags[parentHash]|flag == flags[parentHash] {
+				continue
+			}
+			flags[parentHash] |= flag
+
+			parent, err := repo.CommitObject(parentHash)
+			if err != nil {
+				return nil, err
+			}
+			heap.Push(queue, parent)
+		}
+	}
+
+	return flags, nil
+}
+
+// aheadBehind counts the commits reachable only from local and only from upstream
+func aheadBehind(repo *git.Repository, local, upstream plumbing.Hash) (int, int, error) {
+	flags, err := divergentCommits(repo, local, upstream)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var ahead, behind int
+	for _, flag := range flags {
+		switch flag {
+		case reachLocal:
+			ahead++
+		case reachUpstream:
+			behind++
+		}
+	}
+
+	return ahead, behind, nil
+}
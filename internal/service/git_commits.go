@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// CommitFile describes a file changed by a commit
+type CommitFile struct {
+	Path     string    `json:"path"`     // File path after the commit
+	OldPath  string    `json:"oldPath"`  // Previous path when the file was renamed
+	Status   string    `json:"status"`   // "A" for added, "M" for modified, "D" for deleted and "R" for renamed
+	Stats    DiffStats `json:"stats"`    // Statistics about the changes
+	IsBinary bool      `json:"isBinary"` // Whether the file is binary
+}
+
+// CommitDetails contains the full information about a commit and the changes it made
+type CommitDetails struct {
+	Hash           string       `json:"hash"`
+	Message        string       `json:"message"` // Full commit message
+	Author         string       `json:"author"`
+	AuthorEmail    string       `json:"authorEmail"`
+	AuthorDate     time.Time    `json:"authorDate"`
+	Committer      string       `json:"committer"`
+	CommitterEmail string       `json:"committerEmail"`
+	CommitDate     time.Time    `json:"commitDate"`
+	ParentHashes   []string     `json:"parentHashes"`
+	ComparedTo     string       `json:"comparedTo"` // Parent the changes are relative to, empty for a root commit
+	Files          []CommitFile `json:"files"`      // Changed files, ordered by path
+	Diffs          []FileDiff   `json:"diffs"`      // Diff of each changed file, in the same order
+}
+
+// CommitDetailsOptions contains options for inspecting a commit
+type CommitDetailsOptions struct {
+	Parent int         `json:"parent"` // Parent to compare a merge commit to, 0 for the first parent
+	Diff   DiffOptions `json:"diff"`   // Options for the per-file diffs
+}
+
+// GetCommitDetails returns the full message, identities and changes of a commit,
+// compared to its first parent
+func (s *GitService) GetCommitDetails(projectPath string, hash string) (*CommitDetails, error) {
+	return s.GetCommitDetailsWithOptions(projectPath, hash, CommitDetailsOptions{})
+}
+
+// GetCommitDetailsWithOptions returns the details of a commit, compared to the chosen parent
+func (s *GitService) GetCommitDetailsWithOptions(projectPath string, hash string, opts CommitDetailsOptions) (*CommitDetails, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	commitHash, err := repo.ResolveRevision(plumbing.Revision(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve commit %s: %w", hash, err)
+	}
+
+	commit, err := repo.CommitObject(*commitHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit: %w", err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit tree: %w", err)
+	}
+
+	details := &CommitDetails{
+		Hash:           commit.Hash.String(),
+		Message:        commit.Message,
+		Author:         commit.Author.Name,
+		AuthorEmail:    commit.Author.Email,
+		AuthorDate:     commit.Author.When,
+		Committer:      commit.Committer.Name,
+		CommitterEmail: commit.Committer.Email,
+		CommitDate:     commit.Committer.When,
+		ParentHashes:   make([]string, 0, commit.NumParents()),
+	}
+	for _, parent := range commit.ParentHashes {
+		details.ParentHashes = append(details.ParentHashes, parent.String())
+	}
+
+	// A root commit is compared to the empty tree
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		if opts.Parent < 0 || opts.Parent >= commit.NumParents() {
+			return nil, fmt.Errorf("commit %s has no parent %d", commit.Hash.String()[:7], opts.Parent)
+		}
+
+		parent, err := commit.Parent(opts.Parent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent commit: %w", err)
+		}
+
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, fmt.Errorf("failed to get parent tree: %w", err)
+		}
+		details.ComparedTo = parent.Hash.String()
+	}
+
+	details.Diffs, err = s.diffTrees(repo, parentTree, tree, opts.Diff)
+	if err != nil {
+		return nil, err
+	}
+
+	details.Files = make([]CommitFile, 0, len(details.Diffs))
+	for _, diff := range details.Diffs {
+		details.Files = append(details.Files, CommitFile{
+			Path:     diff.Path,
+			OldPath:  diff.OldPath,
+			Status:   diff.Status,
+			Stats:    diff.Stats,
+			IsBinary: diff.IsBinary,
+		})
+	}
+
+	return details, nil
+}
@@ -0,0 +1,495 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Ways to resolve a conflicted file
+const (
+	ResolveOurs     = "ours"     // Take the version of the current branch
+	ResolveTheirs   = "theirs"   // Take the version being merged
+	ResolveBase     = "base"     // Take the version of the merge base
+	ResolveContent  = "content"  // Take the given content
+	ResolveWorktree = "worktree" // Take the file as it is in the working tree
+)
+
+// ConflictFile holds the versions of a file with merge conflicts
+type ConflictFile struct {
+	Path      string `json:"path"`      // File path relative to repository root
+	Base      string `json:"base"`      // Content in the merge base
+	Ours      string `json:"ours"`      // Content on the current branch
+	Theirs    string `json:"theirs"`    // Content on the merged branch
+	HasBase   bool   `json:"hasBase"`   // Whether the file exists in the merge base
+	HasOurs   bool   `json:"hasOurs"`   // Whether the file exists on the current branch
+	HasTheirs bool   `json:"hasTheirs"` // Whether the file exists on the merged branch
+	IsBinary  bool   `json:"isBinary"`  // Whether any version is binary, contents are left empty then
+}
+
+// ConflictResolution selects how a conflicted file is resolved
+type ConflictResolution struct {
+	Choice  string `json:"choice"`  // "ours", "theirs", "base", "content" or "worktree"
+	Content string `json:"content"` // Resolved content when Choice is "content"
+}
+
+// ListConflicts returns the base, ours and theirs versions of every unmerged file
+func (s *GitService) ListConflicts(projectPath string) ([]ConflictFile, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index: %w", err)
+	}
+
+	conflicts := make(map[string]*ConflictFile)
+	for _, entry := range idx.Entries {
+		if entry.Stage == 0 {
+			continue
+		}
+
+		conflict, ok := conflicts[entry.Name]
+		if !ok {
+			conflict = &ConflictFile{Path: entry.Name}
+			conflicts[entry.Name] = conflict
+		}
+
+		content, err := s.readBlob(repo, entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if isContentBinary([]byte(content)) {
+			conflict.IsBinary = true
+		}
+
+		switch entry.Stage {
+		case index.AncestorMode:
+			conflict.Base, conflict.HasBase = content, true
+		case index.OurMode:
+			conflict.Ours, conflict.HasOurs = content, true
+		case index.TheirMode:
+			conflict.Theirs, conflict.HasTheirs = content, true
+		}
+	}
+
+	files := make([]ConflictFile, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		if conflict.IsBinary {
+			conflict.Base, conflict.Ours, conflict.Theirs = "", "", ""
+		}
+		files = append(files, *conflict)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return files, nil
+}
+
+// ResolveConflict marks a conflicted file as resolved, writing the chosen version
+// to the working tree and staging it. Choosing a side the file doesn't exist on deletes it.
+func (s *GitService) ResolveConflict(projectPath string, file string, resolution ConflictResolution) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	return s.resolveConflict(repo, worktree, file, resolution)
+}
+
+// resolveConflict replaces the index stages of a conflicted file with the resolved version
+func (s *GitService) resolveConflict(repo *git.Repository, worktree *git.Worktree, file string, resolution ConflictResolution) error {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	stages := make(map[index.Stage]*index.Entry)
+	for _, entry := range idx.Entries {
+		if entry.Name == file && entry.Stage != 0 {
+			stages[entry.Stage] = entry
+		}
+	}
+	if len(stages) == 0 {
+		return fmt.Errorf("%s has no conflicts", file)
+	}
+
+	fullPath := filepath.Join(worktree.Filesystem.Root(), file)
+
+	var resolved *object.TreeEntry
+	switch resolution.Choice {
+	case ResolveOurs, ResolveTheirs, ResolveBase:
+		stage := map[string]index.Stage{
+			ResolveOurs:   index.OurMode,
+			ResolveTheirs: index.TheirMode,
+			ResolveBase:   index.AncestorMode,
+		}[resolution.Choice]
+		if entry, ok := stages[stage]; ok {
+			resolved = &object.TreeEntry{Name: file, Hash: entry.Hash, Mode: entry.Mode}
+			if err := s.writeEntryFile(repo, fullPath, resolved); err != nil {
+				return err
+			}
+		}
+	case ResolveContent:
+		// Keep the mode of the file on either side
+		mode := filemode.Regular
+		for _, stage := range []index.Stage{index.OurMode, index.TheirMode} {
+			if entry, ok := stages[stage]; ok {
+				mode = entry.Mode
+				break
+			}
+		}
+		hash, err := s.storeBlob(repo, []byte(resolution.Content))
+		if err != nil {
+			return err
+		}
+		resolved = &object.TreeEntry{Name: file, Hash: hash, Mode: mode}
+		if err := s.writeFileContent(fullPath, []byte(resolution.Content), mode); err != nil {
+			return err
+		}
+	case ResolveWorktree:
+		if _, err := os.Lstat(fullPath); err == nil {
+			entry, err := s.storeWorktreeFile(repo, worktree, file)
+			if err != nil {
+				return err
+			}
+			resolved = &entry
+		}
+	default:
+		return fmt.Errorf("unknown conflict resolution %q", resolution.Choice)
+	}
+
+	if resolved == nil {
+		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete %s: %w", file, err)
+		}
+	}
+
+	if err := s.setIndexEntry(repo, idx, file, resolved); err != nil {
+		return err
+	}
+
+	return s.setIndex(repo, idx)
+}
+
+// MergeAbort abandons a merge, cherry-pick or revert with conflicts,
+// restoring HEAD in the index and working tree
+func (s *GitService) MergeAbort(projectPath string) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	_, merging, err := s.mergeHead(repo)
+	if err != nil {
+		return err
+	}
+	_, _, picking, err := s.pickHead(repo)
+	if err != nil {
+		return err
+	}
+	if !merging && !picking {
+		return errors.New("there is no merge to abort")
+	}
+
+	if err := s.restoreHead(repo, worktree); err != nil {
+		return err
+	}
+
+	return s.clearMergeState(repo)
+}
+
+// restoreHead puts the HEAD version of every file the index doesn't have at HEAD back in
+// the index and working tree, which undoes everything a merge wrote
+func (s *GitService) restoreHead(repo *git.Repository, worktree *git.Worktree) error {
+	headTree, err := s.headTree(repo)
+	if err != nil {
+		return err
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	// Restore every path whose index entry differs from HEAD
+	paths := make(map[string]bool)
+	for _, entry := range idx.Entries {
+		if entry.Stage != 0 {
+			paths[entry.Name] = true
+			continue
+		}
+		headEntry, err := findEntry(headTree, entry.Name)
+		if err != nil || headEntry.Hash != entry.Hash || headEntry.Mode != entry.Mode {
+			paths[entry.Name] = true
+		}
+	}
+	if headTree != nil {
+		err := headTree.Files().ForEach(func(file *object.File) error {
+			if _, err := idx.Entry(file.Name); err != nil {
+				paths[file.Name] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read HEAD tree: %w", err)
+		}
+	}
+
+	for path := range paths {
+		fullPath := filepath.Join(worktree.Filesystem.Root(), path)
+
+		headEntry, err := findEntry(headTree, path)
+		if err != nil {
+			headEntry = nil
+			if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to delete %s: %w", path, err)
+			}
+		} else if err := s.writeEntryFile(repo, fullPath, headEntry); err != nil {
+			return err
+		}
+
+		if err := s.setIndexEntry(repo, idx, path, headEntry); err != nil {
+			return err
+		}
+	}
+
+	return s.setIndex(repo, idx)
+}
+
+// findEntry returns the entry of a file in a tree, which may be nil
+func findEntry(tree *object.Tree, path string) (*object.TreeEntry, error) {
+	if tree == nil {
+		return nil, object.ErrEntryNotFound
+	}
+	return tree.FindEntry(path)
+}
+
+// unmergedPaths returns the files that have conflict stages in the index
+func (s *GitService) unmergedPaths(repo *git.Repository) (map[string]bool, error) {
+	idx, _, err := s.readIndex(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]bool)
+	for _, entry := range idx.Entries {
+		if entry.Stage != 0 {
+			paths[entry.Name] = true
+		}
+	}
+
+	return paths, nil
+}
+
+// removeIndexEntries removes every entry of a path from the index, including conflict stages
+func removeIndexEntries(idx *index.Index, path string) {
+	entries := idx.Entries[:0]
+	for _, entry := range idx.Entries {
+		if entry.Name != path {
+			entries = append(entries, entry)
+		}
+	}
+	idx.Entries = entries
+}
+
+// setIndexEntry replaces all entries of a path with a single merged entry, or removes them for a nil entry
+func (s *GitService) setIndexEntry(repo *git.Repository, idx *index.Index, path string, entry *object.TreeEntry) error {
+	removeIndexEntries(idx, path)
+	if entry == nil {
+		return nil
+	}
+
+	size, err := repo.Storer.EncodedObjectSize(entry.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to get blob size: %w", err)
+	}
+
+	idx.Entries = append(idx.Entries, &index.Entry{
+		Name: path,
+		Hash: entry.Hash,
+		Mode: entry.Mode,
+		Size: uint32(size),
+	})
+
+	return nil
+}
+
+// setIndex writes the index with its entries ordered by path and stage.
+// go-git only orders by path when encoding, which keeps an already ordered index as it is.
+func (s *GitService) setIndex(repo *git.Repository, idx *index.Index) error {
+	sort.SliceStable(idx.Entries, func(i, j int) bool {
+		if idx.Entries[i].Name != idx.Entries[j].Name {
+			return idx.Entries[i].Name < idx.Entries[j].Name
+		}
+		return idx.Entries[i].Stage < idx.Entries[j].Stage
+	})
+
+	// The cached trees no longer match the entries
+	idx.Cache = nil
+
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	return nil
+}
+
+// mergeHead returns the commit being merged when a merge is in progress
+func (s *GitService) mergeHead(repo *git.Repository) (plumbing.Hash, bool, error) {
+	dir, err := s.gitDir(repo)
+	if err != nil {
+		return plumbing.ZeroHash, false, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "MERGE_HEAD"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return plumbing.ZeroHash, false, nil
+		}
+		return plumbing.ZeroHash, false, fmt.Errorf("failed to read MERGE_HEAD: %w", err)
+	}
+
+	line, _, _ := strings.Cut(string(data), "\n")
+	return plumbing.NewHash(strings.TrimSpace(line)), true, nil
+}
+
+// mergeMessageFromState returns the prepared message of the merge in progress without comment lines
+func (s *GitService) mergeMessageFromState(repo *git.Repository) (string, error) {
+	dir, err := s.gitDir(repo)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "MERGE_MSG"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read MERGE_MSG: %w", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
+
+// writeMergeState records a merge waiting for its conflicts to be resolved,
+// in the same files git uses so the CLI can continue or abort it
+func (s *GitService) writeMergeState(repo *git.Repository, theirs plumbing.Hash, message string, conflicts []string, mode string) error {
+	files := map[string]string{
+		"MERGE_HEAD": theirs.String() + "\n",
+		"MERGE_MSG":  conflictMessage(message, conflicts),
+		"MERGE_MODE": "",
+	}
+	if mode == MergeNoFastForward {
+		files["MERGE_MODE"] = "no-ff"
+	}
+
+	return s.writeStateFiles(repo, files)
+}
+
+// writePickState records a cherry-pick or revert waiting for its conflicts to be resolved.
+// head is CHERRY_PICK_HEAD or REVERT_HEAD.
+func (s *GitService) writePickState(repo *git.Repository, head string, commit plumbing.Hash, message string, conflicts []string) error {
+	return s.writeStateFiles(repo, map[string]string{
+		head:        commit.String() + "\n",
+		"MERGE_MSG": conflictMessage(message, conflicts),
+	})
+}
+
+// pickHead returns the commit being cherry-picked or reverted and the name of the file recording it
+func (s *GitService) pickHead(repo *git.Repository) (string, plumbing.Hash, bool, error) {
+	dir, err := s.gitDir(repo)
+	if err != nil {
+		return "", plumbing.ZeroHash, false, err
+	}
+
+	for _, name := range []string{cherryPickHead, revertHead} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", plumbing.ZeroHash, false, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		line, _, _ := strings.Cut(string(data), "\n")
+		return name, plumbing.NewHash(strings.TrimSpace(line)), true, nil
+	}
+
+	return "", plumbing.ZeroHash, false, nil
+}
+
+// conflictMessage returns a commit message followed by the list of conflicts as comments
+func conflictMessage(message string, conflicts []string) string {
+	var msg strings.Builder
+	msg.WriteString(strings.TrimRight(message, "\n"))
+	if len(conflicts) == 0 {
+		msg.WriteString("\n")
+		return msg.String()
+	}
+	msg.WriteString("\n\n# Conflicts:\n")
+	for _, file := range conflicts {
+		fmt.Fprintf(&msg, "#\t%s\n", file)
+	}
+	return msg.String()
+}
+
+// writeStateFiles writes files to the .git directory
+func (s *GitService) writeStateFiles(repo *git.Repository, files map[string]string) error {
+	dir, err := s.gitDir(repo)
+	if err != nil {
+		return err
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// clearMergeState removes the files of a finished or abandoned merge, cherry-pick or revert
+func (s *GitService) clearMergeState(repo *git.Repository) error {
+	dir, err := s.gitDir(repo)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{"MERGE_HEAD", "MERGE_MSG", "MERGE_MODE", cherryPickHead, revertHead} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Special sides of DiffRefs besides branches, tags and commits
+const (
+	RefIndex    = "INDEX"    // The staged content
+	RefWorktree = "WORKTREE" // The tracked files in the working directory
+)
+
+// snapshotFile is a file in one side of a DiffRefs comparison
+type snapshotFile struct {
+	hash   plumbing.Hash
+	mode   filemode.FileMode
+	onDisk bool // Content differs from hash and has to be read from the working tree
+}
+
+// snapshot is one side of a DiffRefs comparison
+type snapshot struct {
+	tree  *object.Tree // Set when the side is a commit
+	files map[string]snapshotFile
+}
+
+// DiffRefs returns the diff of every file that differs between two sides, ordered by path.
+// A side is a branch, tag or commit (empty means HEAD), INDEX or WORKTREE. When paths
+// is not empty only files at or below those paths are compared. Renames are detected
+// on every side, so a staged git mv shows as a rename against INDEX and WORKTREE too.
+func (s *GitService) DiffRefs(projectPath string, fromRef string, toRef string, paths []string) ([]FileDiff, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	from, err := s.loadSnapshot(repo, worktree, fromRef)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := s.loadSnapshot(repo, worktree, toRef)
+	if err != nil {
+		return nil, err
+	}
+
+	if from.tree != nil && to.tree != nil {
+		diffs, err := s.diffTrees(repo, from.tree, to.tree, DiffOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		filtered := diffs[:0]
+		for _, diff := range diffs {
+			if matchesPaths(diff.Path, paths) || (diff.OldPath != "" && matchesPaths(diff.OldPath, paths)) {
+				filtered = append(filtered, diff)
+			}
+		}
+		return filtered, nil
+	}
+
+	changed := make(map[string]bool)
+	for path := range from.files {
+		changed[path] = true
+	}
+	for path := range to.files {
+		changed[path] = true
+	}
+
+	renames, err := s.snapshotRenames(repo, worktree, from, to)
+	if err != nil {
+		return nil, err
+	}
+	renamedFrom := make(map[string]string, len(renames))
+	for _, rename := range renames {
+		renamedFrom[rename.to] = rename.from
+		delete(changed, rename.from)
+	}
+
+	var diffs []FileDiff
+	for path := range changed {
+		oldPath := path
+		if source, ok := renamedFrom[path]; ok {
+			oldPath = source
+		}
+		if !matchesPaths(path, paths) && !matchesPaths(oldPath, paths) {
+			continue
+		}
+
+		fromFile, inFrom := from.files[oldPath]
+		toFile, inTo := to.files[path]
+		if oldPath == path && inFrom && inTo && !fromFile.onDisk && !toFile.onDisk && fromFile.hash == toFile.hash && fromFile.mode == toFile.mode {
+			continue
+		}
+
+		var oldSide, newSide diffSide
+		if inFrom {
+			if oldSide, err = s.snapshotSide(repo, worktree, oldPath, fromFile); err != nil {
+				return nil, err
+			}
+		}
+		if inTo {
+			if newSide, err = s.snapshotSide(repo, worktree, path, toFile); err != nil {
+				return nil, err
+			}
+		}
+
+		// Working tree files are only known to differ after reading them
+		if oldPath == path && oldSide.exists == newSide.exists && oldSide.content == newSide.content && fromFile.mode == toFile.mode {
+			continue
+		}
+
+		diffs = append(diffs, *generateDiff(oldSide, newSide, oldPath, path, DiffOptions{}))
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+
+	return diffs, nil
+}
+
+// loadSnapshot lists the files of one side of a DiffRefs comparison
+func (s *GitService) loadSnapshot(repo *git.Repository, worktree *git.Worktree, ref string) (*snapshot, error) {
+	switch ref {
+	case RefIndex, RefWorktree:
+		idx, _, err := s.readIndex(repo)
+		if err != nil {
+			return nil, err
+		}
+
+		snap := &snapshot{files: make(map[string]snapshotFile, len(idx.Entries))}
+		for _, entry := range idx.Entries {
+			// Unmerged files have no staged content yet
+			if entry.Stage == 0 {
+				snap.files[entry.Name] = snapshotFile{hash: entry.Hash, mode: entry.Mode}
+			}
+		}
+
+		if ref == RefIndex {
+			return snap, nil
+		}
+
+		// Only tracked files are part of the working tree side, like in git diff
+		status, err := s.status(repo, worktree)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status: %w", err)
+		}
+		for path, fileStatus := range status {
+			file, tracked := snap.files[path]
+			if !tracked {
+				continue
+			}
+			switch fileStatus.Worktree {
+			case git.Deleted:
+				delete(snap.files, path)
+			case git.Unmodified:
+			default:
+				file.onDisk = true
+				snap.files[path] = file
+			}
+		}
+		return snap, nil
+	}
+
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit: %w", err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+
+	snap := &snapshot{tree: tree, files: make(map[string]snapshotFile)}
+	err = tree.Files().ForEach(func(file *object.File) error {
+		snap.files[file.Name] = snapshotFile{hash: file.Hash, mode: file.Mode}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of %s: %w", ref, err)
+	}
+
+	return snap, nil
+}
+
+// snapshotRenames pairs the files only in the from side of a DiffRefs comparison with the
+// files only in the to side, like stagedRenames does for the index
+func (s *GitService) snapshotRenames(repo *git.Repository, worktree *git.Worktree, from, to *snapshot) ([]renamePair, error) {
+	var deleted, added []string
+	for path := range from.files {
+		if _, ok := to.files[path]; !ok {
+			deleted = append(deleted, path)
+		}
+	}
+	for path := range to.files {
+		if _, ok := from.files[path]; !ok {
+			added = append(added, path)
+		}
+	}
+	if len(deleted) == 0 || len(added) == 0 {
+		return nil, nil
+	}
+
+	root := worktree.Filesystem.Root()
+	describe := func(snap *snapshot) func(path string) *renameFile {
+		return func(path string) *renameFile {
+			file := snap.files[path]
+			if !file.onDisk {
+				return s.blobRenameFile(repo, path, file.hash, file.mode)
+			}
+			renameFile, err := worktreeRenameFile(root, path)
+			if err != nil {
+				return nil
+			}
+			return renameFile
+		}
+	}
+
+	return findRenames(renameFiles(deleted, describe(from)), nil, renameFiles(added, describe(to)), DefaultRenameSimilarity)
+}
+
+// snapshotSide returns the content of a file in a DiffRefs side
+func (s *GitService) snapshotSide(repo *git.Repository, worktree *git.Worktree, path string, file snapshotFile) (diffSide, error) {
+	if file.onDisk {
+		return s.worktreeSide(worktree, path)
+	}
+	return s.entrySide(repo, object.TreeEntry{Name: path, Hash: file.hash, Mode: file.mode})
+}
+
+// matchesPaths reports whether path is one of paths or inside one of them.
+// No paths match everything.
+func matchesPaths(path string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		p = strings.Trim(p, "/")
+		if p == "" || path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDiffRefsRenames compares HEAD with the index and the working tree after a staged
+// git mv, and checks the renames against git diff
+func TestDiffRefsRenames(t *testing.T) {
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.txt", "1\n2\n3\n4\n5\n6\n7\n8\n")
+	writeTestFile(t, dir, "keep.txt", "keep\n")
+	commitAll(t, dir, "Add files")
+
+	if err := os.Mkdir(filepath.Join(dir, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "mv", "a.txt", "dir/b.txt")
+	writeTestFile(t, dir, "dir/b.txt", "1\n2\n3\n4\n5\n6\n7\n8 changed\n")
+	writeTestFile(t, dir, "keep.txt", "kept\n")
+
+	s := NewGitService(nil)
+	for _, sides := range []struct {
+		from, to string
+		args     []string
+	}{
+		{"HEAD", RefIndex, []string{"diff", "--cached", "-M", "--name-status"}},
+		{"HEAD", RefWorktree, []string{"diff", "HEAD", "-M", "--name-status"}},
+		{RefIndex, RefWorktree, []string{"diff", "-M", "--name-status"}},
+	} {
+		diffs, err := s.DiffRefs(dir, sides.from, sides.to, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+		for _, diff := range diffs {
+			if diff.OldPath != "" {
+				got = append(got, diff.Status+" "+diff.OldPath+" "+diff.Path)
+			} else {
+				got = append(got, diff.Status+" "+diff.Path)
+			}
+		}
+
+		var expected []string
+		for _, line := range strings.Split(strings.TrimSpace(runGit(t, dir, sides.args...)), "\n") {
+			fields := strings.Fields(line)
+			fields[0] = fields[0][:1]
+			expected = append(expected, strings.Join(fields, " "))
+		}
+
+		if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("%s..%s: got\n%s\nexpected\n%s", sides.from, sides.to, strings.Join(got, "\n"), strings.Join(expected, "\n"))
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// Kinds of graph edges. A row is drawn with the commit dot in the middle of its column:
+// pass edges cross the whole row, merge-in edges end at the dot and parent edges start there.
+const (
+	EdgePass    = "pass"     // A lane passing the row, from its column at the top to its column at the bottom
+	EdgeMergeIn = "merge-in" // A lane from the top of the row that ends at the dot
+	EdgeParent  = "parent"   // From the dot to the lane of a parent at the bottom of the row
+)
+
+// Kinds of ref decorations
+const (
+	DecorationHead   = "head"   // The checked out branch, or "HEAD" when it is detached
+	DecorationBranch = "branch" // A local branch
+	DecorationRemote = "remote" // A remote-tracking branch
+	DecorationTag    = "tag"    // A tag
+)
+
+// decorationOrder sorts the decorations of a commit like git log: HEAD, branches, remotes, tags
+var decorationOrder = map[string]int{
+	DecorationHead:   0,
+	DecorationBranch: 1,
+	DecorationRemote: 2,
+	DecorationTag:    3,
+}
+
+// CommitGraph is a page of commits with the layout to draw them as a graph, like git log --graph
+type CommitGraph struct {
+	Commits []CommitInfo `json:"commits"`
+	Rows    []GraphRow   `json:"rows"`  // Layout of each commit, in the same order
+	Lanes   []GraphLane  `json:"lanes"` // Lanes still open after the last row, to lay out the next page
+}
+
+// GraphRow is the layout of one commit in the graph
+type GraphRow struct {
+	Hash        string            `json:"hash"`
+	Column      int               `json:"column"` // Lane of the commit dot
+	Color       int               `json:"color"`  // Color index of the commit's lane
+	Width       int               `json:"width"`  // Number of lanes the row spans
+	Edges       []GraphEdge       `json:"edges"`
+	Decorations []GraphDecoration `json:"decorations"`
+}
+
+// GraphEdge is a line drawn in a row between two lanes
+type GraphEdge struct {
+	Kind  string `json:"kind"`  // EdgePass, EdgeMergeIn or EdgeParent
+	From  int    `json:"from"`  // Lane at the top of the row, or the commit column for parent edges
+	To    int    `json:"to"`    // Lane at the bottom of the row, or the commit column for merge-in edges
+	Color int    `json:"color"` // Color index of the lane the edge belongs to
+}
+
+// GraphDecoration is a ref pointing at a commit
+type GraphDecoration struct {
+	Name string `json:"name"` // Short ref name, like "main", "origin/main" or "v1.0"
+	Kind string `json:"kind"` // DecorationHead, DecorationBranch, DecorationRemote or DecorationTag
+}
+
+// GraphLane is a lane waiting for a commit. Lanes keep their column and color
+// from row to row, a free lane has no hash and is reused by the next new lane.
+type GraphLane struct {
+	Hash  string `json:"hash"`
+	Color int    `json:"color"`
+}
+
+// ErrGraphFilter is returned when a commit graph is filtered by author, message or date
+var ErrGraphFilter = errors.New("the commit graph can't be filtered by author, message or date")
+
+// CommitGraph lists a page of commits in topological order, like git log --graph, and lays
+// them out as a graph. For the next page, pass the OffsetHash of the last commit and the
+// Lanes of this page, so the lanes continue where this page ended. The first page starts
+// with no lanes. Filters that skip commits would break the lanes and fail with ErrGraphFilter.
+func (s *GitService) CommitGraph(projectPath string, filter CommitFilter, lanes []GraphLane) (*CommitGraph, error) {
+	if filter.Author != "" || filter.SearchQuery != "" || !filter.StartDate.IsZero() || !filter.EndDate.IsZero() {
+		return nil, ErrGraphFilter
+	}
+
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := s.logStart(repo, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.graphOrder(projectPath, repo, start)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := s.collectCommits(&hashCommitIter{repo: repo, hashes: order}, filter, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	decorations, err := s.decorations(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &graphLayout{lanes: append([]GraphLane(nil), lanes...)}
+	graph := &CommitGraph{Commits: commits, Rows: make([]GraphRow, 0, len(commits))}
+	for _, commit := range commits {
+		row := layout.add(commit.Hash, commit.ParentHashes)
+		row.Decorations = decorations[commit.Hash]
+		graph.Rows = append(graph.Rows, row)
+	}
+	graph.Lanes = layout.lanes
+
+	return graph, nil
+}
+
+// graphOrder is the topological order of the commits reachable from a commit
+type graphOrder struct {
+	start  plumbing.Hash
+	hashes []plumbing.Hash
+}
+
+// graphOrder returns the commits reachable from start in topological order. Ordering them
+// needs the whole history, so the last order of each project is kept for the next pages.
+func (s *GitService) graphOrder(projectPath string, repo *git.Repository, start plumbing.Hash) ([]plumbing.Hash, error) {
+	s.graphsMu.Lock()
+	cached, ok := s.graphs[projectPath]
+	s.graphsMu.Unlock()
+	if ok && cached.start == start {
+		return cached.hashes, nil
+	}
+
+	hashes, err := topoOrder(repo, start)
+	if err != nil {
+		return nil, err
+	}
+
+	s.graphsMu.Lock()
+	s.graphs[projectPath] = &graphOrder{start: start, hashes: hashes}
+	s.graphsMu.Unlock()
+
+	return hashes, nil
+}
+
+// topoOrder lists the commits reachable from start with every commit before its parents, like
+// git log --topo-order: the commits of the last parent of a merge come right after it, so each
+// line of history stays together. Parents missing from a shallow clone are left out.
+func topoOrder(repo *git.Repository, start plumbing.Hash) ([]plumbing.Hash, error) {
+	parents := make(map[plumbing.Hash][]plumbing.Hash)
+	pending := []plumbing.Hash{start}
+	for len(pending) > 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if _, ok := parents[hash]; ok {
+			continue
+		}
+
+		commit, err := repo.CommitObject(hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) && hash != start {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+		}
+
+		parents[hash] = commit.ParentHashes
+		pending = append(pending, commit.ParentHashes...)
+	}
+
+	// A commit is ready once all its children are listed
+	children := make(map[plumbing.Hash]int, len(parents))
+	for _, hashes := range parents {
+		for _, parent := range hashes {
+			children[parent]++
+		}
+	}
+
+	order := make([]plumbing.Hash, 0, len(parents))
+	ready := []plumbing.Hash{start}
+	for len(ready) > 0 {
+		hash := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		order = append(order, hash)
+
+		for _, parent := range parents[hash] {
+			if _, ok := parents[parent]; !ok {
+				continue
+			}
+			children[parent]--
+			if children[parent] == 0 {
+				ready = append(ready, parent)
+			}
+		}
+	}
+
+	return order, nil
+}
+
+// hashCommitIter iterates over commits listed by hash, reading each one when it is reached
+type hashCommitIter struct {
+	repo   *git.Repository
+	hashes []plumbing.Hash
+	pos    int
+}
+
+func (it *hashCommitIter) Next() (*object.Commit, error) {
+	if it.pos >= len(it.hashes) {
+		return nil, io.EOF
+	}
+
+	commit, err := it.repo.CommitObject(it.hashes[it.pos])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit %s: %w", it.hashes[it.pos], err)
+	}
+	it.pos++
+
+	return commit, nil
+}
+
+func (it *hashCommitIter) ForEach(cb func(*object.Commit) error) error {
+	for {
+		commit, err := it.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := cb(commit); err != nil {
+			if err == storer.ErrStop {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (it *hashCommitIter) Close() {
+	it.pos = len(it.hashes)
+}
+
+// graphLayout assigns commits to lanes row by row
+type graphLayout struct {
+	lanes []GraphLane
+}
+
+// add lays out the next commit. Children come before their parents, a commit that
+// no lane waits for starts a new lane.
+func (l *graphLayout) add(hash string, parents []string) GraphRow {
+	column := l.find(hash)
+	started := column < 0
+	if started {
+		column = l.open(hash)
+	}
+
+	row := GraphRow{Hash: hash, Column: column, Color: l.lanes[column].Color}
+
+	// Lanes from the rows above: the commit's own lane and others waiting for it end at
+	// the dot, the rest pass
+	for i, lane := range l.lanes {
+		switch {
+		case lane.Hash == "", i == column && started:
+		case lane.Hash == hash:
+			row.Edges = append(row.Edges, GraphEdge{Kind: EdgeMergeIn, From: i, To: column, Color: lane.Color})
+			if i != column {
+				l.lanes[i].Hash = ""
+			}
+		default:
+			row.Edges = append(row.Edges, GraphEdge{Kind: EdgePass, From: i, To: i, Color: lane.Color})
+		}
+	}
+
+	// The first parent continues the commit's lane, unless another lane already leads to it
+	l.lanes[column].Hash = ""
+	for i, parent := range parents {
+		target := l.find(parent)
+		if target < 0 {
+			if i == 0 {
+				target = column
+				l.lanes[column].Hash = parent
+			} else {
+				target = l.open(parent)
+			}
+		}
+		row.Edges = append(row.Edges, GraphEdge{Kind: EdgeParent, From: column, To: target, Color: l.lanes[target].Color})
+	}
+
+	row.Width = len(l.lanes)
+	l.trim()
+	if len(l.lanes) > row.Width {
+		row.Width = len(l.lanes)
+	}
+
+	return row
+}
+
+// find returns the lane waiting for a commit, or -1
+func (l *graphLayout) find(hash string) int {
+	for i, lane := range l.lanes {
+		if lane.Hash == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// open puts a commit in the first free lane, or a new one, with a color no other open lane has
+func (l *graphLayout) open(hash string) int {
+	used := make(map[int]bool, len(l.lanes))
+	for _, lane := range l.lanes {
+		if lane.Hash != "" {
+			used[lane.Color] = true
+		}
+	}
+	color := 0
+	for used[color] {
+		color++
+	}
+
+	for i, lane := range l.lanes {
+		if lane.Hash == "" {
+			l.lanes[i] = GraphLane{Hash: hash, Color: color}
+			return i
+		}
+	}
+
+	l.lanes = append(l.lanes, GraphLane{Hash: hash, Color: color})
+	return len(l.lanes) - 1
+}
+
+// trim drops free lanes at the right edge
+func (l *graphLayout) trim() {
+	for len(l.lanes) > 0 && l.lanes[len(l.lanes)-1].Hash == "" {
+		l.lanes = l.lanes[:len(l.lanes)-1]
+	}
+}
+
+// decorations returns the branches, tags and HEAD pointing at each commit, by commit hash
+func (s *GitService) decorations(repo *git.Repository) (map[string][]GraphDecoration, error) {
+	decorations := make(map[string][]GraphDecoration)
+
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD reference: %w", err)
+	}
+	if head.Type() == plumbing.HashReference {
+		decorations[head.Hash().String()] = append(decorations[head.Hash().String()], GraphDecoration{Name: "HEAD", Kind: DecorationHead})
+	}
+
+	refs, err := repo.References()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references: %w", err)
+	}
+	defer refs.Close()
+
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		// Symbolic refs like origin/HEAD only repeat the branch they point at
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+
+		name := ref.Name()
+		hash := ref.Hash()
+		var decoration GraphDecoration
+		switch {
+		case name.IsBranch():
+			decoration = GraphDecoration{Name: name.Short(), Kind: DecorationBranch}
+			if head.Type() == plumbing.SymbolicReference && head.Target() == name {
+				decoration.Kind = DecorationHead
+			}
+		case name.IsRemote():
+			decoration = GraphDecoration{Name: name.Short(), Kind: DecorationRemote}
+		case name.IsTag():
+			decoration = GraphDecoration{Name: name.Short(), Kind: DecorationTag}
+			if tag, err := repo.TagObject(hash); err == nil {
+				if hash, err = s.peelTag(repo, tag); err != nil {
+					return err
+				}
+			}
+		default:
+			return nil
+		}
+
+		decorations[hash.String()] = append(decorations[hash.String()], decoration)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range decorations {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Kind != list[j].Kind {
+				return decorationOrder[list[i].Kind] < decorationOrder[list[j].Kind]
+			}
+			return list[i].Name < list[j].Name
+		})
+	}
+
+	return decorations, nil
+}
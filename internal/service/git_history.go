@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// FileHistory lists the commits that changed a file, newest first, following it
+// across renames like git log --follow. Each commit carries the path the file had
+// in it. The filter is applied as in ListCommits, including OffsetHash and Limit.
+func (s *GitService) FileHistory(projectPath string, file string, filter CommitFilter) ([]CommitInfo, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	file = strings.Trim(file, "/")
+	if file == "" {
+		return nil, errors.New("no file given")
+	}
+
+	startRef, err := s.logStart(repo, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	commitIter, err := repo.Log(&git.LogOptions{
+		From:  startRef,
+		Order: git.LogOrderCommitterTime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit iterator: %w", err)
+	}
+	defer commitIter.Close()
+
+	// The path the file has in the commits still to come, which are older
+	path := file
+	commits, err := s.collectCommits(commitIter, filter, func(c *object.Commit) (string, bool, error) {
+		current := path
+		touched, origin, err := s.fileChange(c, current)
+		if err != nil {
+			return "", false, err
+		}
+		if origin != "" {
+			path = origin
+		}
+		return current, touched, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+// fileChange reports whether a commit changed a file compared to its parents, and the
+// path the file came from when the commit renamed it. Like git, a merge only counts
+// when the file differs from every parent.
+func (s *GitService) fileChange(commit *object.Commit, path string) (bool, string, error) {
+	entry, err := s.commitFileEntry(commit, path)
+	if err != nil {
+		return false, "", err
+	}
+
+	if commit.NumParents() == 0 {
+		return entry != nil, "", nil
+	}
+
+	var first *object.Commit
+	missingInFirst := false
+	for i := 0; i < commit.NumParents(); i++ {
+		parent, err := commit.Parent(i)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to get parent commit: %w", err)
+		}
+
+		parentEntry, err := s.commitFileEntry(parent, path)
+		if err != nil {
+			return false, "", err
+		}
+		if i == 0 {
+			first = parent
+			missingInFirst = parentEntry == nil
+		}
+
+		if entry == nil && parentEntry == nil {
+			return false, "", nil
+		}
+		if entry != nil && parentEntry != nil && entry.Hash == parentEntry.Hash && entry.Mode == parentEntry.Mode {
+			return false, "", nil
+		}
+	}
+
+	// A file that appears may have been renamed from another path
+	if entry != nil && missingInFirst {
+		origin, err := s.renameSource(first, commit, path)
+		if err != nil {
+			return false, "", err
+		}
+		return true, origin, nil
+	}
+
+	return true, "", nil
+}
+
+// renameSource returns the path a file was renamed from between two commits, or "" if it was added
+func (s *GitService) renameSource(parent, commit *object.Commit, path string) (string, error) {
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get parent tree: %w", err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit tree: %w", err)
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.Background(), parentTree, tree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff trees: %w", err)
+	}
+
+	for _, change := range changes {
+		if change.To.Name == path && change.From.Name != "" && change.From.Name != path {
+			return change.From.Name, nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// Hooks run around a commit, in the order git runs them
+const (
+	hookPreCommit        = "pre-commit"
+	hookPreMergeCommit   = "pre-merge-commit" // Instead of pre-commit for the commit of a clean merge
+	hookPrepareCommitMsg = "prepare-commit-msg"
+	hookCommitMsg        = "commit-msg"
+	hookPostCommit       = "post-commit"
+	hookPostMerge        = "post-merge" // Instead of post-commit after a merge
+)
+
+// GitHookEvent is emitted as "git:hook-output" for each line a hook prints
+type GitHookEvent struct {
+	ProjectPath string `json:"projectPath"`
+	Hook        string `json:"hook"` // Name of the hook, like "pre-commit"
+	Line        string `json:"line"`
+}
+
+// HookError is returned when a hook rejects a commit by exiting with a non-zero status
+type HookError struct {
+	Hook     string `json:"hook"`
+	ExitCode int    `json:"exitCode"`
+	Output   string `json:"output"` // Everything the hook printed to stdout and stderr
+}
+
+func (e *HookError) Error() string {
+	message := fmt.Sprintf("%s hook failed with exit code %d", e.Hook, e.ExitCode)
+	if output := strings.TrimSpace(e.Output); output != "" {
+		message += ":\n" + output
+	}
+	return message
+}
+
+// hookWriter captures the output of a hook and emits it line by line as git:hook-output events
+type hookWriter struct {
+	service     *GitService
+	projectPath string
+	hook        string
+	output      bytes.Buffer
+	pending     []byte
+}
+
+func (w *hookWriter) Write(p []byte) (int, error) {
+	w.output.Write(p)
+	w.pending = append(w.pending, p...)
+	for {
+		end := bytes.IndexByte(w.pending, '\n')
+		if end < 0 {
+			break
+		}
+		w.emitLine(string(w.pending[:end]))
+		w.pending = w.pending[end+1:]
+	}
+	return len(p), nil
+}
+
+// flush emits the last line when the hook didn't end it with a newline
+func (w *hookWriter) flush() {
+	if len(w.pending) > 0 {
+		w.emitLine(string(w.pending))
+		w.pending = nil
+	}
+}
+
+func (w *hookWriter) emitLine(line string) {
+	w.service.emit("git:hook-output", GitHookEvent{
+		ProjectPath: w.projectPath,
+		Hook:        w.hook,
+		Line:        strings.TrimRight(line, "\r"),
+	})
+}
+
+// hooksDir returns the directory hooks are read from: core.hooksPath, relative to the
+// working tree, or the hooks directory of the repository
+func (s *GitService) hooksDir(repo *git.Repository, worktree *git.Worktree) (string, error) {
+	scopes, err := s.loadConfigScopes(repo)
+	if err != nil {
+		return "", err
+	}
+
+	if hooksPath, _ := scopes.get("core", "", "hookspath"); hooksPath != "" {
+		hooksPath = expandHome(hooksPath)
+		if !filepath.IsAbs(hooksPath) {
+			hooksPath = filepath.Join(worktree.Filesystem.Root(), hooksPath)
+		}
+		return hooksPath, nil
+	}
+
+	dir, err := s.commonDir(repo)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hooks"), nil
+}
+
+// runHook runs a hook from the working tree root if it exists and is executable, like git
+// does. Its output is streamed as events, and a non-zero exit status returns a *HookError.
+func (s *GitService) runHook(repo *git.Repository, worktree *git.Worktree, hook string, args ...string) error {
+	dir, err := s.hooksDir(repo, worktree)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, hook)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+		// Like git, a missing or non-executable hook is skipped
+		return nil
+	}
+
+	gitDir, err := s.gitDir(repo)
+	if err != nil {
+		return err
+	}
+
+	root := worktree.Filesystem.Root()
+	output := &hookWriter{service: s, projectPath: root, hook: hook}
+
+	cmd := exec.Command(path, args...)
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(),
+		"GIT_INDEX_FILE="+filepath.Join(gitDir, "index"),
+		// There is no editor to open, hooks that ask for one get a no-op
+		"GIT_EDITOR=:",
+	)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err = cmd.Run()
+	output.flush()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &HookError{Hook: hook, ExitCode: exitErr.ExitCode(), Output: output.output.String()}
+		}
+		return fmt.Errorf("failed to run %s hook: %w", hook, err)
+	}
+
+	return nil
+}
+
+// runCommitHooks runs the pre-commit, prepare-commit-msg and commit-msg hooks before a
+// commit and returns the message they leave. The source and sourceArgs are what git passes
+// to prepare-commit-msg: "message", "merge" or "commit" with the amended commit.
+func (s *GitService) runCommitHooks(repo *git.Repository, worktree *git.Worktree, message string, source string, sourceArgs ...string) (string, error) {
+	if err := s.runHook(repo, worktree, hookPreCommit); err != nil {
+		return "", err
+	}
+	return s.runMessageHooks(repo, worktree, message, source, sourceArgs...)
+}
+
+// runMessageHooks runs the prepare-commit-msg and commit-msg hooks and returns the message
+// they leave, cleaned up
+func (s *GitService) runMessageHooks(repo *git.Repository, worktree *git.Worktree, message string, source string, sourceArgs ...string) (string, error) {
+	dir, err := s.gitDir(repo)
+	if err != nil {
+		return "", err
+	}
+
+	// The message hooks edit the message in a file, as if it was being edited
+	messageFile := filepath.Join(dir, "COMMIT_EDITMSG")
+	if err := os.WriteFile(messageFile, []byte(commitMessage(message)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write commit message: %w", err)
+	}
+
+	if err := s.runHook(repo, worktree, hookPrepareCommitMsg, append([]string{messageFile, source}, sourceArgs...)...); err != nil {
+		return "", err
+	}
+	if err := s.runHook(repo, worktree, hookCommitMsg, messageFile); err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(messageFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit message: %w", err)
+	}
+
+	message = cleanupMessage(string(content))
+	if message == "" {
+		return "", errors.New("aborting commit due to empty commit message")
+	}
+
+	return message, nil
+}
+
+// runPostCommitHook runs the post-commit hook. The commit is made, so like git a failing
+// hook doesn't fail it, its output is still streamed.
+func (s *GitService) runPostCommitHook(repo *git.Repository, worktree *git.Worktree) {
+	_ = s.runHook(repo, worktree, hookPostCommit)
+}
+
+// cleanupMessage removes trailing whitespace from the lines of a message, repeated blank
+// lines and blank lines around it, like git's whitespace cleanup
+func cleanupMessage(message string) string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" && (len(lines) == 0 || lines[len(lines)-1] == "") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
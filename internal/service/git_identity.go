@@ -0,0 +1,195 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Config scopes, in order of precedence
+const (
+	ScopeLocal  = "local"  // The repository's .git/config, used for per-project overrides
+	ScopeGlobal = "global" // The user's ~/.gitconfig
+	ScopeSystem = "system" // The system-wide gitconfig
+)
+
+// GitIdentity is who commits are made as in a project and how they are signed
+type GitIdentity struct {
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	SigningKey    string `json:"signingKey"`    // user.signingkey: a GPG key id, or an SSH key file or "key::<public key>"
+	SigningFormat string `json:"signingFormat"` // gpg.format: "openpgp" (default), "ssh" or "x509"
+	SignCommits   bool   `json:"signCommits"`   // commit.gpgsign: whether commits are signed
+	Scope         string `json:"scope"`         // Scope the name comes from, empty when no identity is configured
+}
+
+// configScopes holds the config of each scope, in order of precedence.
+// go-git merges them into one struct, which loses options it doesn't know about.
+type configScopes struct {
+	names   []string
+	configs []*config.Config
+}
+
+// loadConfigScopes reads the repository, global and system config
+func (s *GitService) loadConfigScopes(repo *git.Repository) (*configScopes, error) {
+	local, err := repo.Config()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repository config: %w", err)
+	}
+
+	global, err := config.LoadConfig(config.GlobalScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read global config: %w", err)
+	}
+
+	system, err := config.LoadConfig(config.SystemScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system config: %w", err)
+	}
+
+	return &configScopes{
+		names:   []string{ScopeLocal, ScopeGlobal, ScopeSystem},
+		configs: []*config.Config{local, global, system},
+	}, nil
+}
+
+// get returns the value of an option from the first scope that sets it, and the name of that scope
+func (c *configScopes) get(section, subsection, key string) (string, string) {
+	for i, cfg := range c.configs {
+		if cfg.Raw == nil || !cfg.Raw.HasSection(section) {
+			continue
+		}
+		sec := cfg.Raw.Section(section)
+		options := sec.Options
+		if subsection != "" {
+			if !sec.HasSubsection(subsection) {
+				continue
+			}
+			options = sec.Subsection(subsection).Options
+		}
+		if options.Has(key) {
+			return options.Get(key), c.names[i]
+		}
+	}
+	return "", ""
+}
+
+// getBool returns a boolean option, false when it isn't set
+func (c *configScopes) getBool(section, subsection, key string) bool {
+	value, scope := c.get(section, subsection, key)
+	if scope == "" {
+		return false
+	}
+	switch strings.ToLower(value) {
+	case "", "true", "yes", "on", "1":
+		return true
+	}
+	return false
+}
+
+// identity returns the signature of the author or committer role, like git resolves it:
+// the GIT_<ROLE>_NAME and GIT_<ROLE>_EMAIL variables, then <role>.name and <role>.email,
+// then user.name and user.email
+func (s *GitService) identity(repo *git.Repository, role string) (*object.Signature, error) {
+	scopes, err := s.loadConfigScopes(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup := func(key string) string {
+		if value := os.Getenv("GIT_" + strings.ToUpper(role) + "_" + strings.ToUpper(key)); value != "" {
+			return value
+		}
+		if value, _ := scopes.get(role, "", key); value != "" {
+			return value
+		}
+		value, _ := scopes.get("user", "", key)
+		return value
+	}
+
+	name, email := lookup("name"), lookup("email")
+	if name == "" || email == "" {
+		return nil, ErrMissingIdentity
+	}
+
+	return &object.Signature{Name: name, Email: email, When: time.Now()}, nil
+}
+
+// authorSignature returns the identity to record as the author of new commits
+func (s *GitService) authorSignature(repo *git.Repository) (*object.Signature, error) {
+	return s.identity(repo, "author")
+}
+
+// GetIdentity returns the identity and signing settings commits are made with in a project
+func (s *GitService) GetIdentity(projectPath string) (*GitIdentity, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := s.loadConfigScopes(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &GitIdentity{SignCommits: scopes.getBool("commit", "", "gpgsign")}
+	identity.Name, identity.Scope = scopes.get("user", "", "name")
+	identity.Email, _ = scopes.get("user", "", "email")
+	identity.SigningKey, _ = scopes.get("user", "", "signingkey")
+	identity.SigningFormat, _ = scopes.get("gpg", "", "format")
+
+	return identity, nil
+}
+
+// SetIdentity stores an identity as the project's override in the repository config.
+// Empty fields remove the override, falling back to the global and system config.
+func (s *GitService) SetIdentity(projectPath string, identity GitIdentity) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := repo.Config()
+	if err != nil {
+		return fmt.Errorf("failed to read repository config: %w", err)
+	}
+
+	setOption := func(section, key, value string) {
+		if value == "" {
+			cfg.Raw.Section(section).RemoveOption(key)
+			return
+		}
+		cfg.Raw.Section(section).SetOption(key, value)
+	}
+
+	// go-git writes the user section from its struct fields, keep both in sync
+	cfg.User.Name = identity.Name
+	cfg.User.Email = identity.Email
+	setOption("user", "name", identity.Name)
+	setOption("user", "email", identity.Email)
+	setOption("user", "signingkey", identity.SigningKey)
+	setOption("gpg", "format", identity.SigningFormat)
+
+	// Only keep commit.gpgsign locally when it differs from what the project would inherit
+	cfg.Raw.Section("commit").RemoveOption("gpgsign")
+	inherited, err := s.loadConfigScopes(repo)
+	if err != nil {
+		return err
+	}
+	inherited.configs = inherited.configs[1:]
+	inherited.names = inherited.names[1:]
+	if inherited.getBool("commit", "", "gpgsign") != identity.SignCommits {
+		setOption("commit", "gpgsign", fmt.Sprintf("%t", identity.SignCommits))
+	}
+
+	if err := repo.SetConfig(cfg); err != nil {
+		return fmt.Errorf("failed to write repository config: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,786 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Fast-forward modes of a merge
+const (
+	MergeFastForward     = ""        // Fast-forward when possible, merge otherwise
+	MergeNoFastForward   = "no-ff"   // Always create a merge commit
+	MergeFastForwardOnly = "ff-only" // Fail unless the merge can fast-forward
+)
+
+// ErrMergeInProgress is returned when a merge, cherry-pick or revert is started before the previous one is finished
+var ErrMergeInProgress = errors.New("a merge is in progress, resolve the conflicts and commit or abort it first")
+
+// ErrStagedChanges is returned when a merge is started while changes are staged
+var ErrStagedChanges = errors.New("your index contains uncommitted changes, commit or unstage them first")
+
+// MergeConflictError is returned when both sides of a merge changed the same files
+type MergeConflictError struct {
+	Files []string `json:"files"` // Files changed differently on both sides
+}
+
+func (e *MergeConflictError) Error() string {
+	return fmt.Sprintf("merge conflict in: %s", strings.Join(e.Files, ", "))
+}
+
+// MergeOptions contains options for merging a branch
+type MergeOptions struct {
+	Message     string `json:"message"`     // Merge commit message, defaults to "Merge branch '<branch>'"
+	FastForward string `json:"fastForward"` // "" (fast-forward when possible), "no-ff" or "ff-only"
+}
+
+// MergeResult describes the outcome of a merge
+type MergeResult struct {
+	Status    string   `json:"status"`    // "up-to-date", "fast-forward", "merge" or "conflict"
+	Head      string   `json:"head"`      // Commit HEAD points at after the merge
+	Conflicts []string `json:"conflicts"` // Files left with conflicts when Status is "conflict"
+}
+
+// mergeBaseTree returns the tree of the best common ancestor of two commits,
+// or nil when the commits share no history
+func (s *GitService) mergeBaseTree(ours, theirs *object.Commit) (*object.Tree, error) {
+	bases, err := ours.MergeBase(theirs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find merge base: %w", err)
+	}
+	if len(bases) == 0 {
+		return nil, nil
+	}
+
+	tree, err := bases[0].Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merge base tree: %w", err)
+	}
+
+	return tree, nil
+}
+
+// changedEntries maps every path changed between two trees to its entry in the
+// second tree, or to nil when the path was deleted
+func changedEntries(from, to *object.Tree) (map[string]*object.TreeEntry, error) {
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare trees: %w", err)
+	}
+
+	entries := make(map[string]*object.TreeEntry, len(changes))
+	for _, change := range changes {
+		if change.From.Name != "" {
+			entries[change.From.Name] = nil
+		}
+		if change.To.Name != "" {
+			entry := change.To.TreeEntry
+			entries[change.To.Name] = &entry
+		}
+	}
+
+	return entries, nil
+}
+
+// sameEntry reports whether two tree entries have the same content and mode
+func sameEntry(a, b *object.TreeEntry) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Hash == b.Hash && a.Mode == b.Mode
+}
+
+// Merge merges a branch, tag or commit into the current branch.
+// It fast-forwards when possible, otherwise it performs a three-way merge. Files changed
+// on both sides are merged line by line; when that conflicts the files are left with
+// conflict markers and index stages, and the result lists them with Status "conflict"
+// until they are resolved with ResolveConflict and committed, or the merge is aborted.
+// A merge that needs a commit is refused while changes are staged. The commit of a clean
+// merge runs the pre-merge-commit, prepare-commit-msg and commit-msg hooks, and when one
+// fails the merge is left in progress with a *HookError.
+func (s *GitService) Merge(projectPath string, branch string, opts MergeOptions) (*MergeResult, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	theirs, err := repo.ResolveRevision(plumbing.Revision(branch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", branch, err)
+	}
+
+	message := opts.Message
+	if message == "" {
+		message = s.mergeMessage(repo, branch)
+	}
+
+	return s.merge(repo, worktree, *theirs, branch, message, opts.FastForward)
+}
+
+// mergeMessage returns the default merge commit message for merging a revision, like git names it
+func (s *GitService) mergeMessage(repo *git.Repository, revision string) string {
+	if _, err := repo.Reference(plumbing.NewBranchReferenceName(revision), false); err == nil {
+		return fmt.Sprintf("Merge branch '%s'", revision)
+	}
+	if remote, name, ok := strings.Cut(revision, "/"); ok {
+		if _, err := repo.Reference(plumbing.NewRemoteReferenceName(remote, name), false); err == nil {
+			return fmt.Sprintf("Merge remote-tracking branch '%s'", revision)
+		}
+	}
+	if _, err := repo.Reference(plumbing.NewTagReferenceName(revision), false); err == nil {
+		return fmt.Sprintf("Merge tag '%s'", revision)
+	}
+	return fmt.Sprintf("Merge commit '%s'", revision)
+}
+
+// merge merges the given commit into the current branch, see Merge
+func (s *GitService) merge(repo *git.Repository, worktree *git.Worktree, theirs plumbing.Hash, label string, message string, mode string) (*MergeResult, error) {
+	if err := s.checkNoMergeInProgress(repo); err != nil {
+		return nil, err
+	}
+
+	branch, err := s.currentBranch(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := repo.Head()
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	// An unborn branch simply starts at the merged commit
+	if head == nil {
+		return s.mergeFastForward(repo, worktree, branch, theirs)
+	}
+
+	if head.Hash() == theirs {
+		return &MergeResult{Status: "up-to-date", Head: theirs.String()}, nil
+	}
+
+	oursCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	theirsCommit, err := repo.CommitObject(theirs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit: %w", err)
+	}
+
+	if merged, err := theirsCommit.IsAncestor(oursCommit); err != nil {
+		return nil, fmt.Errorf("failed to compare commits: %w", err)
+	} else if merged {
+		return &MergeResult{Status: "up-to-date", Head: head.Hash().String()}, nil
+	}
+
+	if mode != MergeNoFastForward {
+		canFastForward, err := oursCommit.IsAncestor(theirsCommit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare commits: %w", err)
+		}
+		if canFastForward {
+			return s.mergeFastForward(repo, worktree, branch, theirs)
+		}
+	}
+
+	if mode == MergeFastForwardOnly {
+		return nil, fmt.Errorf("%w: %s and %s have diverged", ErrNonFastForward, branch.Short(), label)
+	}
+
+	if err := s.checkIndexMatchesHead(repo); err != nil {
+		return nil, err
+	}
+
+	baseTree, err := s.mergeBaseTree(oursCommit, theirsCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	oursTree, err := oursCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD tree: %w", err)
+	}
+
+	theirsTree, err := theirsCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+
+	result, err := s.mergeTrees(repo, worktree, baseTree, oursTree, theirsTree, mergeLabels{ours: "HEAD", theirs: label})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.writeMergeResult(repo, worktree, result); err != nil {
+		return nil, err
+	}
+
+	if len(result.conflicts) > 0 {
+		if err := s.writeMergeState(repo, theirs, message, result.conflictPaths(), mode); err != nil {
+			return nil, err
+		}
+		return &MergeResult{
+			Status:    "conflict",
+			Head:      head.Hash().String(),
+			Conflicts: result.conflictPaths(),
+		}, nil
+	}
+
+	// Like git, the hooks run with the merged files in place, and when one fails the merge
+	// is left in progress for Commit to conclude
+	if err := s.runHook(repo, worktree, hookPreMergeCommit); err != nil {
+		return nil, s.leaveMergeInProgress(repo, theirs, message, mode, err)
+	}
+	commitMsg, err := s.runMessageHooks(repo, worktree, message, "merge")
+	if err != nil {
+		return nil, s.leaveMergeInProgress(repo, theirs, message, mode, err)
+	}
+
+	// The merge commit only has the merged changes, which is all the index has since it
+	// matched HEAD
+	entries, err := s.headEntries(repo)
+	if err != nil {
+		return nil, err
+	}
+	for path, entry := range result.updates {
+		if entry == nil {
+			delete(entries, path)
+		} else {
+			entries[path] = *entry
+		}
+	}
+
+	tree, err := s.buildTree(repo, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := s.commitOptions(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := s.createCommit(repo, &object.Commit{
+		Author:       *opts.Author,
+		Committer:    *opts.Committer,
+		Message:      commitMessage(commitMsg),
+		TreeHash:     tree,
+		ParentHashes: []plumbing.Hash{head.Hash(), theirs},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create merge commit: %w", err)
+	}
+
+	if err := s.updateHead(repo, hash, fmt.Sprintf("merge %s: Merge made by the 'ort' strategy.", label)); err != nil {
+		return nil, err
+	}
+
+	// The squash flag git passes, merges here are never squashed
+	_ = s.runHook(repo, worktree, hookPostMerge, "0")
+
+	return &MergeResult{Status: "merge", Head: hash.String()}, nil
+}
+
+// leaveMergeInProgress records a clean merge whose commit a hook rejected, so it can be
+// committed once fixed, and returns the hook error
+func (s *GitService) leaveMergeInProgress(repo *git.Repository, theirs plumbing.Hash, message string, mode string, hookErr error) error {
+	if err := s.writeMergeState(repo, theirs, message, nil, mode); err != nil {
+		return err
+	}
+	return hookErr
+}
+
+// checkIndexMatchesHead refuses a merge while changes are staged, like git, since they would
+// end up in the merge commit or in the commit concluding a conflicted merge
+func (s *GitService) checkIndexMatchesHead(repo *git.Repository) error {
+	staged, err := s.indexEntries(repo)
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.headEntries(repo)
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	for path, entry := range staged {
+		if other, ok := entries[path]; !ok || !sameEntry(&entry, &other) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range entries {
+		if _, ok := staged[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+
+	if len(changed) > 0 {
+		sort.Strings(changed)
+		return fmt.Errorf("%w: %s", ErrStagedChanges, strings.Join(changed, ", "))
+	}
+
+	return nil
+}
+
+// mergeFastForward moves the current branch to the merged commit
+func (s *GitService) mergeFastForward(repo *git.Repository, worktree *git.Worktree, branch plumbing.ReferenceName, target plumbing.Hash) (*MergeResult, error) {
+	if _, err := s.fastForward(repo, worktree, branch, target); err != nil {
+		return nil, err
+	}
+	return &MergeResult{Status: "fast-forward", Head: target.String()}, nil
+}
+
+// mergeLabels names the sides of a merge in conflict markers
+type mergeLabels struct {
+	ours   string
+	theirs string
+}
+
+// mergeConflict is a file that could not be merged automatically
+type mergeConflict struct {
+	base, ours, theirs *object.TreeEntry // Versions of the file, nil where it doesn't exist
+	content            []byte            // Working tree content, with conflict markers when both sides are text
+	mode               filemode.FileMode
+}
+
+// treeMerge is the outcome of merging two trees
+type treeMerge struct {
+	updates   map[string]*object.TreeEntry // Merged entries to write, nil to delete
+	conflicts map[string]*mergeConflict
+}
+
+// conflictPaths returns the conflicted paths in order
+func (m *treeMerge) conflictPaths() []string {
+	paths := make([]string, 0, len(m.conflicts))
+	for path := range m.conflicts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// mergeTrees merges the changes between base and theirs into ours. Files changed on only
+// one side are taken from that side, files changed on both sides are merged line by line.
+// Files that can't be merged are returned as conflicts. It fails with a
+// *CheckoutConflictError when any of the files to write has local changes.
+func (s *GitService) mergeTrees(repo *git.Repository, worktree *git.Worktree, base, ours, theirs *object.Tree, labels mergeLabels) (*treeMerge, error) {
+	oursChanges, err := changedEntries(base, ours)
+	if err != nil {
+		return nil, err
+	}
+
+	theirsChanges, err := changedEntries(base, theirs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &treeMerge{
+		updates:   make(map[string]*object.TreeEntry),
+		conflicts: make(map[string]*mergeConflict),
+	}
+	for path, theirsEntry := range theirsChanges {
+		oursEntry, changed := oursChanges[path]
+		if !changed {
+			result.updates[path] = theirsEntry
+			continue
+		}
+		if sameEntry(oursEntry, theirsEntry) {
+			continue
+		}
+
+		var baseEntry *object.TreeEntry
+		if base != nil {
+			if entry, err := base.FindEntry(path); err == nil {
+				baseEntry = entry
+			}
+		}
+
+		merged, conflict, err := s.mergeFile(repo, baseEntry, oursEntry, theirsEntry, labels)
+		if err != nil {
+			return nil, err
+		}
+		if conflict != nil {
+			result.conflicts[path] = conflict
+		} else {
+			result.updates[path] = merged
+		}
+	}
+
+	// Both merged and conflicted files are written, none of them may have local changes
+	touched := make(map[string]*object.TreeEntry, len(result.updates)+len(result.conflicts))
+	for path, entry := range result.updates {
+		touched[path] = entry
+	}
+	for path := range result.conflicts {
+		touched[path] = nil
+	}
+	if err := s.checkDirtyFiles(repo, worktree, touched, labels.theirs); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// mergeFile merges a file changed on both sides. It returns the merged entry,
+// or a conflict when the changes overlap or can't be merged by content.
+func (s *GitService) mergeFile(repo *git.Repository, base, ours, theirs *object.TreeEntry, labels mergeLabels) (*object.TreeEntry, *mergeConflict, error) {
+	conflict := &mergeConflict{base: base, ours: ours, theirs: theirs}
+
+	// Modified on one side and deleted on the other: keep the surviving version
+	if ours == nil || theirs == nil {
+		survivor := ours
+		if survivor == nil {
+			survivor = theirs
+		}
+		content, err := s.readBlob(repo, survivor.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		conflict.content, conflict.mode = []byte(content), survivor.Mode
+		return nil, conflict, nil
+	}
+
+	// Mode changes merge like content: a side that kept the base mode takes the other one
+	mode := ours.Mode
+	if base != nil && ours.Mode == base.Mode {
+		mode = theirs.Mode
+	}
+	conflict.mode = mode
+
+	oursContent, err := s.readBlob(repo, ours.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	conflict.content = []byte(oursContent)
+
+	if !ours.Mode.IsFile() || !theirs.Mode.IsFile() || ours.Mode == filemode.Symlink || theirs.Mode == filemode.Symlink {
+		return nil, conflict, nil
+	}
+
+	theirsContent, err := s.readBlob(repo, theirs.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var baseContent string
+	if base != nil {
+		if baseContent, err = s.readBlob(repo, base.Hash); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if isContentBinary([]byte(baseContent)) || isContentBinary([]byte(oursContent)) || isContentBinary([]byte(theirsContent)) {
+		return nil, conflict, nil
+	}
+
+	merged, clean := merge3(baseContent, oursContent, theirsContent, labels)
+	if !clean {
+		conflict.content = []byte(merged)
+		return nil, conflict, nil
+	}
+
+	hash, err := s.storeBlob(repo, []byte(merged))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &object.TreeEntry{Hash: hash, Mode: mode}, nil, nil
+}
+
+// writeMergeResult writes the merged files to the working tree and index,
+// and the conflicted files with their markers and index stages
+func (s *GitService) writeMergeResult(repo *git.Repository, worktree *git.Worktree, result *treeMerge) error {
+	for path, entry := range result.updates {
+		if err := s.writeTreeEntry(repo, worktree, path, entry); err != nil {
+			return err
+		}
+	}
+
+	if len(result.conflicts) == 0 {
+		return nil
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	for path, conflict := range result.conflicts {
+		fullPath := filepath.Join(worktree.Filesystem.Root(), path)
+		if err := s.writeFileContent(fullPath, conflict.content, conflict.mode); err != nil {
+			return err
+		}
+
+		removeIndexEntries(idx, path)
+		for _, stage := range []struct {
+			stage index.Stage
+			entry *object.TreeEntry
+		}{
+			{index.AncestorMode, conflict.base},
+			{index.OurMode, conflict.ours},
+			{index.TheirMode, conflict.theirs},
+		} {
+			if stage.entry != nil {
+				idx.Entries = append(idx.Entries, &index.Entry{
+					Name:  path,
+					Hash:  stage.entry.Hash,
+					Mode:  stage.entry.Mode,
+					Stage: stage.stage,
+				})
+			}
+		}
+	}
+
+	return s.setIndex(repo, idx)
+}
+
+// mergeChunk replaces the base lines [start, end) with lines
+type mergeChunk struct {
+	start, end int
+	lines      []string
+}
+
+// mergeChunks returns the changes of an edit script as replaced ranges of its old side.
+// Lines keep their newline so a missing one at the end of the file survives the merge.
+func mergeChunks(ops []diffLineOp) []mergeChunk {
+	var chunks []mergeChunk
+	var current *mergeChunk
+	pos := 0
+	for _, op := range ops {
+		if op.kind == ' ' {
+			if current != nil {
+				chunks = append(chunks, *current)
+				current = nil
+			}
+			pos++
+			continue
+		}
+
+		if current == nil {
+			current = &mergeChunk{start: pos, end: pos}
+		}
+		if op.kind == '-' {
+			current.end++
+			pos++
+		} else {
+			line := op.text
+			if !op.noNewline {
+				line += "\n"
+			}
+			current.lines = append(current.lines, line)
+		}
+	}
+	if current != nil {
+		chunks = append(chunks, *current)
+	}
+	return chunks
+}
+
+// applyChunks returns the base lines [start, end) with the chunks applied
+func applyChunks(baseLines []string, start, end int, chunks []mergeChunk) []string {
+	var lines []string
+	pos := start
+	for _, chunk := range chunks {
+		lines = append(lines, baseLines[pos:chunk.start]...)
+		lines = append(lines, chunk.lines...)
+		pos = chunk.end
+	}
+	return append(lines, baseLines[pos:end]...)
+}
+
+// merge3 merges the changes from base to ours and from base to theirs line by line.
+// Overlapping or adjacent changes that differ are written between conflict markers,
+// in which case clean is false.
+func merge3(base, ours, theirs string, labels mergeLabels) (merged string, clean bool) {
+	baseLines := strings.SplitAfter(base, "\n")
+	if baseLines[len(baseLines)-1] == "" {
+		baseLines = baseLines[:len(baseLines)-1]
+	}
+
+	oursChunks := mergeChunks(diffLines(base, ours))
+	theirsChunks := mergeChunks(diffLines(base, theirs))
+
+	var out strings.Builder
+	writeLines := func(lines []string) {
+		for _, line := range lines {
+			out.WriteString(line)
+		}
+	}
+
+	clean = true
+	pos, i, j := 0, 0, 0
+	for i < len(oursChunks) || j < len(theirsChunks) {
+		// Start a group with the first chunk and pull in every chunk touching it
+		start := -1
+		if i < len(oursChunks) {
+			start = oursChunks[i].start
+		}
+		if j < len(theirsChunks) && (start < 0 || theirsChunks[j].start < start) {
+			start = theirsChunks[j].start
+		}
+
+		end := start
+		firstOurs, firstTheirs := i, j
+		for {
+			if i < len(oursChunks) && oursChunks[i].start <= end {
+				end = max(end, oursChunks[i].end)
+				i++
+				continue
+			}
+			if j < len(theirsChunks) && theirsChunks[j].start <= end {
+				end = max(end, theirsChunks[j].end)
+				j++
+				continue
+			}
+			break
+		}
+
+		writeLines(baseLines[pos:start])
+		pos = end
+
+		oursLines := applyChunks(baseLines, start, end, oursChunks[firstOurs:i])
+		theirsLines := applyChunks(baseLines, start, end, theirsChunks[firstTheirs:j])
+		switch {
+		case firstTheirs == j:
+			writeLines(oursLines)
+		case firstOurs == i:
+			writeLines(theirsLines)
+		case strings.Join(oursLines, "") == strings.Join(theirsLines, ""):
+			writeLines(oursLines)
+		default:
+			clean = false
+			fmt.Fprintf(&out, "<<<<<<< %s\n", labels.ours)
+			writeMarkedLines(&out, oursLines)
+			out.WriteString("=======\n")
+			writeMarkedLines(&out, theirsLines)
+			fmt.Fprintf(&out, ">>>>>>> %s\n", labels.theirs)
+		}
+	}
+	writeLines(baseLines[pos:])
+
+	return out.String(), clean
+}
+
+// writeMarkedLines writes one side of a conflict, making sure the marker after it starts a new line
+func writeMarkedLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(line)
+	}
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		out.WriteString("\n")
+	}
+}
+
+// checkDirtyFiles returns a *CheckoutConflictError if any of the given paths has local changes
+func (s *GitService) checkDirtyFiles(repo *git.Repository, worktree *git.Worktree, paths map[string]*object.TreeEntry, label string) error {
+	status, err := s.status(repo, worktree)
+	if err != nil {
+		return fmt.Errorf("failed to get status: %w", err)
+	}
+
+	var dirty []string
+	for path := range paths {
+		fileStatus, ok := status[path]
+		if !ok {
+			continue
+		}
+		if fileStatus.Staging != git.Unmodified || fileStatus.Worktree != git.Unmodified {
+			dirty = append(dirty, path)
+		}
+	}
+
+	if len(dirty) > 0 {
+		sort.Strings(dirty)
+		return &CheckoutConflictError{Branch: label, Files: dirty}
+	}
+
+	return nil
+}
+
+// writeTreeEntry writes a tree entry to the working tree and stages it.
+// A nil entry removes the file from both.
+func (s *GitService) writeTreeEntry(repo *git.Repository, worktree *git.Worktree, path string, entry *object.TreeEntry) error {
+	if entry == nil {
+		if _, err := worktree.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		return nil
+	}
+
+	fullPath := filepath.Join(worktree.Filesystem.Root(), path)
+	if err := s.writeEntryFile(repo, fullPath, entry); err != nil {
+		return err
+	}
+
+	if _, err := worktree.Add(path); err != nil {
+		return fmt.Errorf("failed to stage %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// writeEntryFile writes the content of a tree entry to a file, replacing what is there
+func (s *GitService) writeEntryFile(repo *git.Repository, fullPath string, entry *object.TreeEntry) error {
+	blob, err := repo.BlobObject(entry.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to get blob object: %w", err)
+	}
+
+	reader, err := blob.Reader()
+	if err != nil {
+		return fmt.Errorf("failed to get blob reader: %w", err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read blob content: %w", err)
+	}
+
+	return s.writeFileContent(fullPath, content, entry.Mode)
+}
+
+// writeFileContent writes a file with the given mode, replacing what is there
+func (s *GitService) writeFileContent(fullPath string, content []byte, mode filemode.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	// Replace whatever is there, a symlink can't be written through
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to replace %s: %w", fullPath, err)
+	}
+
+	if mode == filemode.Symlink {
+		if err := os.Symlink(string(content), fullPath); err != nil {
+			return fmt.Errorf("failed to create symlink: %w", err)
+		}
+	} else {
+		osMode, err := mode.ToOSFileMode()
+		if err != nil {
+			return fmt.Errorf("failed to convert file mode: %w", err)
+		}
+		if err := os.WriteFile(fullPath, content, osMode.Perm()); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+	}
+
+	return nil
+}
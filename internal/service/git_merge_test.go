@@ -0,0 +1,127 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// createMergeRepo creates a repository whose branches main and feat changed the same
+// files: a.txt on different lines, c.txt on the same line, and del.txt was deleted on
+// main but changed on feat
+func createMergeRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.txt", "1\n2\n3\n4\n5\n6\n7\n8\n9\n")
+	writeTestFile(t, dir, "c.txt", "c\n")
+	writeTestFile(t, dir, "del.txt", "d\n")
+	commitAll(t, dir, "Add files")
+
+	runGit(t, dir, "checkout", "-q", "-b", "feat")
+	writeTestFile(t, dir, "a.txt", "1 feat\n2\n3\n4\n5\n6\n7\n8\n9\n")
+	writeTestFile(t, dir, "c.txt", "c feat\n")
+	writeTestFile(t, dir, "del.txt", "d feat\n")
+	writeTestFile(t, dir, "f.txt", "only feat\n")
+	commitAll(t, dir, "Change files on feat")
+
+	runGit(t, dir, "checkout", "-q", "main")
+	writeTestFile(t, dir, "a.txt", "1\n2\n3\n4\n5\n6\n7\n8\n9 main\n")
+	writeTestFile(t, dir, "c.txt", "c main\n")
+	runGit(t, dir, "rm", "-q", "del.txt")
+	commitAll(t, dir, "Change files on main")
+	return dir
+}
+
+// TestMergeConflicts merges conflicting branches and checks the index stages, status and
+// merged files against git merge, then resolves the conflicts and concludes the merge
+func TestMergeConflicts(t *testing.T) {
+	dir := createMergeRepo(t)
+	expected := createMergeRepo(t)
+
+	s := NewGitService(nil)
+	result, err := s.Merge(dir, "feat", MergeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != "conflict" || strings.Join(result.Conflicts, ",") != "c.txt,del.txt" {
+		t.Fatalf("unexpected merge result: %+v", result)
+	}
+	runGitFails(t, expected, "merge", "-q", "feat")
+
+	for _, args := range [][]string{
+		{"ls-files", "--stage"},
+		{"status", "--porcelain"},
+		{"rev-parse", "MERGE_HEAD"},
+	} {
+		if got, want := runGit(t, dir, args...), runGit(t, expected, args...); got != want {
+			t.Errorf("git %s differs from git merge:\n%s\nexpected:\n%s", strings.Join(args, " "), got, want)
+		}
+	}
+	for _, file := range []string{"a.txt", "c.txt", "del.txt", "f.txt"} {
+		if got, want := readTestFile(t, dir, file), readTestFile(t, expected, file); got != want {
+			t.Errorf("%s differs from git merge:\n%s\nexpected:\n%s", file, got, want)
+		}
+	}
+
+	conflicts, err := s.ListConflicts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 2 || conflicts[0].Ours != "c main\n" || conflicts[0].Theirs != "c feat\n" || conflicts[0].Base != "c\n" ||
+		conflicts[1].HasOurs || !conflicts[1].HasTheirs {
+		t.Errorf("unexpected conflicts: %+v", conflicts)
+	}
+
+	if err := s.Commit(dir, "Merge feat"); err == nil {
+		t.Error("expected committing with unresolved conflicts to fail")
+	}
+	if err := s.ResolveConflict(dir, "c.txt", ConflictResolution{Choice: ResolveContent, Content: "c both\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ResolveConflict(dir, "del.txt", ConflictResolution{Choice: ResolveOurs}); err != nil {
+		t.Fatal(err)
+	}
+	if unmerged := runGit(t, dir, "ls-files", "--unmerged"); unmerged != "" {
+		t.Errorf("expected no unmerged entries, got:\n%s", unmerged)
+	}
+	if status := runGit(t, dir, "status", "--porcelain"); status != "M  a.txt\nM  c.txt\nA  f.txt\n" {
+		t.Errorf("unexpected status after resolving:\n%s", status)
+	}
+
+	if err := s.Commit(dir, ""); err != nil {
+		t.Fatal(err)
+	}
+	if parents := runGit(t, dir, "log", "-1", "--format=%P"); parents != runGit(t, dir, "rev-parse", "main~1")[:40]+" "+runGit(t, dir, "rev-parse", "feat") {
+		t.Errorf("unexpected merge commit parents: %s", parents)
+	}
+	if subject := runGit(t, dir, "log", "-1", "--format=%s"); subject != "Merge branch 'feat'\n" {
+		t.Errorf("unexpected merge commit subject: %q", subject)
+	}
+	runGit(t, dir, "fsck", "--no-progress")
+}
+
+// TestMergeAbort aborts a conflicted merge and checks HEAD, the index and the working
+// tree are back to where they were, untracked files included
+func TestMergeAbort(t *testing.T) {
+	dir := createMergeRepo(t)
+	head := runGit(t, dir, "rev-parse", "HEAD")
+	writeTestFile(t, dir, "untracked.txt", "untracked\n")
+
+	s := NewGitService(nil)
+	if _, err := s.Merge(dir, "feat", MergeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MergeAbort(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := runGit(t, dir, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD moved to %s", got)
+	}
+	if status := runGit(t, dir, "status", "--porcelain"); status != "?? untracked.txt\n" {
+		t.Errorf("unexpected status after aborting:\n%s", status)
+	}
+	if content := readTestFile(t, dir, "c.txt"); content != "c main\n" {
+		t.Errorf("c.txt was not restored: %q", content)
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// ErrMissingIdentity is returned when no user name and email are configured
+var ErrMissingIdentity = errors.New("author identity unknown, set user.name and user.email in the git config")
+
+// gitDir returns the path of the .git directory of a repository
+func (s *GitService) gitDir(repo *git.Repository) (string, error) {
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return "", errors.New("repository is not stored on disk")
+	}
+	return storage.Filesystem().Root(), nil
+}
+
+// commonDir returns the directory with the objects, refs, config and hooks that all worktrees
+// of a repository share. For a linked worktree it is the main .git directory, while gitDir
+// holds the worktree's own HEAD, index and merge state.
+func (s *GitService) commonDir(repo *git.Repository) (string, error) {
+	dir, err := s.gitDir(repo)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "commondir"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return dir, nil
+		}
+		return "", fmt.Errorf("failed to read commondir: %w", err)
+	}
+
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(dir, common)
+	}
+	return filepath.Clean(common), nil
+}
+
+// signature returns the identity to record as the committer of new commits and in reflog
+// entries, see identity
+func (s *GitService) signature(repo *git.Repository) (*object.Signature, error) {
+	return s.identity(repo, "committer")
+}
+
+// storeBlob writes content to the object database as a blob
+func (s *GitService) storeBlob(repo *git.Repository, content []byte) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	writer, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to create blob: %w", err)
+	}
+	if _, err := writer.Write(content); err != nil {
+		writer.Close()
+		return plumbing.ZeroHash, fmt.Errorf("failed to write blob: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to write blob: %w", err)
+	}
+
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store blob: %w", err)
+	}
+
+	return hash, nil
+}
+
+// storeWorktreeFile writes a working tree file to the object database and returns
+// its tree entry. Symlinks are stored as their target, like git does.
+func (s *GitService) storeWorktreeFile(repo *git.Repository, worktree *git.Worktree, path string) (object.TreeEntry, error) {
+	fullPath := filepath.Join(worktree.Filesystem.Root(), path)
+
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return object.TreeEntry{}, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	var content []byte
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(fullPath)
+		if err != nil {
+			return object.TreeEntry{}, fmt.Errorf("failed to read symlink %s: %w", path, err)
+		}
+		content = []byte(target)
+	} else {
+		content, err = os.ReadFile(fullPath)
+		if err != nil {
+			return object.TreeEntry{}, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	mode, err := filemode.NewFromOSFileMode(info.Mode())
+	if err != nil {
+		return object.TreeEntry{}, fmt.Errorf("failed to convert file mode: %w", err)
+	}
+
+	hash, err := s.storeBlob(repo, content)
+	if err != nil {
+		return object.TreeEntry{}, err
+	}
+
+	return object.TreeEntry{Name: path, Mode: mode, Hash: hash}, nil
+}
+
+// indexEntries returns the index as a map of paths to tree entries.
+// It fails when the index has unresolved conflicts.
+func (s *GitService) indexEntries(repo *git.Repository) (map[string]object.TreeEntry, error) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index: %w", err)
+	}
+
+	entries := make(map[string]object.TreeEntry, len(idx.Entries))
+	for _, entry := range idx.Entries {
+		if entry.Stage != 0 {
+			return nil, fmt.Errorf("%s has unresolved conflicts", entry.Name)
+		}
+		entries[entry.Name] = object.TreeEntry{Name: entry.Name, Mode: entry.Mode, Hash: entry.Hash}
+	}
+
+	return entries, nil
+}
+
+// buildTree writes the tree objects for a flat map of file paths to entries
+// and returns the hash of the root tree
+func (s *GitService) buildTree(repo *git.Repository, entries map[string]object.TreeEntry) (plumbing.Hash, error) {
+	tree := &object.Tree{}
+	dirs := make(map[string]map[string]object.TreeEntry)
+	for path, entry := range entries {
+		if dir, rest, nested := strings.Cut(path, "/"); nested {
+			if dirs[dir] == nil {
+				dirs[dir] = make(map[string]object.TreeEntry)
+			}
+			dirs[dir][rest] = entry
+			continue
+		}
+		entry.Name = path
+		tree.Entries = append(tree.Entries, entry)
+	}
+
+	for dir, children := range dirs {
+		hash, err := s.buildTree(repo, children)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tree.Entries = append(tree.Entries, object.TreeEntry{Name: dir, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// Git orders tree entries as if directory names ended with a slash
+	sortKey := func(entry object.TreeEntry) string {
+		if entry.Mode == filemode.Dir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return sortKey(tree.Entries[i]) < sortKey(tree.Entries[j])
+	})
+
+	obj := repo.Storer.NewEncodedObject()
+	if err := tree.Encode(obj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to encode tree: %w", err)
+	}
+
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store tree: %w", err)
+	}
+
+	return hash, nil
+}
+
+// storeCommit writes a commit object to the object database
+func (s *GitService) storeCommit(repo *git.Repository, commit *object.Commit) (plumbing.Hash, error) {
+	obj := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to encode commit: %w", err)
+	}
+
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store commit: %w", err)
+	}
+
+	return hash, nil
+}
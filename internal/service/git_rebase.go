@@ -0,0 +1,989 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Actions of a rebase step, named like the commands of git rebase -i
+const (
+	RebasePick   = "pick"   // Replay the commit
+	RebaseReword = "reword" // Replay the commit with the message of the step
+	RebaseSquash = "squash" // Meld the commit into the previous one, joining their messages
+	RebaseFixup  = "fixup"  // Meld the commit into the previous one, keeping its message
+	RebaseDrop   = "drop"   // Leave the commit out
+)
+
+// rebaseActions maps the commands git accepts in a todo list, abbreviated or not, to actions
+var rebaseActions = map[string]string{
+	"p": RebasePick, RebasePick: RebasePick,
+	"r": RebaseReword, RebaseReword: RebaseReword,
+	"s": RebaseSquash, RebaseSquash: RebaseSquash,
+	"f": RebaseFixup, RebaseFixup: RebaseFixup,
+	"d": RebaseDrop, RebaseDrop: RebaseDrop,
+}
+
+// rebaseMergeDir is the directory in .git where git keeps the state of an interactive rebase.
+// The state is written the way git writes it, so git can continue or abort a rebase started
+// here. A rebase started by git is continued here only when it has no other commands than
+// the actions above; otherwise it is reported as Foreign and left to git.
+const rebaseMergeDir = "rebase-merge"
+
+// Files of a rebase stopped at a conflict, besides MERGE_MSG
+const (
+	rebaseHead        = "REBASE_HEAD"   // In .git, the commit being replayed
+	rebaseStoppedFile = "stopped-sha"   // In rebase-merge, the same commit
+	rebaseMessageFile = "message"       // In rebase-merge, the message the commit will get
+	rebaseAuthorFile  = "author-script" // In rebase-merge, the author it will get
+	rebaseMessagesDir = "messages"      // In rebase-merge, messages given to reword and squash steps, by commit
+)
+
+// ErrRebaseInProgress is returned when a rebase is started before the previous one is finished
+var ErrRebaseInProgress = errors.New("a rebase is in progress, continue or abort it first")
+
+// ErrForeignRebase is returned when the rebase in progress was started by git with commands
+// that aren't replayed here, like exec, edit or label
+var ErrForeignRebase = errors.New("the rebase in progress uses commands only git can run, continue or abort it with git")
+
+// RebaseStep is one commit of a rebase and what to do with it
+type RebaseStep struct {
+	Action  string    `json:"action"`  // RebasePick, RebaseReword, RebaseSquash, RebaseFixup or RebaseDrop
+	Hash    string    `json:"hash"`    // Commit to replay
+	Subject string    `json:"subject"` // First line of the commit message
+	Message string    `json:"message"` // For reword and squash, the message to use instead of the commit's
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"` // Author date
+}
+
+// RebasePlan lists the commits a rebase replays, oldest first. Reorder, edit or drop the
+// steps and pass the plan to RebaseExecute.
+type RebasePlan struct {
+	Onto   string       `json:"onto"`   // Commit the steps are replayed on
+	Branch string       `json:"branch"` // Branch being rebased, empty when HEAD is detached
+	Head   string       `json:"head"`   // Commit HEAD was at when the plan was made
+	Steps  []RebaseStep `json:"steps"`
+}
+
+// RebaseResult describes where a rebase got to
+type RebaseResult struct {
+	Status    string      `json:"status"`    // "done" or "conflict"
+	Head      string      `json:"head"`      // Commit HEAD points at
+	Conflicts []string    `json:"conflicts"` // Files left with conflicts when Status is "conflict"
+	Stopped   *RebaseStep `json:"stopped"`   // Step that conflicted, finish it with RebaseContinue
+}
+
+// RebaseState is a rebase in progress, as kept on disk across restarts
+type RebaseState struct {
+	Onto      string       `json:"onto"`
+	Branch    string       `json:"branch"`    // Branch being rebased, empty when HEAD was detached
+	OrigHead  string       `json:"origHead"`  // Commit the branch was at, which RebaseAbort returns to
+	Done      []RebaseStep `json:"done"`      // Steps replayed, including the stopped one
+	Todo      []RebaseStep `json:"todo"`      // Steps still to replay
+	Stopped   *RebaseStep  `json:"stopped"`   // Step waiting for its conflicts to be resolved
+	Conflicts []string     `json:"conflicts"` // Files that still have conflicts
+	Foreign   bool         `json:"foreign"`   // Started by git with commands only git can run, the steps are left empty
+}
+
+// rebaseState is the on-disk state of a rebase
+type rebaseState struct {
+	dir      string
+	headName string // Branch being rebased, or "detached HEAD"
+	onto     plumbing.Hash
+	origHead plumbing.Hash
+	done     []RebaseStep
+	todo     []RebaseStep
+}
+
+// RebasePlan lists the commits of the current branch that a rebase onto a branch, tag or
+// commit would replay: those not reachable from onto, oldest first, all picked. Merge
+// commits are left out, like git rebase does.
+func (s *GitService) RebasePlan(projectPath string, onto string) (*RebasePlan, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	ontoCommit, err := s.resolveCommit(repo, onto)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &RebasePlan{Onto: ontoCommit.Hash.String(), Head: head.Hash().String(), Steps: []RebaseStep{}}
+	if head.Name().IsBranch() {
+		plan.Branch = head.Name().Short()
+	}
+
+	flags, err := divergentCommits(repo, head.Hash(), ontoCommit.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s with HEAD: %w", onto, err)
+	}
+
+	// Parents come before their children, visited depth first from HEAD
+	visited := make(map[plumbing.Hash]bool)
+	var visit func(hash plumbing.Hash) error
+	visit = func(hash plumbing.Hash) error {
+		if visited[hash] || flags[hash] != reachLocal {
+			return nil
+		}
+		visited[hash] = true
+
+		commit, err := repo.CommitObject(hash)
+		if err != nil {
+			return fmt.Errorf("failed to get commit: %w", err)
+		}
+		for _, parent := range commit.ParentHashes {
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+
+		if commit.NumParents() <= 1 {
+			plan.Steps = append(plan.Steps, rebaseStep(RebasePick, commit))
+		}
+		return nil
+	}
+	if err := visit(head.Hash()); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// rebaseStep returns a step for a commit
+func rebaseStep(action string, commit *object.Commit) RebaseStep {
+	return RebaseStep{
+		Action:  action,
+		Hash:    commit.Hash.String(),
+		Subject: firstLine(commit.Message),
+		Message: commit.Message,
+		Author:  commit.Author.Name,
+		Date:    commit.Author.When,
+	}
+}
+
+// RebaseExecute replays the steps of a plan onto its Onto commit and moves the branch to
+// the result. HEAD must still be where it was when the plan was made, with no local changes
+// to tracked files. When a step conflicts the rebase stops with Status "conflict": resolve
+// the files, then call RebaseContinue, or RebaseAbort to return to where it started.
+// The state is kept in .git, so a stopped rebase survives a restart.
+func (s *GitService) RebaseExecute(projectPath string, plan RebasePlan) (*RebaseResult, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	if err := s.checkNoMergeInProgress(repo); err != nil {
+		return nil, err
+	}
+
+	if err := checkRebaseSteps(plan.Steps); err != nil {
+		return nil, err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	if plan.Head != "" && plan.Head != head.Hash().String() {
+		return nil, errors.New("HEAD has moved since the rebase was planned")
+	}
+
+	onto, err := s.resolveCommit(repo, plan.Onto)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, step := range plan.Steps {
+		if _, err := s.resolveCommit(repo, step.Hash); err != nil {
+			return nil, err
+		}
+	}
+
+	status, err := s.status(repo, worktree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+	for _, fileStatus := range status {
+		if fileStatus.Worktree != git.Untracked && (fileStatus.Staging != git.Unmodified || fileStatus.Worktree != git.Unmodified) {
+			return nil, errors.New("cannot rebase with local changes, commit or stash them first")
+		}
+	}
+
+	if err := s.runHook(repo, worktree, "pre-rebase", plan.Onto); err != nil {
+		return nil, err
+	}
+
+	gitDir, err := s.gitDir(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &rebaseState{
+		dir:      filepath.Join(gitDir, rebaseMergeDir),
+		headName: "detached HEAD",
+		onto:     onto.Hash,
+		origHead: head.Hash(),
+		todo:     plan.Steps,
+	}
+	if head.Name().IsBranch() {
+		state.headName = head.Name().String()
+	}
+
+	if err := os.Mkdir(state.dir, 0755); err != nil {
+		if os.IsExist(err) {
+			return nil, ErrRebaseInProgress
+		}
+		return nil, fmt.Errorf("failed to start rebase: %w", err)
+	}
+	if err := s.writeRebaseState(repo, state); err != nil {
+		os.RemoveAll(state.dir)
+		return nil, err
+	}
+	if err := s.writeStateFiles(repo, map[string]string{"ORIG_HEAD": head.Hash().String() + "\n"}); err != nil {
+		os.RemoveAll(state.dir)
+		return nil, err
+	}
+
+	// The steps are replayed on a detached HEAD, the branch only moves once all are done
+	files, err := s.prepareCheckout(repo, worktree, onto.Hash, plan.Onto)
+	if err != nil {
+		os.RemoveAll(state.dir)
+		return nil, err
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, onto.Hash)); err != nil {
+		os.RemoveAll(state.dir)
+		return nil, fmt.Errorf("failed to detach HEAD: %w", err)
+	}
+	if err := s.checkoutFiles(worktree, onto.Hash, files); err != nil {
+		return nil, s.undoRebaseStart(repo, worktree, head, files, state, err)
+	}
+	if err := s.logHeadUpdate(repo, head.Hash(), onto.Hash, "rebase (start): checkout "+plan.Onto); err != nil {
+		return nil, s.undoRebaseStart(repo, worktree, head, files, state, err)
+	}
+
+	return s.runRebase(repo, worktree, state)
+}
+
+// undoRebaseStart puts HEAD and the files back where they were when a rebase fails to start
+// after detaching HEAD, removes its state and returns the error it failed with
+func (s *GitService) undoRebaseStart(repo *git.Repository, worktree *git.Worktree, head *plumbing.Reference, files []string, state *rebaseState, cause error) error {
+	ref := plumbing.NewHashReference(plumbing.HEAD, state.origHead)
+	if head.Name().IsBranch() {
+		ref = plumbing.NewSymbolicReference(plumbing.HEAD, head.Name())
+	}
+	if err := repo.Storer.SetReference(ref); err != nil {
+		log.Printf("[GitService] Failed to restore HEAD after a failed rebase: %v", err)
+		return cause
+	}
+
+	if err := s.checkoutFiles(worktree, state.origHead, files); err != nil {
+		log.Printf("[GitService] Failed to restore files after a failed rebase: %v", err)
+	}
+	os.RemoveAll(state.dir)
+
+	return cause
+}
+
+// checkRebaseSteps checks that every step has a known action and that squash and fixup
+// steps have a commit before them to meld into
+func checkRebaseSteps(steps []RebaseStep) error {
+	kept := false
+	for _, step := range steps {
+		action, ok := rebaseActions[step.Action]
+		if !ok {
+			return fmt.Errorf("unknown rebase action %q", step.Action)
+		}
+		if (action == RebaseSquash || action == RebaseFixup) && !kept {
+			return fmt.Errorf("cannot %s %s without a previous commit", action, step.Hash)
+		}
+		if action != RebaseDrop {
+			kept = true
+		}
+	}
+	return nil
+}
+
+// RebaseContinue commits the resolution of the step a rebase stopped at, from the staged
+// files, and replays the remaining steps. A step whose resolution changes nothing is left out.
+func (s *GitService) RebaseContinue(projectPath string) (*RebaseResult, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	state, err := s.readRebaseState(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	unmerged, err := s.unmergedPaths(repo)
+	if err != nil {
+		return nil, err
+	}
+	if len(unmerged) > 0 {
+		return nil, errors.New("cannot continue the rebase with unresolved conflicts")
+	}
+
+	stopped, err := s.rebaseStopped(state)
+	if err != nil {
+		return nil, err
+	}
+
+	if stopped != nil {
+		commit, err := s.resolveCommit(repo, stopped.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		entries, err := s.indexEntries(repo)
+		if err != nil {
+			return nil, err
+		}
+		tree, err := s.buildTree(repo, entries)
+		if err != nil {
+			return nil, err
+		}
+
+		message, err := os.ReadFile(filepath.Join(state.dir, rebaseMessageFile))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read rebase message: %w", err)
+		}
+
+		if err := s.commitRebaseStep(repo, *stopped, commit, tree, string(message)); err != nil {
+			return nil, err
+		}
+
+		if err := s.clearRebaseStop(repo, state); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.runRebase(repo, worktree, state)
+}
+
+// RebaseAbort abandons a rebase and returns the branch, index and working tree to
+// where they were before it started
+func (s *GitService) RebaseAbort(projectPath string) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	state, err := s.readRebaseState(repo)
+	if err != nil {
+		return err
+	}
+
+	// Undo the step that stopped, then go back from the replayed commits to the original ones
+	if err := s.restoreHead(repo, worktree); err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	headTree, err := s.headTree(repo)
+	if err != nil {
+		return err
+	}
+	origCommit, err := repo.CommitObject(state.origHead)
+	if err != nil {
+		return fmt.Errorf("failed to get commit: %w", err)
+	}
+	origTree, err := origCommit.Tree()
+	if err != nil {
+		return fmt.Errorf("failed to get tree: %w", err)
+	}
+	changed, err := changedEntries(headTree, origTree)
+	if err != nil {
+		return err
+	}
+	files := make([]string, 0, len(changed))
+	for path := range changed {
+		files = append(files, path)
+	}
+
+	// Logged while HEAD is still detached, the branch itself never moved
+	if err := s.logHeadUpdate(repo, head.Hash(), state.origHead, "rebase (abort): returning to "+state.headName); err != nil {
+		return err
+	}
+
+	headRef := plumbing.NewHashReference(plumbing.HEAD, state.origHead)
+	if branch := plumbing.ReferenceName(state.headName); branch.IsBranch() {
+		headRef = plumbing.NewSymbolicReference(plumbing.HEAD, branch)
+	}
+	if err := repo.Storer.SetReference(headRef); err != nil {
+		return fmt.Errorf("failed to update HEAD: %w", err)
+	}
+
+	if err := s.checkoutFiles(worktree, state.origHead, files); err != nil {
+		return err
+	}
+
+	if err := s.clearRebaseStop(repo, state); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(state.dir); err != nil {
+		return fmt.Errorf("failed to remove rebase state: %w", err)
+	}
+
+	return nil
+}
+
+// GetRebaseState returns the rebase in progress, or nil when there is none
+func (s *GitService) GetRebaseState(projectPath string) (*RebaseState, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.readRebaseState(repo)
+	if errors.Is(err, errNoRebase) {
+		return nil, nil
+	}
+	foreign := errors.Is(err, ErrForeignRebase)
+	if err != nil && !foreign {
+		return nil, err
+	}
+
+	info := &RebaseState{
+		Onto:     state.onto.String(),
+		OrigHead: state.origHead.String(),
+		Done:     state.done,
+		Todo:     state.todo,
+		Foreign:  foreign,
+	}
+	if branch := plumbing.ReferenceName(state.headName); branch.IsBranch() {
+		info.Branch = branch.Short()
+	}
+
+	if !foreign {
+		if info.Stopped, err = s.rebaseStopped(state); err != nil {
+			return nil, err
+		}
+	}
+
+	unmerged, err := s.unmergedPaths(repo)
+	if err != nil {
+		return nil, err
+	}
+	for path := range unmerged {
+		info.Conflicts = append(info.Conflicts, path)
+	}
+	sort.Strings(info.Conflicts)
+
+	return info, nil
+}
+
+// runRebase replays the steps left to do. It stops at the first step that conflicts and
+// finishes the rebase once all are replayed.
+func (s *GitService) runRebase(repo *git.Repository, worktree *git.Worktree, state *rebaseState) (*RebaseResult, error) {
+	for len(state.todo) > 0 {
+		step := state.todo[0]
+		step.Action = rebaseActions[step.Action]
+
+		conflicts, err := s.replayRebaseStep(repo, worktree, state, step)
+		if err != nil {
+			return nil, err
+		}
+
+		// Moved to done once replayed, so an interrupted step is replayed again
+		state.todo = state.todo[1:]
+		state.done = append(state.done, step)
+		if err := s.writeRebaseState(repo, state); err != nil {
+			return nil, err
+		}
+
+		if len(conflicts) > 0 {
+			head, err := repo.Head()
+			if err != nil {
+				return nil, fmt.Errorf("failed to get HEAD: %w", err)
+			}
+			return &RebaseResult{Status: "conflict", Head: head.Hash().String(), Conflicts: conflicts, Stopped: &step}, nil
+		}
+	}
+
+	return s.finishRebase(repo, state)
+}
+
+// replayRebaseStep applies a step on top of HEAD. When its changes conflict the conflicts
+// are left in the index and working tree, the rebase is marked as stopped at the step
+// and the conflicted files are returned.
+func (s *GitService) replayRebaseStep(repo *git.Repository, worktree *git.Worktree, state *rebaseState, step RebaseStep) ([]string, error) {
+	if step.Action == RebaseDrop {
+		return nil, nil
+	}
+
+	commit, err := s.resolveCommit(repo, step.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	short := commit.Hash.String()[:7]
+	if commit.NumParents() > 1 {
+		return nil, fmt.Errorf("%s is a merge commit, which can't be rebased", short)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	// A commit already on top of HEAD is kept as it is, like git does
+	if step.Action == RebasePick && commit.NumParents() == 1 && commit.ParentHashes[0] == head.Hash() {
+		files, err := s.prepareCheckout(repo, worktree, commit.Hash, short)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.updateHead(repo, commit.Hash, "rebase (pick): "+firstLine(commit.Message)); err != nil {
+			return nil, err
+		}
+		return nil, s.checkoutFiles(worktree, commit.Hash, files)
+	}
+
+	headTree, err := s.headTree(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit tree: %w", err)
+	}
+
+	parentTree, err := s.parentTree(commit)
+	if err != nil {
+		return nil, err
+	}
+
+	label := fmt.Sprintf("%s (%s)", short, firstLine(commit.Message))
+	result, err := s.mergeTrees(repo, worktree, parentTree, headTree, tree, mergeLabels{ours: "HEAD", theirs: label})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.writeMergeResult(repo, worktree, result); err != nil {
+		return nil, err
+	}
+
+	if len(result.conflicts) > 0 {
+		if err := s.stopRebase(repo, state, step, commit, result.conflictPaths()); err != nil {
+			return nil, err
+		}
+		return result.conflictPaths(), nil
+	}
+
+	entries, err := s.headEntries(repo)
+	if err != nil {
+		return nil, err
+	}
+	for path, entry := range result.updates {
+		if entry == nil {
+			delete(entries, path)
+		} else {
+			entries[path] = *entry
+		}
+	}
+
+	newTree, err := s.buildTree(repo, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, s.commitRebaseStep(repo, step, commit, newTree, "")
+}
+
+// parentTree returns the tree of the parent of a commit, nil for a root commit
+func (s *GitService) parentTree(commit *object.Commit) (*object.Tree, error) {
+	if commit.NumParents() == 0 {
+		return nil, nil
+	}
+
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent commit: %w", err)
+	}
+
+	tree, err := parent.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent tree: %w", err)
+	}
+
+	return tree, nil
+}
+
+// commitRebaseStep commits the replayed changes of a step with the given tree. Picks and
+// rewords become a new commit on HEAD, squashes and fixups replace HEAD. A pick or reword
+// whose changes are already in HEAD is left out, unless the original commit was empty too.
+// An empty message means the one rebaseMessage gives.
+func (s *GitService) commitRebaseStep(repo *git.Repository, step RebaseStep, commit *object.Commit, tree plumbing.Hash, message string) error {
+	head, err := repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	if message == "" {
+		if message, err = s.rebaseMessage(repo, step, commit); err != nil {
+			return err
+		}
+	}
+
+	author := commit.Author
+	parents := []plumbing.Hash{headCommit.Hash}
+	if step.Action == RebaseSquash || step.Action == RebaseFixup {
+		author = headCommit.Author
+		parents = headCommit.ParentHashes
+	} else if tree == headCommit.TreeHash {
+		parentTree, err := s.parentTree(commit)
+		if err != nil {
+			return err
+		}
+		if parentTree == nil || parentTree.Hash != commit.TreeHash {
+			return nil
+		}
+	}
+
+	committer, err := s.signature(repo)
+	if err != nil {
+		return err
+	}
+
+	hash, err := s.createCommit(repo, &object.Commit{
+		Author:       author,
+		Committer:    *committer,
+		Message:      commitMessage(message),
+		TreeHash:     tree,
+		ParentHashes: parents,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.updateHead(repo, hash, fmt.Sprintf("rebase (%s): %s", step.Action, firstLine(message)))
+}
+
+// rebaseMessage returns the message a step gives its commit: its own message for reword,
+// the message of HEAD for fixup, the messages of HEAD and the step joined for squash, and
+// the commit's message otherwise
+func (s *GitService) rebaseMessage(repo *git.Repository, step RebaseStep, commit *object.Commit) (string, error) {
+	message := commit.Message
+	if (step.Action == RebaseReword || step.Action == RebaseSquash) && strings.TrimSpace(step.Message) != "" {
+		message = step.Message
+	}
+
+	if step.Action != RebaseSquash && step.Action != RebaseFixup {
+		return message, nil
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	if step.Action == RebaseFixup {
+		return headCommit.Message, nil
+	}
+	return cleanupMessage(headCommit.Message) + "\n\n" + cleanupMessage(message), nil
+}
+
+// stopRebase records that the rebase stopped at a step with conflicts, in the files git
+// uses for it
+func (s *GitService) stopRebase(repo *git.Repository, state *rebaseState, step RebaseStep, commit *object.Commit, conflicts []string) error {
+	message, err := s.rebaseMessage(repo, step, commit)
+	if err != nil {
+		return err
+	}
+
+	author := commit.Author
+	if step.Action == RebaseSquash || step.Action == RebaseFixup {
+		head, err := repo.Head()
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		headCommit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD commit: %w", err)
+		}
+		author = headCommit.Author
+	}
+
+	files := map[string]string{
+		rebaseStoppedFile: commit.Hash.String() + "\n",
+		rebaseMessageFile: commitMessage(message),
+		rebaseAuthorFile:  authorScript(author),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(state.dir, name), []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	return s.writeStateFiles(repo, map[string]string{
+		rebaseHead:  commit.Hash.String() + "\n",
+		"MERGE_MSG": conflictMessage(message, conflicts),
+	})
+}
+
+// rebaseStopped returns the step a rebase stopped at, or nil when it isn't stopped
+func (s *GitService) rebaseStopped(state *rebaseState) (*RebaseStep, error) {
+	if _, err := os.Stat(filepath.Join(state.dir, rebaseStoppedFile)); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read rebase state: %w", err)
+	}
+
+	if len(state.done) == 0 {
+		return nil, errors.New("the rebase stopped before any step")
+	}
+
+	step := state.done[len(state.done)-1]
+	return &step, nil
+}
+
+// clearRebaseStop removes the files of a stop once its step is committed
+func (s *GitService) clearRebaseStop(repo *git.Repository, state *rebaseState) error {
+	gitDir, err := s.gitDir(repo)
+	if err != nil {
+		return err
+	}
+
+	paths := []string{
+		filepath.Join(gitDir, rebaseHead),
+		filepath.Join(gitDir, "MERGE_MSG"),
+		filepath.Join(state.dir, rebaseStoppedFile),
+		filepath.Join(state.dir, rebaseMessageFile),
+		filepath.Join(state.dir, rebaseAuthorFile),
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	return nil
+}
+
+// finishRebase moves the rebased branch to HEAD and checks it out again
+func (s *GitService) finishRebase(repo *git.Repository, state *rebaseState) (*RebaseResult, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	if branch := plumbing.ReferenceName(state.headName); branch.IsBranch() {
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, head.Hash())); err != nil {
+			return nil, fmt.Errorf("failed to update %s: %w", branch.Short(), err)
+		}
+		message := fmt.Sprintf("rebase (finish): %s onto %s", branch, state.onto)
+		if err := s.logRefUpdate(repo, []plumbing.ReferenceName{branch}, state.origHead, head.Hash(), message); err != nil {
+			return nil, err
+		}
+
+		if err := s.logHeadUpdate(repo, head.Hash(), head.Hash(), "rebase (finish): returning to "+branch.String()); err != nil {
+			return nil, err
+		}
+		if err := repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branch)); err != nil {
+			return nil, fmt.Errorf("failed to update HEAD: %w", err)
+		}
+	}
+
+	if err := os.RemoveAll(state.dir); err != nil {
+		return nil, fmt.Errorf("failed to remove rebase state: %w", err)
+	}
+
+	return &RebaseResult{Status: "done", Head: head.Hash().String()}, nil
+}
+
+// errNoRebase is returned by readRebaseState when no rebase is in progress
+var errNoRebase = errors.New("there is no rebase in progress")
+
+// readRebaseState reads the state of the rebase in progress. For a rebase with commands
+// only git can run, it returns ErrForeignRebase along with the state without its steps.
+func (s *GitService) readRebaseState(repo *git.Repository) (*rebaseState, error) {
+	gitDir, err := s.gitDir(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &rebaseState{dir: filepath.Join(gitDir, rebaseMergeDir)}
+	read := func(name string) (string, error) {
+		data, err := os.ReadFile(filepath.Join(state.dir, name))
+		if err != nil {
+			return "", fmt.Errorf("failed to read rebase state: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	if _, err := os.Stat(state.dir); err != nil {
+		if os.IsNotExist(err) {
+			return nil, errNoRebase
+		}
+		return nil, fmt.Errorf("failed to read rebase state: %w", err)
+	}
+
+	if state.headName, err = read("head-name"); err != nil {
+		return nil, err
+	}
+	for name, hash := range map[string]*plumbing.Hash{"onto": &state.onto, "orig-head": &state.origHead} {
+		value, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		*hash = plumbing.NewHash(value)
+	}
+
+	for name, steps := range map[string]*[]RebaseStep{"done": &state.done, "git-rebase-todo": &state.todo} {
+		if *steps, err = s.readRebaseSteps(repo, state, name); err != nil {
+			if errors.Is(err, ErrForeignRebase) {
+				state.done, state.todo = []RebaseStep{}, []RebaseStep{}
+				return state, err
+			}
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
+
+// readRebaseSteps parses a todo list. Like git, commands may be abbreviated and blank
+// lines and comments are skipped. Only the commands RebaseExecute knows are supported.
+func (s *GitService) readRebaseSteps(repo *git.Repository, state *rebaseState, name string) ([]RebaseStep, error) {
+	data, err := os.ReadFile(filepath.Join(state.dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []RebaseStep{}, nil
+		}
+		return nil, fmt.Errorf("failed to read rebase state: %w", err)
+	}
+
+	steps := []RebaseStep{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		action, ok := rebaseActions[fields[0]]
+		if !ok || len(fields) < 2 {
+			return nil, fmt.Errorf("%w: %q", ErrForeignRebase, line)
+		}
+
+		commit, err := s.resolveCommit(repo, fields[1])
+		if err != nil {
+			return nil, err
+		}
+
+		step := rebaseStep(action, commit)
+		if message, err := os.ReadFile(filepath.Join(state.dir, rebaseMessagesDir, step.Hash)); err == nil {
+			step.Message = string(message)
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// writeRebaseState writes the state of a rebase the way git does
+func (s *GitService) writeRebaseState(repo *git.Repository, state *rebaseState) error {
+	files := map[string]string{
+		"head-name":       state.headName + "\n",
+		"onto":            state.onto.String() + "\n",
+		"orig-head":       state.origHead.String() + "\n",
+		"interactive":     "",
+		"done":            rebaseTodo(state.done),
+		"git-rebase-todo": rebaseTodo(state.todo),
+		"msgnum":          strconv.Itoa(len(state.done)) + "\n",
+		"end":             strconv.Itoa(len(state.done)+len(state.todo)) + "\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(state.dir, name), []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write rebase state: %w", err)
+		}
+	}
+
+	// Messages given to steps are kept apart, git's todo list has no room for them
+	for _, step := range append(append([]RebaseStep(nil), state.done...), state.todo...) {
+		if step.Action != RebaseReword && step.Action != RebaseSquash {
+			continue
+		}
+		commit, err := repo.CommitObject(plumbing.NewHash(step.Hash))
+		if err != nil || strings.TrimSpace(step.Message) == "" || step.Message == commit.Message {
+			continue
+		}
+
+		dir := filepath.Join(state.dir, rebaseMessagesDir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to write rebase state: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, step.Hash), []byte(step.Message), 0644); err != nil {
+			return fmt.Errorf("failed to write rebase state: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rebaseTodo formats steps as a git todo list
+func rebaseTodo(steps []RebaseStep) string {
+	var todo strings.Builder
+	for _, step := range steps {
+		fmt.Fprintf(&todo, "%s %s %s\n", rebaseActions[step.Action], step.Hash, step.Subject)
+	}
+	return todo.String()
+}
+
+// authorScript formats an author the way git records it for a stopped rebase
+func authorScript(author object.Signature) string {
+	quote := func(value string) string {
+		return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+	}
+	return fmt.Sprintf("GIT_AUTHOR_NAME=%s\nGIT_AUTHOR_EMAIL=%s\nGIT_AUTHOR_DATE=%s\n",
+		quote(author.Name),
+		quote(author.Email),
+		quote(fmt.Sprintf("@%d %s", author.When.Unix(), author.When.Format("-0700"))),
+	)
+}
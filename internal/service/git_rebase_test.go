@@ -0,0 +1,143 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createRebaseRepo creates a repository whose branch feat, checked out, has three commits
+// on top of the first commit of main, the last of them conflicting with main
+func createRebaseRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.txt", "1\n2\n3\n4\n5\n")
+	commitAll(t, dir, "Add a")
+
+	runGit(t, dir, "checkout", "-q", "-b", "feat")
+	writeTestFile(t, dir, "x.txt", "x\n")
+	commitAll(t, dir, "Add x")
+	writeTestFile(t, dir, "y.txt", "y\n")
+	commitAll(t, dir, "Add y")
+	writeTestFile(t, dir, "a.txt", "1\nfeat\n3\n4\n5\n")
+	commitAll(t, dir, "Change a on feat")
+
+	runGit(t, dir, "checkout", "-q", "main")
+	writeTestFile(t, dir, "a.txt", "1\nmain\n3\n4\n5\n")
+	writeTestFile(t, dir, "m.txt", "m\n")
+	commitAll(t, dir, "Change a on main")
+	runGit(t, dir, "checkout", "-q", "feat")
+	return dir
+}
+
+// TestRebaseContinue rebases a branch onto main, resolves the conflict it stops at and
+// continues, and checks the result against the same rebase made with git
+func TestRebaseContinue(t *testing.T) {
+	dir := createRebaseRepo(t)
+	expected := createRebaseRepo(t)
+
+	s := NewGitService(nil)
+	plan, err := s.RebasePlan(dir, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 3 || plan.Branch != "feat" {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+	result, err := s.RebaseExecute(dir, *plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != "conflict" || strings.Join(result.Conflicts, ",") != "a.txt" || result.Stopped.Subject != "Change a on feat" {
+		t.Fatalf("unexpected rebase result: %+v", result)
+	}
+	runGitFails(t, expected, "rebase", "main")
+
+	for _, args := range [][]string{
+		{"ls-files", "--stage"},
+		{"status", "--porcelain"},
+		{"log", "--format=%s", "HEAD"},
+	} {
+		if got, want := runGit(t, dir, args...), runGit(t, expected, args...); got != want {
+			t.Errorf("git %s differs from git rebase:\n%s\nexpected:\n%s", strings.Join(args, " "), got, want)
+		}
+	}
+
+	// git reads the state of the stopped rebase too
+	if status := runGit(t, dir, "status"); !strings.Contains(status, "rebase in progress") {
+		t.Errorf("git doesn't see the rebase:\n%s", status)
+	}
+
+	if _, err := s.RebaseContinue(dir); err == nil {
+		t.Error("expected continuing with unresolved conflicts to fail")
+	}
+	for _, repo := range []string{dir, expected} {
+		writeTestFile(t, repo, "a.txt", "1\nresolved\n3\n4\n5\n")
+	}
+	if err := s.StageFile(dir, "a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, expected, "add", "a.txt")
+
+	result, err = s.RebaseContinue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != "done" {
+		t.Fatalf("unexpected rebase result: %+v", result)
+	}
+	runGit(t, expected, "-c", "core.editor=true", "rebase", "--continue")
+
+	for _, args := range [][]string{
+		{"log", "--format=%s%n%an <%ae>%n%T", "HEAD"},
+		{"status", "--porcelain"},
+		{"symbolic-ref", "HEAD"},
+		{"rev-parse", "HEAD~3"},
+	} {
+		if got, want := runGit(t, dir, args...), runGit(t, expected, args...); got != want {
+			t.Errorf("git %s differs from git rebase:\n%s\nexpected:\n%s", strings.Join(args, " "), got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git", "rebase-merge")); !os.IsNotExist(err) {
+		t.Errorf("the rebase state was left behind: %v", err)
+	}
+	runGit(t, dir, "fsck", "--no-progress")
+}
+
+// TestRebaseAbort stops a rebase on a conflict and aborts it, and checks the branch,
+// index and working tree are back to where they were
+func TestRebaseAbort(t *testing.T) {
+	dir := createRebaseRepo(t)
+	head := runGit(t, dir, "rev-parse", "HEAD")
+	writeTestFile(t, dir, "untracked.txt", "untracked\n")
+
+	s := NewGitService(nil)
+	plan, err := s.RebasePlan(dir, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RebaseExecute(dir, *plan); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RebaseAbort(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := runGit(t, dir, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD moved to %s", got)
+	}
+	if ref := runGit(t, dir, "symbolic-ref", "HEAD"); ref != "refs/heads/feat\n" {
+		t.Errorf("HEAD points at %s", ref)
+	}
+	if status := runGit(t, dir, "status", "--porcelain"); status != "?? untracked.txt\n" {
+		t.Errorf("unexpected status after aborting:\n%s", status)
+	}
+	if status := runGit(t, dir, "status"); strings.Contains(status, "rebase in progress") {
+		t.Errorf("git still sees a rebase:\n%s", status)
+	}
+	if content := readTestFile(t, dir, "a.txt"); content != "1\nfeat\n3\n4\n5\n" {
+		t.Errorf("a.txt was not restored: %q", content)
+	}
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// reflogEntry is one line of a reference log, recording a single update of a reference
+type reflogEntry struct {
+	Old       plumbing.Hash
+	New       plumbing.Hash
+	Committer object.Signature
+	Message   string
+}
+
+// reflogPath returns the path of the log file of a reference. Each worktree has its
+// own HEAD log, the logs of branches are shared.
+func (s *GitService) reflogPath(repo *git.Repository, name plumbing.ReferenceName) (string, error) {
+	logDir := s.commonDir
+	if name == plumbing.HEAD {
+		logDir = s.gitDir
+	}
+	dir, err := logDir(repo)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "logs", filepath.FromSlash(name.String())), nil
+}
+
+// readReflog returns the log of a reference, oldest entry first.
+// A reference without a log has no entries.
+func (s *GitService) readReflog(repo *git.Repository, name plumbing.ReferenceName) ([]reflogEntry, error) {
+	path, err := s.reflogPath(repo, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read reflog: %w", err)
+	}
+
+	var entries []reflogEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// <old> <new> <name> <<email>> <time> <tz>\t<message>
+		line, message, _ := strings.Cut(scanner.Text(), "\t")
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) < 3 {
+			continue
+		}
+
+		entry := reflogEntry{
+			Old:     plumbing.NewHash(fields[0]),
+			New:     plumbing.NewHash(fields[1]),
+			Message: message,
+		}
+		entry.Committer.Decode([]byte(fields[2]))
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reflog: %w", err)
+	}
+
+	return entries, nil
+}
+
+// writeReflog replaces the log of a reference. Writing no entries deletes the log.
+func (s *GitService) writeReflog(repo *git.Repository, name plumbing.ReferenceName, entries []reflogEntry) error {
+	path, err := s.reflogPath(repo, name)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete reflog: %w", err)
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		fmt.Fprintf(&buf, "%s %s ", entry.Old, entry.New)
+		if err := entry.Committer.Encode(&buf); err != nil {
+			return fmt.Errorf("failed to encode reflog entry: %w", err)
+		}
+		// Messages are kept on a single line
+		fmt.Fprintf(&buf, "\t%s\n", strings.Join(strings.Fields(entry.Message), " "))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create reflog directory: %w", err)
+	}
+
+	// Write to a temporary file first so a failed write can't truncate the log
+	tmp := path + ".lock"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write reflog: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write reflog: %w", err)
+	}
+
+	return nil
+}
+
+// appendReflog records an update of a reference in its log
+func (s *GitService) appendReflog(repo *git.Repository, name plumbing.ReferenceName, entry reflogEntry) error {
+	entries, err := s.readReflog(repo, name)
+	if err != nil {
+		return err
+	}
+	return s.writeReflog(repo, name, append(entries, entry))
+}
+
+// updateHead points HEAD, or the branch it is on, at a commit and records the move in the reflogs
+func (s *GitService) updateHead(repo *git.Repository, target plumbing.Hash, message string) error {
+	old := plumbing.ZeroHash
+	if head, err := repo.Head(); err == nil {
+		old = head.Hash()
+	}
+
+	name := plumbing.HEAD
+	if ref, err := repo.Reference(plumbing.HEAD, false); err == nil && ref.Type() == plumbing.SymbolicReference {
+		name = ref.Target()
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(name, target)); err != nil {
+		return fmt.Errorf("failed to update %s: %w", name.Short(), err)
+	}
+
+	return s.logHeadUpdate(repo, old, target, message)
+}
+
+// logHeadUpdate records a move of HEAD in its reflog and in the one of the branch it is on
+func (s *GitService) logHeadUpdate(repo *git.Repository, old, new plumbing.Hash, message string) error {
+	names := []plumbing.ReferenceName{plumbing.HEAD}
+	if ref, err := repo.Reference(plumbing.HEAD, false); err == nil && ref.Type() == plumbing.SymbolicReference {
+		names = append(names, ref.Target())
+	}
+
+	return s.logRefUpdate(repo, names, old, new, message)
+}
+
+// logRefUpdate records the same move of each reference in its reflog
+func (s *GitService) logRefUpdate(repo *git.Repository, names []plumbing.ReferenceName, old, new plumbing.Hash, message string) error {
+	sig, err := s.signature(repo)
+	if errors.Is(err, ErrMissingIdentity) {
+		// The move must still be recoverable, git falls back to a placeholder identity too
+		sig = &object.Signature{Name: "unknown", When: time.Now()}
+	} else if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		err := s.appendReflog(repo, name, reflogEntry{
+			Old:       old,
+			New:       new,
+			Committer: *sig,
+			Message:   message,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reflogRevision resolves revisions of the form <ref>@{<n>}, the value the reference had
+// n updates ago, which go-git doesn't support. ok is false for other revisions.
+func (s *GitService) reflogRevision(repo *git.Repository, rev string) (plumbing.Hash, bool, error) {
+	ref, rest, found := strings.Cut(rev, "@{")
+	if !found || !strings.HasSuffix(rest, "}") {
+		return plumbing.ZeroHash, false, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(rest, "}"))
+	if err != nil || n < 0 {
+		return plumbing.ZeroHash, false, nil
+	}
+
+	name := plumbing.HEAD
+	switch {
+	case ref == "" || ref == "HEAD":
+	case strings.HasPrefix(ref, "refs/"):
+		name = plumbing.ReferenceName(ref)
+	default:
+		name = plumbing.NewBranchReferenceName(ref)
+	}
+
+	entries, err := s.readReflog(repo, name)
+	if err != nil {
+		return plumbing.ZeroHash, true, err
+	}
+	if n >= len(entries) {
+		return plumbing.ZeroHash, true, fmt.Errorf("log for %s only has %d entries", name.Short(), len(entries))
+	}
+
+	return entries[len(entries)-1-n].New, true, nil
+}
@@ -0,0 +1,534 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+var (
+	// ErrNonFastForward is returned when a pull or push can't be done as a fast-forward
+	ErrNonFastForward = errors.New("non-fast-forward update")
+	// ErrAuthFailed is returned when the remote rejects the provided credentials
+	ErrAuthFailed = errors.New("authentication failed")
+)
+
+// Authentication methods for RemoteAuth.Method
+const (
+	AuthSSHAgent = "ssh-agent"
+	AuthSSHKey   = "ssh-key"
+	AuthHTTPS    = "https"
+)
+
+// Pull modes for RemoteOptions.PullMode
+const (
+	PullFastForwardOnly = "ff-only"
+	PullMerge           = "merge"
+)
+
+// localRemote is the remote of a branch whose upstream is another local branch
+const localRemote = "."
+
+// GitProgressEvent is emitted as "git:progress" while a remote operation runs
+type GitProgressEvent struct {
+	ProjectPath string `json:"projectPath"`
+	Operation   string `json:"operation"` // "fetch", "pull" or "push"
+	Message     string `json:"message"`   // Progress line as reported by the remote
+}
+
+// RemoteAuth holds the credentials used to talk to a remote
+type RemoteAuth struct {
+	Method        string `json:"method"`        // "ssh-agent", "ssh-key", "https" or empty to pick one from the remote URL
+	Username      string `json:"username"`      // HTTPS user name (for SSH the user comes from the URL)
+	Password      string `json:"password"`      // HTTPS password or access token
+	KeyPath       string `json:"keyPath"`       // Private key file for "ssh-key"
+	KeyPassphrase string `json:"keyPassphrase"` // Passphrase of the private key, if any
+}
+
+// RemoteOptions contains options for fetch, pull and push
+type RemoteOptions struct {
+	Remote      string     `json:"remote"`      // Remote name, defaults to the branch upstream or "origin"
+	Branch      string     `json:"branch"`      // Remote branch to pull or push, defaults to the upstream or current branch
+	Auth        RemoteAuth `json:"auth"`        // Credentials for the remote
+	Prune       bool       `json:"prune"`       // Fetch: remove remote-tracking refs that no longer exist on the remote
+	PullMode    string     `json:"pullMode"`    // Pull: "ff-only" (default) or "merge"
+	Force       bool       `json:"force"`       // Push: allow non-fast-forward updates
+	SetUpstream bool       `json:"setUpstream"` // Push: make the pushed branch the upstream of the current branch
+}
+
+// PullResult describes what a pull did to the current branch
+type PullResult struct {
+	Status string `json:"status"` // "up-to-date", "fast-forward" or "merge"
+	Head   string `json:"head"`   // Hash of HEAD after the pull
+}
+
+// progressWriter turns sideband progress output into git:progress events
+type progressWriter struct {
+	service     *GitService
+	projectPath string
+	operation   string
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	// Progress counters are redrawn with carriage returns, report each update
+	for _, line := range strings.FieldsFunc(string(p), func(r rune) bool { return r == '\r' || r == '\n' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			w.service.emit("git:progress", GitProgressEvent{
+				ProjectPath: w.projectPath,
+				Operation:   w.operation,
+				Message:     line,
+			})
+		}
+	}
+	return len(p), nil
+}
+
+// remoteAuth builds the transport auth method for a remote
+func (s *GitService) remoteAuth(repo *git.Repository, remoteName string, auth RemoteAuth) (transport.AuthMethod, error) {
+	// Local branches are reached without a transport
+	if remoteName == localRemote {
+		return nil, nil
+	}
+
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote %q: %w", remoteName, err)
+	}
+
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("remote %q has no URL", remoteName)
+	}
+
+	endpoint, err := transport.NewEndpoint(urls[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote URL: %w", err)
+	}
+
+	sshUser := endpoint.User
+	if sshUser == "" {
+		sshUser = "git"
+	}
+
+	method := auth.Method
+	if method == "" {
+		switch endpoint.Protocol {
+		case "ssh":
+			method = AuthSSHAgent
+			if auth.KeyPath != "" {
+				method = AuthSSHKey
+			} else if os.Getenv("SSH_AUTH_SOCK") == "" {
+				// Without an agent fall back to the default key files
+				auth.KeyPath = defaultSSHKey()
+				if auth.KeyPath != "" {
+					method = AuthSSHKey
+				}
+			}
+		case "http", "https":
+			if auth.Username == "" && auth.Password == "" {
+				return nil, nil
+			}
+			method = AuthHTTPS
+		default:
+			// Local and git:// remotes don't authenticate
+			return nil, nil
+		}
+	}
+
+	switch method {
+	case AuthSSHAgent:
+		agentAuth, err := gitssh.NewSSHAgentAuth(sshUser)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrAuthFailed, err)
+		}
+		return agentAuth, nil
+	case AuthSSHKey:
+		if auth.KeyPath == "" {
+			return nil, errors.New("no SSH key file given")
+		}
+		keyAuth, err := gitssh.NewPublicKeysFromFile(sshUser, auth.KeyPath, auth.KeyPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to load SSH key: %v", ErrAuthFailed, err)
+		}
+		return keyAuth, nil
+	case AuthHTTPS:
+		username := auth.Username
+		if username == "" {
+			// Token based auth accepts any non-empty user name
+			username = "git"
+		}
+		return &githttp.BasicAuth{Username: username, Password: auth.Password}, nil
+	default:
+		return nil, fmt.Errorf("unknown authentication method %q", method)
+	}
+}
+
+// defaultSSHKey returns the first of the usual private key files that exists
+func defaultSSHKey() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		path := filepath.Join(homeDir, ".ssh", name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// RemoteError is returned when a fetch, pull or push fails.
+// Kind is ErrAuthFailed or ErrNonFastForward when the failure was recognized,
+// so callers can check for them with errors.Is.
+type RemoteError struct {
+	Operation string
+	Kind      error
+	Err       error
+}
+
+func (e *RemoteError) Error() string {
+	if e.Kind != nil && !strings.Contains(e.Err.Error(), e.Kind.Error()) {
+		return fmt.Sprintf("%s failed: %v: %v", e.Operation, e.Kind, e.Err)
+	}
+	return fmt.Sprintf("%s failed: %v", e.Operation, e.Err)
+}
+
+func (e *RemoteError) Unwrap() []error {
+	if e.Kind == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// remoteError classifies transport errors into a *RemoteError
+func remoteError(operation string, err error) error {
+	msg := err.Error()
+	remoteErr := &RemoteError{Operation: operation, Err: err}
+	switch {
+	case errors.Is(err, transport.ErrAuthenticationRequired),
+		errors.Is(err, transport.ErrAuthorizationFailed),
+		strings.Contains(msg, "unable to authenticate"):
+		remoteErr.Kind = ErrAuthFailed
+	case errors.Is(err, git.ErrNonFastForwardUpdate),
+		errors.Is(err, git.ErrForceNeeded),
+		strings.Contains(msg, "non-fast-forward"),
+		strings.Contains(msg, "fetch first"):
+		remoteErr.Kind = ErrNonFastForward
+	}
+	return remoteErr
+}
+
+// upstream returns the remote and remote branch a local branch pulls from,
+// falling back to the options and then to "origin" and the same branch name
+func (s *GitService) upstream(repo *git.Repository, branch string, opts RemoteOptions) (string, plumbing.ReferenceName, error) {
+	remoteName := opts.Remote
+	merge := plumbing.ReferenceName("")
+	if opts.Branch != "" {
+		merge = plumbing.NewBranchReferenceName(opts.Branch)
+	}
+
+	if branchCfg, err := repo.Branch(branch); err == nil {
+		if remoteName == "" {
+			remoteName = branchCfg.Remote
+		}
+		if merge == "" && remoteName == branchCfg.Remote {
+			merge = branchCfg.Merge
+		}
+	} else if !errors.Is(err, git.ErrBranchNotFound) {
+		return "", "", fmt.Errorf("failed to read branch config: %w", err)
+	}
+
+	if remoteName == "" {
+		remoteName = git.DefaultRemoteName
+	}
+	if merge == "" {
+		merge = plumbing.NewBranchReferenceName(branch)
+	}
+
+	return remoteName, merge, nil
+}
+
+// currentBranch returns the branch HEAD points at, failing on a detached HEAD
+func (s *GitService) currentBranch(repo *git.Repository) (plumbing.ReferenceName, error) {
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD reference: %w", err)
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		return "", errors.New("HEAD is detached, check out a branch first")
+	}
+	return head.Target(), nil
+}
+
+// Fetch downloads objects and refs from a remote, updating its remote-tracking branches
+func (s *GitService) Fetch(projectPath string, opts RemoteOptions) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	// Default to the remote the current branch tracks
+	remoteName := opts.Remote
+	if remoteName == "" {
+		remoteName = git.DefaultRemoteName
+		if branch, err := s.currentBranch(repo); err == nil {
+			if branchCfg, err := repo.Branch(branch.Short()); err == nil && branchCfg.Remote != "" {
+				remoteName = branchCfg.Remote
+			}
+		}
+	}
+
+	return s.fetch(repo, projectPath, "fetch", remoteName, opts)
+}
+
+// fetch runs a fetch against the given remote, treating "already up-to-date" as success.
+// The local remote has nothing to fetch.
+func (s *GitService) fetch(repo *git.Repository, projectPath, operation, remoteName string, opts RemoteOptions) error {
+	if remoteName == localRemote {
+		return nil
+	}
+
+	auth, err := s.remoteAuth(repo, remoteName, opts.Auth)
+	if err != nil {
+		return err
+	}
+
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: remoteName,
+		Auth:       auth,
+		Progress:   &progressWriter{service: s, projectPath: projectPath, operation: operation},
+		Prune:      opts.Prune,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return remoteError("fetch", err)
+	}
+
+	return nil
+}
+
+// Pull fetches the upstream of the current branch and integrates it.
+// By default only fast-forwards are allowed and ErrNonFastForward is returned when
+// the branches diverged; with PullMode "merge" a merge commit is created instead.
+func (s *GitService) Pull(projectPath string, opts RemoteOptions) (*PullResult, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	branch, err := s.currentBranch(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteName, merge, err := s.upstream(repo, branch.Short(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.fetch(repo, projectPath, "pull", remoteName, opts); err != nil {
+		return nil, err
+	}
+
+	// An upstream on the local remote is merged straight from the local branch
+	upstreamRef := plumbing.NewRemoteReferenceName(remoteName, merge.Short())
+	if remoteName == localRemote {
+		upstreamRef = merge
+	}
+
+	remoteRef, err := repo.Reference(upstreamRef, true)
+	if err != nil {
+		return nil, fmt.Errorf("upstream branch %s not found: %w", upstreamRef.Short(), err)
+	}
+	theirs := remoteRef.Hash()
+
+	head, err := repo.Head()
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	// An unborn branch simply starts at the remote branch
+	if head == nil {
+		return s.fastForward(repo, worktree, branch, theirs)
+	}
+
+	if head.Hash() == theirs {
+		return &PullResult{Status: "up-to-date", Head: theirs.String()}, nil
+	}
+
+	theirsCommit, err := repo.CommitObject(theirs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get remote commit: %w", err)
+	}
+
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	// Nothing to do when the remote branch is already part of our history
+	if behind, err := theirsCommit.IsAncestor(headCommit); err != nil {
+		return nil, fmt.Errorf("failed to compare commits: %w", err)
+	} else if behind {
+		return &PullResult{Status: "up-to-date", Head: head.Hash().String()}, nil
+	}
+
+	canFastForward, err := headCommit.IsAncestor(theirsCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare commits: %w", err)
+	}
+	if canFastForward {
+		return s.fastForward(repo, worktree, branch, theirs)
+	}
+
+	if opts.PullMode != PullMerge {
+		return nil, &RemoteError{
+			Operation: "pull",
+			Kind:      ErrNonFastForward,
+			Err:       fmt.Errorf("%s and %s have diverged", branch.Short(), upstreamRef.Short()),
+		}
+	}
+
+	message := fmt.Sprintf("Merge branch '%s' of %s into %s", merge.Short(), remoteName, branch.Short())
+	if remoteName == localRemote {
+		message = fmt.Sprintf("Merge branch '%s' into %s", merge.Short(), branch.Short())
+	}
+	result, err := s.merge(repo, worktree, theirs, upstreamRef.Short(), message, MergeNoFastForward)
+	if err != nil {
+		return nil, err
+	}
+	if result.Status == "conflict" {
+		return nil, &MergeConflictError{Files: result.Conflicts}
+	}
+
+	return &PullResult{Status: "merge", Head: result.Head}, nil
+}
+
+// fastForward moves the branch to the target commit, updating the files that changed
+func (s *GitService) fastForward(repo *git.Repository, worktree *git.Worktree, branch plumbing.ReferenceName, target plumbing.Hash) (*PullResult, error) {
+	files, err := s.prepareCheckout(repo, worktree, target, branch.Short())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(branch, target)); err != nil {
+		return nil, fmt.Errorf("failed to update branch: %w", err)
+	}
+
+	if err := s.checkoutFiles(worktree, target, files); err != nil {
+		return nil, err
+	}
+
+	return &PullResult{Status: "fast-forward", Head: target.String()}, nil
+}
+
+// Push uploads the current branch to a remote.
+// Non-fast-forward updates fail with ErrNonFastForward unless Force is set.
+func (s *GitService) Push(projectPath string, opts RemoteOptions) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	branch, err := s.currentBranch(repo)
+	if err != nil {
+		return err
+	}
+
+	remoteName, merge, err := s.upstream(repo, branch.Short(), opts)
+	if err != nil {
+		return err
+	}
+
+	auth, err := s.remoteAuth(repo, remoteName, opts.Auth)
+	if err != nil {
+		return err
+	}
+
+	if remoteName == localRemote {
+		if err := s.pushLocal(repo, branch, merge, opts.Force); err != nil {
+			return err
+		}
+	} else {
+		refSpec := config.RefSpec(fmt.Sprintf("%s:%s", branch, merge))
+		if opts.Force {
+			refSpec = "+" + refSpec
+		}
+
+		err = repo.Push(&git.PushOptions{
+			RemoteName: remoteName,
+			RefSpecs:   []config.RefSpec{refSpec},
+			Auth:       auth,
+			Progress:   &progressWriter{service: s, projectPath: projectPath, operation: "push"},
+		})
+		if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return remoteError("push", err)
+		}
+	}
+
+	if opts.SetUpstream {
+		if err := s.setBranchUpstream(repo, branch.Short(), remoteName, merge); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pushLocal pushes a branch to another local branch, like git push to the local remote:
+// the target only moves forward unless force is set
+func (s *GitService) pushLocal(repo *git.Repository, branch, target plumbing.ReferenceName, force bool) error {
+	ref, err := repo.Reference(branch, true)
+	if err != nil {
+		return fmt.Errorf("failed to get branch %s: %w", branch.Short(), err)
+	}
+
+	old, err := repo.Reference(target, true)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return fmt.Errorf("failed to get branch %s: %w", target.Short(), err)
+	}
+	if old != nil && old.Hash() == ref.Hash() {
+		return nil
+	}
+
+	if old != nil && !force {
+		oldCommit, err := repo.CommitObject(old.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to get commit: %w", err)
+		}
+		commit, err := repo.CommitObject(ref.Hash())
+		if err != nil {
+			return fmt.Errorf("failed to get commit: %w", err)
+		}
+		if ahead, err := oldCommit.IsAncestor(commit); err != nil {
+			return fmt.Errorf("failed to compare commits: %w", err)
+		} else if !ahead {
+			return &RemoteError{
+				Operation: "push",
+				Kind:      ErrNonFastForward,
+				Err:       fmt.Errorf("%s is not ahead of %s", branch.Short(), target.Short()),
+			}
+		}
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(target, ref.Hash())); err != nil {
+		return fmt.Errorf("failed to update branch %s: %w", target.Short(), err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+// createLocalUpstreamRepo creates a repository whose branch feat, checked out, tracks main
+// as its upstream on the local remote, with a commit on each branch since they forked
+func createLocalUpstreamRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.txt", "a\n")
+	commitAll(t, dir, "Add a")
+	runGit(t, dir, "checkout", "-q", "-b", "feat")
+	runGit(t, dir, "branch", "-q", "--set-upstream-to=main")
+	writeTestFile(t, dir, "f.txt", "f\n")
+	commitAll(t, dir, "Add f")
+
+	runGit(t, dir, "checkout", "-q", "main")
+	writeTestFile(t, dir, "m.txt", "m\n")
+	commitAll(t, dir, "Add m")
+	runGit(t, dir, "checkout", "-q", "feat")
+	return dir
+}
+
+// TestPullLocalUpstream pulls a branch whose upstream is a local branch and checks the
+// merge against git pull
+func TestPullLocalUpstream(t *testing.T) {
+	dir := createLocalUpstreamRepo(t)
+	expected := createLocalUpstreamRepo(t)
+
+	s := NewGitService(nil)
+	if err := s.Fetch(dir, RemoteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Pull(dir, RemoteOptions{}); !errors.Is(err, ErrNonFastForward) {
+		t.Fatalf("expected ErrNonFastForward, got %v", err)
+	}
+
+	result, err := s.Pull(dir, RemoteOptions{PullMode: PullMerge})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != "merge" {
+		t.Fatalf("unexpected pull result: %+v", result)
+	}
+	runGit(t, expected, "pull", "-q", "--no-rebase", "--no-edit")
+
+	compareRepos(t, dir, expected,
+		[]string{"log", "-1", "--format=%s%n%T%n%P"},
+		[]string{"status", "--porcelain"},
+	)
+}
+
+// TestPushLocalUpstream pushes a branch to its local upstream, which only moves forward
+func TestPushLocalUpstream(t *testing.T) {
+	dir := createLocalUpstreamRepo(t)
+
+	s := NewGitService(nil)
+	if err := s.Push(dir, RemoteOptions{}); !errors.Is(err, ErrNonFastForward) {
+		t.Fatalf("expected ErrNonFastForward, got %v", err)
+	}
+
+	runGit(t, dir, "reset", "-q", "--hard", "main")
+	writeTestFile(t, dir, "g.txt", "g\n")
+	commitAll(t, dir, "Add g")
+	if err := s.Push(dir, RemoteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if main, feat := runGit(t, dir, "rev-parse", "main"), runGit(t, dir, "rev-parse", "feat"); main != feat {
+		t.Errorf("main is at %s, expected %s", main, feat)
+	}
+}
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+// DefaultRenameSimilarity is the similarity, in percent, from which a deleted and an added
+// file are taken as a rename, like git's default of -M50%
+const DefaultRenameSimilarity = 50
+
+// renameLimit caps the files compared by content, past it only exact renames are found.
+// Like git's diff.renameLimit it applies to both the sources and the targets.
+const renameLimit = 1000
+
+// renameChunkSize is the longest piece of a line compared on its own, so binary files and
+// very long lines can still be partly similar
+const renameChunkSize = 64
+
+// renameFile is a file that may be the source or the target of a rename
+type renameFile struct {
+	path   string
+	hash   plumbing.Hash
+	mode   filemode.FileMode
+	size   int64
+	read   func() ([]byte, error)
+	chunks map[uint64]int // Bytes of the file in each chunk, by chunk hash, once read
+}
+
+// renamePair is a detected rename or copy
+type renamePair struct {
+	from       string
+	to         string
+	similarity int
+	copied     bool // The source still exists, so the file was copied rather than moved
+}
+
+// renameConfig returns whether renames and copies are detected for the status, from
+// status.renames or diff.renames. Like git, renames are detected unless set to false.
+func (s *GitService) renameConfig(repo *git.Repository) (bool, bool, error) {
+	scopes, err := s.loadConfigScopes(repo)
+	if err != nil {
+		return false, false, err
+	}
+
+	value, scope := scopes.get("status", "", "renames")
+	if scope == "" {
+		value, scope = scopes.get("diff", "", "renames")
+	}
+	if scope == "" {
+		return true, false, nil
+	}
+
+	switch strings.ToLower(value) {
+	case "copies", "copy":
+		return true, true, nil
+	case "", "true", "yes", "on", "1":
+		return true, false, nil
+	}
+	return false, false, nil
+}
+
+// stagedRenames pairs files deleted from the index with files added to it, and with copies
+// enabled also pairs added files with modified ones they were copied from
+func (s *GitService) stagedRenames(repo *git.Repository, status git.Status, copies bool) ([]renamePair, error) {
+	var deleted, modified, added []string
+	for file, fileStatus := range status {
+		switch fileStatus.Staging {
+		case git.Deleted:
+			deleted = append(deleted, file)
+		case git.Modified:
+			modified = append(modified, file)
+		case git.Added:
+			added = append(added, file)
+		}
+	}
+	if len(added) == 0 || (len(deleted) == 0 && !copies) {
+		return nil, nil
+	}
+
+	head, err := s.headEntries(repo)
+	if err != nil {
+		return nil, err
+	}
+	idx, _, err := s.readIndex(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	headFile := func(path string) *renameFile {
+		entry, ok := head[path]
+		if !ok {
+			return nil
+		}
+		return s.blobRenameFile(repo, path, entry.Hash, entry.Mode)
+	}
+
+	sources := renameFiles(deleted, headFile)
+	var copySources []*renameFile
+	if copies {
+		copySources = append(renameFiles(modified, headFile), sources...)
+	}
+	targets := renameFiles(added, func(path string) *renameFile {
+		entry, err := idx.Entry(path)
+		if err != nil || entry.Stage != 0 {
+			return nil
+		}
+		return s.blobRenameFile(repo, path, entry.Hash, entry.Mode)
+	})
+
+	return findRenames(sources, copySources, targets, DefaultRenameSimilarity)
+}
+
+// worktreeRenames pairs tracked files missing from the working tree with untracked files.
+// git status doesn't do this until the new file is added.
+func (s *GitService) worktreeRenames(repo *git.Repository, worktree *git.Worktree, status git.Status) ([]renamePair, error) {
+	var deleted, untracked []string
+	for file, fileStatus := range status {
+		switch {
+		case fileStatus.Worktree == git.Deleted && fileStatus.Staging != git.Deleted:
+			deleted = append(deleted, file)
+		case fileStatus.Worktree == git.Untracked:
+			untracked = append(untracked, file)
+		}
+	}
+	if len(deleted) == 0 || len(untracked) == 0 {
+		return nil, nil
+	}
+
+	idx, _, err := s.readIndex(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := renameFiles(deleted, func(path string) *renameFile {
+		entry, err := idx.Entry(path)
+		if err != nil || entry.Stage != 0 {
+			return nil
+		}
+		return s.blobRenameFile(repo, path, entry.Hash, entry.Mode)
+	})
+
+	root := worktree.Filesystem.Root()
+	targets := renameFiles(untracked, func(path string) *renameFile {
+		file, err := worktreeRenameFile(root, path)
+		if err != nil {
+			return nil
+		}
+		return file
+	})
+
+	return findRenames(sources, nil, targets, DefaultRenameSimilarity)
+}
+
+// renameFiles describes the given paths with describe, leaving out the ones it returns nil for
+func renameFiles(paths []string, describe func(path string) *renameFile) []*renameFile {
+	sort.Strings(paths)
+
+	files := make([]*renameFile, 0, len(paths))
+	for _, path := range paths {
+		if file := describe(path); file != nil {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// blobRenameFile describes a blob of the repository. Submodules are never renamed.
+func (s *GitService) blobRenameFile(repo *git.Repository, path string, hash plumbing.Hash, mode filemode.FileMode) *renameFile {
+	if mode == filemode.Submodule {
+		return nil
+	}
+
+	size, err := repo.Storer.EncodedObjectSize(hash)
+	if err != nil {
+		return nil
+	}
+
+	return &renameFile{
+		path: path,
+		hash: hash,
+		mode: mode,
+		size: size,
+		read: func() ([]byte, error) {
+			content, err := s.readBlob(repo, hash)
+			return []byte(content), err
+		},
+	}
+}
+
+// worktreeRenameFile describes a file in the working tree
+func worktreeRenameFile(root string, path string) (*renameFile, error) {
+	fullPath := filepath.Join(root, filepath.FromSlash(path))
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	hash, err := hashWorktreeFile(fullPath, info)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, err := filemode.NewFromOSFileMode(info.Mode())
+	if err != nil {
+		return nil, err
+	}
+
+	return &renameFile{
+		path: path,
+		hash: hash,
+		mode: mode,
+		size: info.Size(),
+		read: func() ([]byte, error) {
+			if mode == filemode.Symlink {
+				target, err := os.Readlink(fullPath)
+				return []byte(filepath.ToSlash(target)), err
+			}
+			return os.ReadFile(fullPath)
+		},
+	}, nil
+}
+
+// findRenames pairs each target with the most similar source, like git's rename detection:
+// identical files first, then by similarity of content, each source used once. Targets
+// left over are then paired with copySources, which can be copied any number of times.
+// Empty files are only paired when moved, their content tells nothing.
+func findRenames(sources, copySources, targets []*renameFile, threshold int) ([]renamePair, error) {
+	var pairs []renamePair
+	used := make(map[string]bool)
+	paired := make(map[string]bool)
+
+	match := func(sources []*renameFile, copied bool) error {
+		// Identical content is a full match
+		for _, target := range targets {
+			if paired[target.path] || (copied && target.size == 0) {
+				continue
+			}
+			for _, source := range sources {
+				if (!copied && used[source.path]) || source.hash != target.hash || !sameFileKind(source.mode, target.mode) {
+					continue
+				}
+				pairs = append(pairs, renamePair{from: source.path, to: target.path, similarity: 100, copied: copied})
+				used[source.path] = true
+				paired[target.path] = true
+				break
+			}
+		}
+
+		var remainingSources, remainingTargets []*renameFile
+		for _, source := range sources {
+			if (copied || !used[source.path]) && source.size > 0 {
+				remainingSources = append(remainingSources, source)
+			}
+		}
+		for _, target := range targets {
+			if !paired[target.path] && target.size > 0 {
+				remainingTargets = append(remainingTargets, target)
+			}
+		}
+		if len(remainingSources) == 0 || len(remainingTargets) == 0 ||
+			len(remainingSources) > renameLimit || len(remainingTargets) > renameLimit {
+			return nil
+		}
+
+		var candidates []renamePair
+		for _, target := range remainingTargets {
+			for _, source := range remainingSources {
+				if !sameFileKind(source.mode, target.mode) || !similarSize(source.size, target.size, threshold) {
+					continue
+				}
+				similarity, err := fileSimilarity(source, target)
+				if err != nil {
+					return err
+				}
+				if similarity >= threshold {
+					candidates = append(candidates, renamePair{from: source.path, to: target.path, similarity: similarity, copied: copied})
+				}
+			}
+		}
+
+		// The most similar pairs win, ties go to the first paths
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].similarity > candidates[j].similarity
+		})
+		for _, candidate := range candidates {
+			if paired[candidate.to] || (!copied && used[candidate.from]) {
+				continue
+			}
+			pairs = append(pairs, candidate)
+			used[candidate.from] = true
+			paired[candidate.to] = true
+		}
+
+		return nil
+	}
+
+	if err := match(sources, false); err != nil {
+		return nil, err
+	}
+	if len(copySources) > 0 {
+		if err := match(copySources, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return pairs, nil
+}
+
+// sameFileKind reports whether two modes are both symlinks or both regular files
+func sameFileKind(a, b filemode.FileMode) bool {
+	return (a == filemode.Symlink) == (b == filemode.Symlink)
+}
+
+// similarSize reports whether files of these sizes can reach the similarity threshold at all
+func similarSize(a, b int64, threshold int) bool {
+	if a > b {
+		a, b = b, a
+	}
+	return a*100 >= b*int64(threshold)
+}
+
+// fileSimilarity returns how much of the larger of two files is found in the other, in percent
+func fileSimilarity(a, b *renameFile) (int, error) {
+	chunksA, err := a.loadChunks()
+	if err != nil {
+		return 0, err
+	}
+	chunksB, err := b.loadChunks()
+	if err != nil {
+		return 0, err
+	}
+
+	var common int64
+	for hash, bytesA := range chunksA {
+		common += int64(min(bytesA, chunksB[hash]))
+	}
+
+	return int(common * 100 / max(a.size, b.size)), nil
+}
+
+// loadChunks reads the file and counts its bytes per chunk: lines, split further when they
+// are longer than renameChunkSize
+func (f *renameFile) loadChunks() (map[uint64]int, error) {
+	if f.chunks != nil {
+		return f.chunks, nil
+	}
+
+	content, err := f.read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", f.path, err)
+	}
+
+	chunks := make(map[uint64]int)
+	for len(content) > 0 {
+		end := 0
+		for end < len(content) && end < renameChunkSize {
+			end++
+			if content[end-1] == '\n' {
+				break
+			}
+		}
+
+		hasher := fnv.New64a()
+		hasher.Write(content[:end])
+		chunks[hasher.Sum64()] += end
+		content = content[end:]
+	}
+
+	f.chunks = chunks
+	return chunks, nil
+}
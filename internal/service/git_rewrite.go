@@ -0,0 +1,431 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Reset modes, named like the git reset flags
+const (
+	ResetSoft  = "soft"  // Move HEAD only
+	ResetMixed = "mixed" // Move HEAD and reset the index
+	ResetHard  = "hard"  // Move HEAD and reset the index and working tree
+)
+
+// Files git uses to record a cherry-pick or revert waiting for its conflicts to be resolved
+const (
+	cherryPickHead = "CHERRY_PICK_HEAD"
+	revertHead     = "REVERT_HEAD"
+)
+
+// AmendCommit replaces the HEAD commit with one that has the staged content, keeping its
+// author and parents. An empty message keeps the previous message. The commit hooks run
+// as for Commit. It returns the new HEAD.
+func (s *GitService) AmendCommit(projectPath string, message string) (string, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.checkNoMergeInProgress(repo); err != nil {
+		return "", err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return "", errors.New("there is no commit to amend yet")
+		}
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	// The hooks run first, pre-commit may still change the index
+	source, sourceArgs := "message", []string(nil)
+	if message == "" {
+		message = headCommit.Message
+		source, sourceArgs = "commit", []string{headCommit.Hash.String()}
+	}
+	if message, err = s.runCommitHooks(repo, worktree, message, source, sourceArgs...); err != nil {
+		return "", err
+	}
+
+	entries, err := s.indexEntries(repo)
+	if err != nil {
+		return "", fmt.Errorf("cannot amend: %w", err)
+	}
+
+	tree, err := s.buildTree(repo, entries)
+	if err != nil {
+		return "", err
+	}
+
+	committer, err := s.signature(repo)
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := s.createCommit(repo, &object.Commit{
+		Author:       headCommit.Author,
+		Committer:    *committer,
+		Message:      commitMessage(message),
+		TreeHash:     tree,
+		ParentHashes: headCommit.ParentHashes,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.updateHead(repo, hash, "commit (amend): "+firstLine(message)); err != nil {
+		return "", err
+	}
+
+	s.runPostCommitHook(repo, worktree)
+
+	return hash.String(), nil
+}
+
+// Reset moves the current branch to target (HEAD if empty). The mode is ResetSoft,
+// ResetMixed (the default) or ResetHard. Mixed and hard resets abandon a merge,
+// cherry-pick or revert in progress. It returns the new HEAD.
+func (s *GitService) Reset(projectPath string, mode string, target string) (string, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return "", err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	var resetMode git.ResetMode
+	switch mode {
+	case ResetSoft:
+		resetMode = git.SoftReset
+	case ResetMixed, "":
+		resetMode = git.MixedReset
+	case ResetHard:
+		resetMode = git.HardReset
+	default:
+		return "", fmt.Errorf("unknown reset mode %q", mode)
+	}
+
+	if resetMode == git.SoftReset {
+		if err := s.checkNoMergeInProgress(repo); err != nil {
+			return "", fmt.Errorf("cannot do a soft reset: %w", err)
+		}
+	}
+
+	commit, err := s.resolveCommit(repo, target)
+	if err != nil {
+		return "", err
+	}
+
+	old := plumbing.ZeroHash
+	if head, err := repo.Head(); err == nil {
+		old = head.Hash()
+	}
+
+	// Conflict stages are dropped first, the reset only knows about merged entries
+	if resetMode != git.SoftReset {
+		if err := s.dropConflictStages(repo); err != nil {
+			return "", err
+		}
+	}
+
+	opts := &git.ResetOptions{Commit: commit.Hash, Mode: resetMode}
+	if resetMode == git.HardReset {
+		// A hard reset of go-git deletes untracked files, so it is limited to the tracked ones
+		opts.Files, err = s.hardResetFiles(repo, worktree, commit)
+		if err != nil {
+			return "", err
+		}
+		if len(opts.Files) == 0 {
+			opts.Mode = git.SoftReset
+		}
+	}
+	if err := worktree.Reset(opts); err != nil {
+		return "", fmt.Errorf("failed to reset: %w", err)
+	}
+
+	if resetMode != git.SoftReset {
+		if err := s.clearMergeState(repo); err != nil {
+			return "", err
+		}
+	}
+
+	if target == "" {
+		target = "HEAD"
+	}
+	if err := s.logHeadUpdate(repo, old, commit.Hash, "reset: moving to "+target); err != nil {
+		return "", err
+	}
+
+	return commit.Hash.String(), nil
+}
+
+// hardResetFiles returns the files a hard reset to a commit changes: those that differ
+// between HEAD and the commit, and those with staged or unstaged changes
+func (s *GitService) hardResetFiles(repo *git.Repository, worktree *git.Worktree, commit *object.Commit) ([]string, error) {
+	headTree, err := s.headTree(repo)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree: %w", err)
+	}
+	changed, err := changedEntries(headTree, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := s.status(repo, worktree)
+	if err != nil {
+		return nil, err
+	}
+	for path, fileStatus := range status {
+		if fileStatus.Staging != git.Untracked {
+			changed[path] = nil
+		}
+	}
+
+	files := make([]string, 0, len(changed))
+	for path := range changed {
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// RevertCommit creates a commit that undoes the changes of a commit. When the undo conflicts
+// with later changes, the conflicts are left to resolve as for a merge and a
+// *MergeConflictError is returned; Commit finishes the revert and MergeAbort abandons it.
+// It returns the new HEAD.
+func (s *GitService) RevertCommit(projectPath string, hash string) (string, error) {
+	return s.pickCommit(projectPath, hash, true)
+}
+
+// CherryPick applies the changes of a commit on top of HEAD, keeping its author and message.
+// Conflicts are handled like in RevertCommit. It returns the new HEAD.
+func (s *GitService) CherryPick(projectPath string, hash string) (string, error) {
+	return s.pickCommit(projectPath, hash, false)
+}
+
+// pickCommit cherry-picks or reverts a commit, see CherryPick and RevertCommit
+func (s *GitService) pickCommit(projectPath string, hash string, revert bool) (string, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return "", err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	if err := s.checkNoMergeInProgress(repo); err != nil {
+		return "", err
+	}
+
+	commit, err := s.resolveCommit(repo, hash)
+	if err != nil {
+		return "", err
+	}
+
+	short := commit.Hash.String()[:7]
+	if commit.NumParents() > 1 {
+		return "", fmt.Errorf("%s is a merge commit, which can't be cherry-picked or reverted", short)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get HEAD tree: %w", err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit tree: %w", err)
+	}
+
+	// A root commit is compared to the empty tree
+	var parentTree *object.Tree
+	if commit.NumParents() == 1 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return "", fmt.Errorf("failed to get parent commit: %w", err)
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return "", fmt.Errorf("failed to get parent tree: %w", err)
+		}
+	}
+
+	subject := firstLine(commit.Message)
+	base, theirs := parentTree, tree
+	label := fmt.Sprintf("%s (%s)", short, subject)
+	stateFile, reflogMessage := cherryPickHead, "cherry-pick: "+subject
+	author := commit.Author
+	message := commit.Message
+
+	committer, err := s.signature(repo)
+	if err != nil {
+		return "", err
+	}
+
+	if revert {
+		base, theirs = tree, parentTree
+		label = "parent of " + label
+		message = fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s.\n", subject, commit.Hash)
+		stateFile, reflogMessage = revertHead, fmt.Sprintf("revert: Revert \"%s\"", subject)
+		reverter, err := s.authorSignature(repo)
+		if err != nil {
+			return "", err
+		}
+		author = *reverter
+	}
+
+	result, err := s.mergeTrees(repo, worktree, base, headTree, theirs, mergeLabels{ours: "HEAD", theirs: label})
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.writeMergeResult(repo, worktree, result); err != nil {
+		return "", err
+	}
+
+	if len(result.conflicts) > 0 {
+		if err := s.writePickState(repo, stateFile, commit.Hash, message, result.conflictPaths()); err != nil {
+			return "", err
+		}
+		return "", &MergeConflictError{Files: result.conflictPaths()}
+	}
+
+	// The commit only has the picked changes, not whatever else is staged
+	entries, err := s.headEntries(repo)
+	if err != nil {
+		return "", err
+	}
+	for path, entry := range result.updates {
+		if entry == nil {
+			delete(entries, path)
+		} else {
+			entries[path] = *entry
+		}
+	}
+
+	newTree, err := s.buildTree(repo, entries)
+	if err != nil {
+		return "", err
+	}
+	if newTree == headCommit.TreeHash {
+		if revert {
+			return "", fmt.Errorf("the changes of %s are already undone", short)
+		}
+		return "", fmt.Errorf("the changes of %s are already applied", short)
+	}
+
+	newHash, err := s.createCommit(repo, &object.Commit{
+		Author:       author,
+		Committer:    *committer,
+		Message:      message,
+		TreeHash:     newTree,
+		ParentHashes: []plumbing.Hash{headCommit.Hash},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.updateHead(repo, newHash, reflogMessage); err != nil {
+		return "", err
+	}
+
+	return newHash.String(), nil
+}
+
+// checkNoMergeInProgress returns ErrMergeInProgress while a merge, cherry-pick or revert
+// waits for its conflicts to be resolved, and ErrRebaseInProgress during a rebase
+func (s *GitService) checkNoMergeInProgress(repo *git.Repository) error {
+	if _, merging, err := s.mergeHead(repo); err != nil {
+		return err
+	} else if merging {
+		return ErrMergeInProgress
+	}
+
+	if _, _, picking, err := s.pickHead(repo); err != nil {
+		return err
+	} else if picking {
+		return ErrMergeInProgress
+	}
+
+	gitDir, err := s.gitDir(repo)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(gitDir, rebaseMergeDir)); err == nil {
+		return ErrRebaseInProgress
+	}
+
+	return nil
+}
+
+// dropConflictStages replaces the conflict stages of unmerged files in the index with their
+// HEAD version, or removes them when HEAD doesn't have them
+func (s *GitService) dropConflictStages(repo *git.Repository) error {
+	unmerged, err := s.unmergedPaths(repo)
+	if err != nil || len(unmerged) == 0 {
+		return err
+	}
+
+	headTree, err := s.headTree(repo)
+	if err != nil {
+		return err
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	for path := range unmerged {
+		var entry *object.TreeEntry
+		if headEntry, err := findEntry(headTree, path); err == nil {
+			entry = headEntry
+		}
+		if err := s.setIndexEntry(repo, idx, path, entry); err != nil {
+			return err
+		}
+	}
+
+	return s.setIndex(repo, idx)
+}
+
+// commitMessage returns a message ending with a newline, like git writes them
+func commitMessage(message string) string {
+	return strings.TrimRight(message, "\n") + "\n"
+}
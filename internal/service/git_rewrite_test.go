@@ -0,0 +1,126 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+// createRewriteRepo creates a repository with three commits on main, each changing a.txt,
+// and a branch side with a commit adding s.txt
+func createRewriteRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.txt", "1\n2\n3\n4\n5\n6\n7\n8\n9\n")
+	commitAll(t, dir, "Add a")
+	runGit(t, dir, "branch", "side")
+	writeTestFile(t, dir, "a.txt", "one\n2\n3\n4\n5\n6\n7\n8\n9\n")
+	commitAll(t, dir, "Change one")
+	writeTestFile(t, dir, "a.txt", "one\n2\n3\n4\n5\n6\n7\n8\nnine\n")
+	commitAll(t, dir, "Change nine")
+
+	runGit(t, dir, "checkout", "-q", "side")
+	writeTestFile(t, dir, "s.txt", "side\n")
+	commitAll(t, dir, "Add s")
+	runGit(t, dir, "checkout", "-q", "main")
+	return dir
+}
+
+// compareRepos fails the test for every git command whose output differs between the
+// repository and the one of git
+func compareRepos(t *testing.T, dir, expected string, commands ...[]string) {
+	t.Helper()
+
+	for _, args := range commands {
+		if got, want := runGit(t, dir, args...), runGit(t, expected, args...); got != want {
+			t.Errorf("git %s differs from git:\n%s\nexpected:\n%s", strings.Join(args, " "), got, want)
+		}
+	}
+}
+
+// TestAmendCommit amends the HEAD commit with staged changes and a new message
+func TestAmendCommit(t *testing.T) {
+	dir := createRewriteRepo(t)
+	expected := createRewriteRepo(t)
+	for _, repo := range []string{dir, expected} {
+		writeTestFile(t, repo, "b.txt", "b\n")
+		runGit(t, repo, "add", "b.txt")
+	}
+
+	s := NewGitService(nil)
+	head, err := s.AmendCommit(dir, "Change nine and add b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, expected, "commit", "-q", "--amend", "-m", "Change nine and add b")
+
+	if got := runGit(t, dir, "rev-parse", "HEAD"); got != head+"\n" {
+		t.Errorf("AmendCommit returned %s, HEAD is %s", head, got)
+	}
+	compareRepos(t, dir, expected,
+		[]string{"log", "--format=%s%n%an <%ae> %ad%n%T%n%P", "HEAD"},
+		[]string{"status", "--porcelain"},
+	)
+}
+
+// TestReset resets main to its first commit in every mode, with staged, unstaged and
+// untracked changes
+func TestReset(t *testing.T) {
+	for _, mode := range []string{ResetSoft, ResetMixed, ResetHard} {
+		dir := createRewriteRepo(t)
+		expected := createRewriteRepo(t)
+		for _, repo := range []string{dir, expected} {
+			writeTestFile(t, repo, "untracked.txt", "untracked\n")
+			writeTestFile(t, repo, "staged.txt", "staged\n")
+			runGit(t, repo, "add", "staged.txt")
+			writeTestFile(t, repo, "a.txt", "changed\n")
+		}
+
+		s := NewGitService(nil)
+		if _, err := s.Reset(dir, mode, "HEAD~2"); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, expected, "reset", "-q", "--"+mode, "HEAD~2")
+
+		compareRepos(t, dir, expected,
+			[]string{"rev-parse", "HEAD"},
+			[]string{"status", "--porcelain"},
+			[]string{"diff"},
+			[]string{"diff", "--cached"},
+		)
+	}
+}
+
+// TestRevertCommit reverts a commit that later commits didn't touch the lines of
+func TestRevertCommit(t *testing.T) {
+	dir := createRewriteRepo(t)
+	expected := createRewriteRepo(t)
+
+	s := NewGitService(nil)
+	if _, err := s.RevertCommit(dir, "HEAD~1"); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, expected, "revert", "--no-edit", "HEAD~1")
+
+	compareRepos(t, dir, expected,
+		[]string{"log", "--format=%s%n%b%n%T%n%P", "-1"},
+		[]string{"status", "--porcelain"},
+	)
+}
+
+// TestCherryPick picks the commit of side onto main
+func TestCherryPick(t *testing.T) {
+	dir := createRewriteRepo(t)
+	expected := createRewriteRepo(t)
+
+	s := NewGitService(nil)
+	if _, err := s.CherryPick(dir, "side"); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, expected, "cherry-pick", "side")
+
+	compareRepos(t, dir, expected,
+		[]string{"log", "--format=%s%n%an <%ae> %ad%n%T%n%P", "-1"},
+		[]string{"status", "--porcelain"},
+	)
+}
@@ -0,0 +1,465 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Signature formats, the values of gpg.format
+const (
+	SigningFormatOpenPGP = "openpgp" // Signed by gpg
+	SigningFormatX509    = "x509"    // Signed by gpgsm
+	SigningFormatSSH     = "ssh"     // Signed by ssh-keygen
+)
+
+// Armor headers that tell the format of a signature
+var signatureHeaders = map[string]string{
+	"-----BEGIN PGP SIGNATURE-----":  SigningFormatOpenPGP,
+	"-----BEGIN PGP MESSAGE-----":    SigningFormatOpenPGP,
+	"-----BEGIN SIGNED MESSAGE-----": SigningFormatX509,
+	"-----BEGIN SSH SIGNATURE-----":  SigningFormatSSH,
+}
+
+// commandSigner signs commits with the program configured for the signature format,
+// passing the same arguments as git
+type commandSigner struct {
+	format  string
+	program string
+	key     string
+}
+
+// commitOptions returns the options for a new commit: the configured author and
+// committer, and a signer when commit.gpgsign is set
+func (s *GitService) commitOptions(repo *git.Repository) (*git.CommitOptions, error) {
+	author, err := s.authorSignature(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	committer, err := s.signature(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &git.CommitOptions{Author: author, Committer: committer}
+
+	signer, err := s.commitSigner(repo)
+	if err != nil {
+		return nil, err
+	}
+	if signer != nil {
+		opts.Signer = signer
+	}
+
+	return opts, nil
+}
+
+// commitSigner returns the signer for new commits, or nil when commit.gpgsign isn't set
+func (s *GitService) commitSigner(repo *git.Repository) (*commandSigner, error) {
+	scopes, err := s.loadConfigScopes(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scopes.getBool("commit", "", "gpgsign") {
+		return nil, nil
+	}
+
+	return s.signer(repo, scopes)
+}
+
+// signer returns a signer for the configured gpg.format and user.signingkey
+func (s *GitService) signer(repo *git.Repository, scopes *configScopes) (*commandSigner, error) {
+	signer := &commandSigner{format: SigningFormatOpenPGP}
+	if format, _ := scopes.get("gpg", "", "format"); format != "" {
+		signer.format = strings.ToLower(format)
+	}
+	signer.program = signingProgram(scopes, signer.format)
+	if signer.program == "" {
+		return nil, fmt.Errorf("unsupported signing format %q", signer.format)
+	}
+
+	signer.key, _ = scopes.get("user", "", "signingkey")
+	if signer.key == "" {
+		if signer.format == SigningFormatSSH {
+			return nil, errors.New("signing with SSH needs user.signingkey to be set")
+		}
+
+		// gpg picks the key that matches the committer
+		committer, err := s.signature(repo)
+		if err != nil {
+			return nil, err
+		}
+		signer.key = fmt.Sprintf("%s <%s>", committer.Name, committer.Email)
+	}
+
+	return signer, nil
+}
+
+// signingProgram returns the program that signs and verifies a signature format:
+// gpg.<format>.program, gpg.program for openpgp, or the program git defaults to
+func signingProgram(scopes *configScopes, format string) string {
+	if program, _ := scopes.get("gpg", format, "program"); program != "" {
+		return program
+	}
+
+	switch format {
+	case SigningFormatOpenPGP:
+		if program, _ := scopes.get("gpg", "", "program"); program != "" {
+			return program
+		}
+		return "gpg"
+	case SigningFormatX509:
+		return "gpgsm"
+	case SigningFormatSSH:
+		return "ssh-keygen"
+	}
+	return ""
+}
+
+// Sign returns a detached signature of the message, implementing git.Signer
+func (c *commandSigner) Sign(message io.Reader) ([]byte, error) {
+	if c.format == SigningFormatSSH {
+		return c.signSSH(message)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(c.program, "--status-fd=2", "-bsau", c.key)
+	cmd.Stdin = message
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil || !strings.Contains(stderr.String(), "[GNUPG:] SIG_CREATED ") {
+		return nil, fmt.Errorf("failed to sign with %s: %s", c.program, commandOutput(err, stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// signSSH signs a message with ssh-keygen, which only signs files
+func (c *commandSigner) signSSH(message io.Reader) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "editor-sign-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	payload := filepath.Join(dir, "payload")
+	content, err := io.ReadAll(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload: %w", err)
+	}
+	if err := os.WriteFile(payload, content, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write payload: %w", err)
+	}
+
+	args := []string{"-Y", "sign", "-n", "git", "-f"}
+	if key, literal := literalSSHKey(c.key); literal {
+		// With a public key, ssh-keygen signs through the ssh-agent that holds its private key
+		keyFile := filepath.Join(dir, "key.pub")
+		if err := os.WriteFile(keyFile, []byte(key+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write signing key: %w", err)
+		}
+		args = append(args, keyFile, "-U")
+	} else {
+		args = append(args, expandHome(c.key))
+	}
+	args = append(args, payload)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(c.program, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to sign with %s: %s", c.program, commandOutput(err, stderr.String()))
+	}
+
+	signature, err := os.ReadFile(payload + ".sig")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature: %w", err)
+	}
+
+	return signature, nil
+}
+
+// signCommit signs a commit that is about to be stored, if commit signing is enabled
+func (s *GitService) signCommit(repo *git.Repository, commit *object.Commit) error {
+	signer, err := s.commitSigner(repo)
+	if err != nil || signer == nil {
+		return err
+	}
+
+	payload, err := commitPayload(commit)
+	if err != nil {
+		return err
+	}
+
+	signature, err := signer.Sign(bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	commit.PGPSignature = string(signature)
+	return nil
+}
+
+// createCommit signs a commit if commit signing is enabled and writes it to the object database
+func (s *GitService) createCommit(repo *git.Repository, commit *object.Commit) (plumbing.Hash, error) {
+	if err := s.signCommit(repo, commit); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return s.storeCommit(repo, commit)
+}
+
+// maxVerifiers is how many signature checks VerifyCommits runs at once
+const maxVerifiers = 4
+
+// VerifyCommits checks the signatures of commits and returns whether each one is good and made
+// by a trusted key, by hash. Unsigned commits are not verified. Listing commits doesn't check
+// signatures, each check runs gpg, gpgsm or ssh-keygen, so this is called for the commits shown.
+func (s *GitService) VerifyCommits(projectPath string, hashes []string) (map[string]bool, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]*object.Commit, len(hashes))
+	for i, hash := range hashes {
+		if commits[i], err = repo.CommitObject(plumbing.NewHash(hash)); err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", hash, err)
+		}
+	}
+
+	verified := make([]bool, len(commits))
+	work := make(chan int)
+	var workers sync.WaitGroup
+	for i := 0; i < maxVerifiers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for i := range work {
+				verified[i] = s.verifyCommit(repo, commits[i])
+			}
+		}()
+	}
+	for i := range commits {
+		work <- i
+	}
+	close(work)
+	workers.Wait()
+
+	results := make(map[string]bool, len(hashes))
+	for i, hash := range hashes {
+		results[hash] = verified[i]
+	}
+	return results, nil
+}
+
+// verifyCommit reports whether a commit has a good signature from a trusted key, see verifySignature
+func (s *GitService) verifyCommit(repo *git.Repository, commit *object.Commit) bool {
+	if commit.PGPSignature == "" {
+		return false
+	}
+
+	payload, err := commitPayload(commit)
+	if err != nil {
+		log.Printf("[GitService] Failed to verify commit %s: %v", commit.Hash, err)
+		return false
+	}
+
+	return s.verifySignature(repo, commit.Hash, commit.PGPSignature, payload)
+}
+
+// verifySignature reports whether the signature of an object is good and made by a trusted key:
+// a key in the gpg keyring, or one listed in gpg.ssh.allowedSignersFile for SSH signatures.
+// Results are cached by object hash, a signature doesn't change and the keys rarely do.
+// A signature that can't be checked, because the verifier is missing or fails to run, is
+// not verified. The failure is logged once per program and not cached, so installing the
+// program later is enough.
+func (s *GitService) verifySignature(repo *git.Repository, hash plumbing.Hash, signature string, payload []byte) bool {
+	s.signaturesMu.Lock()
+	verified, ok := s.signatures[hash]
+	s.signaturesMu.Unlock()
+	if ok {
+		return verified
+	}
+
+	scopes, err := s.loadConfigScopes(repo)
+	if err != nil {
+		log.Printf("[GitService] Failed to verify %s: %v", hash, err)
+		return false
+	}
+
+	var program string
+	format := signatureFormat(signature)
+	switch format {
+	case SigningFormatOpenPGP, SigningFormatX509:
+		program = signingProgram(scopes, format)
+		verified, err = verifyGPG(program, signature, payload)
+	case SigningFormatSSH:
+		program = signingProgram(scopes, format)
+		allowedSigners, _ := scopes.get("gpg", "ssh", "allowedsignersfile")
+		verified, err = verifySSH(program, expandHome(allowedSigners), signature, payload)
+	}
+	if err != nil {
+		s.signaturesMu.Lock()
+		logged := s.verifyFailed[program]
+		s.verifyFailed[program] = true
+		s.signaturesMu.Unlock()
+		if !logged {
+			log.Printf("[GitService] Can't verify signatures, signed commits and tags show as unverified: %v", err)
+		}
+		return false
+	}
+
+	s.signaturesMu.Lock()
+	s.signatures[hash] = verified
+	s.signaturesMu.Unlock()
+
+	return verified
+}
+
+// verifyGPG checks a gpg or gpgsm signature, git requires a GOODSIG status
+func verifyGPG(program string, signature string, content []byte) (bool, error) {
+	dir, err := os.MkdirTemp("", "editor-verify-")
+	if err != nil {
+		return false, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sigFile := filepath.Join(dir, "signature")
+	if err := os.WriteFile(sigFile, []byte(signature), 0600); err != nil {
+		return false, fmt.Errorf("failed to write signature: %w", err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(program, "--keyid-format=long", "--status-fd=1", "--verify", sigFile, "-")
+	cmd.Stdin = bytes.NewReader(content)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// A bad signature or an unknown key
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to run %s: %w", program, err)
+	}
+
+	return strings.Contains(stdout.String(), "[GNUPG:] GOODSIG "), nil
+}
+
+// verifySSH checks an SSH signature against the allowed signers file, without
+// which no SSH signature verifies
+func verifySSH(program string, allowedSigners string, signature string, content []byte) (bool, error) {
+	if allowedSigners == "" {
+		return false, nil
+	}
+
+	dir, err := os.MkdirTemp("", "editor-verify-")
+	if err != nil {
+		return false, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sigFile := filepath.Join(dir, "signature")
+	if err := os.WriteFile(sigFile, []byte(signature), 0600); err != nil {
+		return false, fmt.Errorf("failed to write signature: %w", err)
+	}
+
+	// The signature doesn't say who made it, look up the principals of its key
+	output, err := exec.Command(program, "-Y", "find-principals", "-f", allowedSigners, "-s", sigFile).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// The key is not an allowed signer
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to run %s: %w", program, err)
+	}
+
+	for _, principal := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if principal == "" {
+			continue
+		}
+		cmd := exec.Command(program, "-Y", "verify", "-f", allowedSigners, "-I", principal, "-n", "git", "-s", sigFile)
+		cmd.Stdin = bytes.NewReader(content)
+		if err := cmd.Run(); err == nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// commitPayload returns the encoded commit without its signature, which is what gets signed
+func commitPayload(commit *object.Commit) ([]byte, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return nil, fmt.Errorf("failed to encode commit: %w", err)
+	}
+
+	reader, err := encoded.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit: %w", err)
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// signatureFormat returns the format of a signature from its armor header
+func signatureFormat(signature string) string {
+	for header, format := range signatureHeaders {
+		if strings.HasPrefix(signature, header) {
+			return format
+		}
+	}
+	return ""
+}
+
+// literalSSHKey returns the public key of a user.signingkey given as "key::<key>" or
+// as the key itself, rather than as a key file
+func literalSSHKey(key string) (string, bool) {
+	if strings.HasPrefix(key, "key::") {
+		return strings.TrimPrefix(key, "key::"), true
+	}
+	if strings.HasPrefix(key, "ssh-") || strings.HasPrefix(key, "ecdsa-") || strings.HasPrefix(key, "sk-") {
+		return key, true
+	}
+	return "", false
+}
+
+// expandHome expands a leading ~/ in a configured path
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// commandOutput describes why a command failed, preferring what it printed
+func commandOutput(err error, stderr string) string {
+	if output := strings.TrimSpace(stderr); output != "" {
+		return output
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return "no signature was created"
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+)
+
+// LineRange selects the changed lines of a diff by line number.
+// Side "old" matches deleted lines by their old line number, side "new" (the default)
+// matches added lines by their new line number.
+type LineRange struct {
+	Side  string `json:"side"`  // "old" or "new"
+	Start int    `json:"start"` // First line of the range
+	End   int    `json:"end"`   // Last line of the range (inclusive)
+}
+
+// partialMode selects what a partial operation changes
+type partialMode int
+
+const (
+	partialStage   partialMode = iota // apply selected worktree changes to the index
+	partialUnstage                    // revert selected staged changes in the index
+	partialDiscard                    // revert selected unstaged changes in the worktree
+)
+
+// hunkRanges returns the line ranges covering all changes of a hunk
+func hunkRanges(hunk Hunk) []LineRange {
+	return []LineRange{
+		{Side: "old", Start: hunk.OldStart, End: hunk.OldStart + hunk.OldLines - 1},
+		{Side: "new", Start: hunk.NewStart, End: hunk.NewStart + hunk.NewLines - 1},
+	}
+}
+
+// StageHunk stages one hunk of the unstaged diff of a file, leaving the working tree untouched
+func (s *GitService) StageHunk(projectPath string, file string, hunk Hunk) error {
+	return s.applyPartial(projectPath, file, hunkRanges(hunk), partialStage)
+}
+
+// UnstageHunk removes one hunk of the staged diff of a file from the index
+func (s *GitService) UnstageHunk(projectPath string, file string, hunk Hunk) error {
+	return s.applyPartial(projectPath, file, hunkRanges(hunk), partialUnstage)
+}
+
+// DiscardHunk reverts one hunk of the unstaged diff of a file in the working tree
+func (s *GitService) DiscardHunk(projectPath string, file string, hunk Hunk) error {
+	return s.applyPartial(projectPath, file, hunkRanges(hunk), partialDiscard)
+}
+
+// StageLines stages the selected lines of the unstaged diff of a file
+func (s *GitService) StageLines(projectPath string, file string, ranges []LineRange) error {
+	return s.applyPartial(projectPath, file, ranges, partialStage)
+}
+
+// UnstageLines removes the selected lines of the staged diff of a file from the index
+func (s *GitService) UnstageLines(projectPath string, file string, ranges []LineRange) error {
+	return s.applyPartial(projectPath, file, ranges, partialUnstage)
+}
+
+// DiscardLines reverts the selected lines of the unstaged diff of a file in the working tree
+func (s *GitService) DiscardLines(projectPath string, file string, ranges []LineRange) error {
+	return s.applyPartial(projectPath, file, ranges, partialDiscard)
+}
+
+// applyPartial applies or reverts the selected changes of a file's diff.
+// The new content is built from the structured diff, so staging and unstaging only
+// write a new index blob while discarding only rewrites the working tree file.
+func (s *GitService) applyPartial(projectPath string, file string, ranges []LineRange, mode partialMode) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	var oldSide, newSide diffSide
+	if mode == partialUnstage {
+		oldSide, newSide, err = s.getStagedDiff(repo, file)
+	} else {
+		newSide, err = s.worktreeSide(worktree, file)
+		if err == nil {
+			oldSide, err = s.indexSide(repo, file)
+		}
+		if err == nil && !oldSide.exists {
+			oldSide, err = s.headSide(repo, file)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if isContentBinary([]byte(oldSide.content)) || isContentBinary([]byte(newSide.content)) {
+		return errors.New("partial changes are not supported for binary files")
+	}
+
+	ops := diffLines(oldSide.content, newSide.content)
+	selected := selectOps(ops, ranges)
+	if len(selected) == 0 {
+		return errors.New("no changed lines selected")
+	}
+
+	// Staging moves the old side towards the new one, the other modes move back
+	content := applyOps(ops, selected, mode == partialStage)
+
+	switch mode {
+	case partialStage:
+		// Staging everything of a deleted file removes it from the index
+		remove := !newSide.exists && content == ""
+		return s.writeIndexContent(repo, worktree, file, content, remove)
+	case partialUnstage:
+		// Unstaging everything of an added file removes it from the index
+		remove := !oldSide.exists && content == ""
+		return s.writeIndexContent(repo, worktree, file, content, remove)
+	default:
+		return s.writeWorktreeContent(repo, worktree, file, content, !oldSide.exists && content == "")
+	}
+}
+
+// selectOps returns the indexes of the edit script ops matched by the ranges
+func selectOps(ops []diffLineOp, ranges []LineRange) map[int]bool {
+	selected := make(map[int]bool)
+	oldLine, newLine := 0, 0
+	for i, op := range ops {
+		switch op.kind {
+		case '-':
+			oldLine++
+			if inRanges(ranges, "old", oldLine) {
+				selected[i] = true
+			}
+		case '+':
+			newLine++
+			if inRanges(ranges, "new", newLine) {
+				selected[i] = true
+			}
+		default:
+			oldLine++
+			newLine++
+		}
+	}
+	return selected
+}
+
+// inRanges reports whether a line on the given side is covered by any of the ranges
+func inRanges(ranges []LineRange, side string, line int) bool {
+	for _, r := range ranges {
+		rangeSide := r.Side
+		if rangeSide == "" {
+			rangeSide = "new"
+		}
+		if rangeSide == side && line >= r.Start && line <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// applyOps rebuilds file content from an edit script. Going forward, selected
+// changes are applied to the old side; going backward, selected changes are
+// reverted from the new side. Unselected changes stay as they were on the starting side.
+func applyOps(ops []diffLineOp, selected map[int]bool, forward bool) string {
+	var lines []diffLineOp
+	for i, op := range ops {
+		keep := true
+		switch op.kind {
+		case '+':
+			keep = selected[i] == forward
+		case '-':
+			keep = selected[i] != forward
+		}
+		if keep {
+			lines = append(lines, op)
+		}
+	}
+
+	var content strings.Builder
+	for i, line := range lines {
+		content.WriteString(line.text)
+		// Only the last line may lack its newline
+		if !line.noNewline || i < len(lines)-1 {
+			content.WriteString("\n")
+		}
+	}
+	return content.String()
+}
+
+// writeIndexContent stores content as a new blob and points the index entry of file at it
+func (s *GitService) writeIndexContent(repo *git.Repository, worktree *git.Worktree, file string, content string, remove bool) error {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	if remove {
+		if _, err := idx.Remove(file); err != nil && !errors.Is(err, index.ErrEntryNotFound) {
+			return fmt.Errorf("failed to remove index entry: %w", err)
+		}
+		return repo.Storer.SetIndex(idx)
+	}
+
+	hash, err := s.storeBlob(repo, []byte(content))
+	if err != nil {
+		return err
+	}
+
+	entry, err := idx.Entry(file)
+	if err != nil {
+		// New file, take the mode from the working tree
+		entry = idx.Add(file)
+		entry.Mode = filemode.Regular
+		if info, err := os.Lstat(filepath.Join(worktree.Filesystem.Root(), file)); err == nil {
+			if mode, err := filemode.NewFromOSFileMode(info.Mode()); err == nil {
+				entry.Mode = mode
+			}
+		}
+	}
+
+	entry.Hash = hash
+	entry.Size = uint32(len(content))
+	// Clear the stat data so the working tree file is compared by content again
+	entry.ModifiedAt = time.Time{}
+	entry.CreatedAt = time.Time{}
+
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	return nil
+}
+
+// writeWorktreeContent overwrites a working tree file, keeping its permissions
+func (s *GitService) writeWorktreeContent(repo *git.Repository, worktree *git.Worktree, file string, content string, remove bool) error {
+	fullPath := filepath.Join(worktree.Filesystem.Root(), file)
+
+	if remove {
+		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete file: %w", err)
+		}
+		return nil
+	}
+
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(fullPath); err == nil {
+		perm = info.Mode().Perm()
+	} else if idx, err := repo.Storer.Index(); err == nil {
+		// Restoring part of a deleted file, use the mode it had in the index
+		if entry, err := idx.Entry(file); err == nil {
+			if mode, err := entry.Mode.ToOSFileMode(); err == nil {
+				perm = mode.Perm()
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directories: %w", err)
+	}
+
+	if err := os.WriteFile(fullPath, []byte(content), perm); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestStageHunk stages one hunk and then single lines of a file and checks the index
+// with git diff --cached, and that the working tree keeps every change
+func TestStageHunk(t *testing.T) {
+	dir := initTestRepo(t)
+	var lines strings.Builder
+	for i := 1; i <= 40; i++ {
+		fmt.Fprintf(&lines, "line %d\n", i)
+	}
+	original := lines.String()
+	writeTestFile(t, dir, "f.txt", original)
+	commitAll(t, dir, "Add f")
+
+	changed := strings.Replace(original, "line 5\n", "line five\n", 1)
+	changed = strings.Replace(changed, "line 30\n", "line 30\nnew\n", 1)
+	writeTestFile(t, dir, "f.txt", changed)
+
+	s := NewGitService(nil)
+	diff, err := s.GetFileDiff(dir, "f.txt", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(diff.Hunks))
+	}
+	if err := s.StageHunk(dir, "f.txt", diff.Hunks[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if cached := changedLines(runGit(t, dir, "diff", "--cached", "-U0")); cached != "+new" {
+		t.Errorf("expected only +new to be staged, got %q", cached)
+	}
+	if unstaged := changedLines(runGit(t, dir, "diff", "-U0")); unstaged != "-line 5\n+line five" {
+		t.Errorf("expected line 5 to stay unstaged, got %q", unstaged)
+	}
+	if content := readTestFile(t, dir, "f.txt"); content != changed {
+		t.Errorf("the working tree changed:\n%s", content)
+	}
+
+	// Staging the added line alone stages an insertion before the old line 5
+	if err := s.StageLines(dir, "f.txt", []LineRange{{Side: "new", Start: 5, End: 5}}); err != nil {
+		t.Fatal(err)
+	}
+	if cached := changedLines(runGit(t, dir, "diff", "--cached", "-U0")); cached != "+line five\n+new" {
+		t.Errorf("expected +line five and +new to be staged, got %q", cached)
+	}
+	if unstaged := changedLines(runGit(t, dir, "diff", "-U0")); unstaged != "-line 5" {
+		t.Errorf("expected -line 5 to stay unstaged, got %q", unstaged)
+	}
+
+	staged, err := s.GetFileDiff(dir, "f.txt", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hunk := range staged.Hunks {
+		if err := s.UnstageHunk(dir, "f.txt", hunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cached := runGit(t, dir, "diff", "--cached"); cached != "" {
+		t.Errorf("expected nothing staged, got:\n%s", cached)
+	}
+}
+
+// changedLines returns the added and deleted lines of a diff, without the file headers
+func changedLines(diff string) string {
+	var lines []string
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+++") || strings.HasPrefix(line, "---") {
+			continue
+		}
+		if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
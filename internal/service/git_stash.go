@@ -0,0 +1,576 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// stashRef is the reference whose log holds the stash, newest entry last
+const stashRef plumbing.ReferenceName = "refs/stash"
+
+// ErrNoLocalChanges is returned when there is nothing to stash
+var ErrNoLocalChanges = errors.New("no local changes to save")
+
+// StashEntry represents one entry of the stash
+type StashEntry struct {
+	Index   int       `json:"index"`   // Position in the stash, 0 is the most recent (stash@{0})
+	Hash    string    `json:"hash"`    // Hash of the stash commit
+	Message string    `json:"message"` // Stash message, e.g. "WIP on main: 1a2b3c4 Fix typo"
+	Branch  string    `json:"branch"`  // Branch the changes were stashed on
+	Date    time.Time `json:"date"`    // When the changes were stashed
+}
+
+// StashOptions contains options for saving changes to the stash
+type StashOptions struct {
+	Message          string `json:"message"`          // Custom message, defaults to "WIP on <branch>: <commit>"
+	IncludeUntracked bool   `json:"includeUntracked"` // Also stash and remove untracked files
+}
+
+// StashSave records the local changes in a new stash entry and reverts them,
+// leaving a clean working tree. The entry is stored like `git stash` does: a commit
+// of the working tree whose parents are HEAD, a commit of the index and optionally
+// a commit of the untracked files.
+func (s *GitService) StashSave(projectPath string, opts StashOptions) (*StashEntry, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, errors.New("cannot stash changes without an initial commit")
+	}
+
+	headCommit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD commit: %w", err)
+	}
+
+	headTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD tree: %w", err)
+	}
+
+	indexEntries, err := s.indexEntries(repo)
+	if err != nil {
+		return nil, fmt.Errorf("cannot stash changes: %w", err)
+	}
+
+	status, err := s.status(repo, worktree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	// The working tree commit holds the index with the unstaged changes on top
+	worktreeEntries := make(map[string]object.TreeEntry, len(indexEntries))
+	for path, entry := range indexEntries {
+		worktreeEntries[path] = entry
+	}
+
+	var tracked, untracked []string
+	untrackedEntries := make(map[string]object.TreeEntry)
+	for path, fileStatus := range status {
+		if fileStatus.Worktree == git.Untracked && fileStatus.Staging == git.Untracked {
+			if opts.IncludeUntracked {
+				entry, err := s.storeWorktreeFile(repo, worktree, path)
+				if err != nil {
+					return nil, err
+				}
+				untrackedEntries[path] = entry
+				untracked = append(untracked, path)
+			}
+			continue
+		}
+
+		if fileStatus.Staging == git.Unmodified && fileStatus.Worktree == git.Unmodified {
+			continue
+		}
+		tracked = append(tracked, path)
+
+		// A file removed from the index but kept on disk, as git rm --cached does, is
+		// untracked in the working tree and saved with it like a modified file
+		switch fileStatus.Worktree {
+		case git.Deleted:
+			delete(worktreeEntries, path)
+		case git.Modified, git.Untracked:
+			entry, err := s.storeWorktreeFile(repo, worktree, path)
+			if err != nil {
+				return nil, err
+			}
+			worktreeEntries[path] = entry
+		}
+	}
+
+	if len(tracked) == 0 && len(untracked) == 0 {
+		return nil, ErrNoLocalChanges
+	}
+
+	sig, err := s.signature(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := "(no branch)"
+	if head.Name().IsBranch() {
+		branch = head.Name().Short()
+	}
+	summary := fmt.Sprintf("%s: %s %s", branch, head.Hash().String()[:7], firstLine(headCommit.Message))
+
+	indexTree, err := s.buildTree(repo, indexEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	indexCommit, err := s.storeCommit(repo, &object.Commit{
+		Author:       *sig,
+		Committer:    *sig,
+		Message:      "index on " + summary + "\n",
+		TreeHash:     indexTree,
+		ParentHashes: []plumbing.Hash{head.Hash()},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	parents := []plumbing.Hash{head.Hash(), indexCommit}
+	if len(untracked) > 0 {
+		untrackedTree, err := s.buildTree(repo, untrackedEntries)
+		if err != nil {
+			return nil, err
+		}
+
+		untrackedCommit, err := s.storeCommit(repo, &object.Commit{
+			Author:    *sig,
+			Committer: *sig,
+			Message:   "untracked files on " + summary + "\n",
+			TreeHash:  untrackedTree,
+		})
+		if err != nil {
+			return nil, err
+		}
+		parents = append(parents, untrackedCommit)
+	}
+
+	message := "WIP on " + summary
+	if opts.Message != "" {
+		message = fmt.Sprintf("On %s: %s", branch, firstLine(opts.Message))
+	}
+
+	worktreeTree, err := s.buildTree(repo, worktreeEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	stashCommit, err := s.storeCommit(repo, &object.Commit{
+		Author:       *sig,
+		Committer:    *sig,
+		Message:      message + "\n",
+		TreeHash:     worktreeTree,
+		ParentHashes: parents,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	previous := plumbing.ZeroHash
+	if ref, err := repo.Reference(stashRef, false); err == nil {
+		previous = ref.Hash()
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(stashRef, stashCommit)); err != nil {
+		return nil, fmt.Errorf("failed to update stash: %w", err)
+	}
+
+	err = s.appendReflog(repo, stashRef, reflogEntry{
+		Old:       previous,
+		New:       stashCommit,
+		Committer: *sig,
+		Message:   message,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The changes are safe in the stash now, revert them
+	for _, path := range tracked {
+		var entry *object.TreeEntry
+		if headEntry, err := headTree.FindEntry(path); err == nil {
+			entry = headEntry
+		}
+		if err := s.writeTreeEntry(repo, worktree, path, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range untracked {
+		if err := s.removeWorktreeFile(worktree, path); err != nil {
+			return nil, err
+		}
+	}
+
+	return &StashEntry{
+		Index:   0,
+		Hash:    stashCommit.String(),
+		Message: message,
+		Branch:  branch,
+		Date:    sig.When,
+	}, nil
+}
+
+// StashList returns the stash entries, most recent first
+func (s *GitService) StashList(projectPath string) ([]StashEntry, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.readReflog(repo, stashRef)
+	if err != nil {
+		return nil, err
+	}
+
+	stashes := make([]StashEntry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		stashes = append(stashes, StashEntry{
+			Index:   len(stashes),
+			Hash:    entry.New.String(),
+			Message: entry.Message,
+			Branch:  stashBranch(entry.Message),
+			Date:    entry.Committer.When,
+		})
+	}
+
+	return stashes, nil
+}
+
+// StashApply restores the changes of a stash entry on top of the current working tree.
+// Like `git stash apply`, changes to tracked files are left unstaged and new files staged.
+func (s *GitService) StashApply(projectPath string, index int) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	return s.applyStash(repo, index)
+}
+
+// StashPop applies a stash entry and drops it once it applied cleanly
+func (s *GitService) StashPop(projectPath string, index int) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	if err := s.applyStash(repo, index); err != nil {
+		return err
+	}
+
+	return s.dropStash(repo, index)
+}
+
+// StashDrop removes an entry from the stash
+func (s *GitService) StashDrop(projectPath string, index int) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	return s.dropStash(repo, index)
+}
+
+// StashShow returns the diff of every file changed by a stash entry,
+// including the untracked files it holds
+func (s *GitService) StashShow(projectPath string, index int) ([]FileDiff, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	stash, err := s.stashCommit(repo, index)
+	if err != nil {
+		return nil, err
+	}
+
+	baseTree, stashTree, untrackedTree, err := s.stashTrees(stash)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs, err := s.diffTrees(repo, baseTree, stashTree, DiffOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	if untrackedTree != nil {
+		untrackedDiffs, err := s.diffTrees(repo, nil, untrackedTree, DiffOptions{})
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, untrackedDiffs...)
+		sort.Slice(diffs, func(i, j int) bool {
+			return diffs[i].Path < diffs[j].Path
+		})
+	}
+
+	return diffs, nil
+}
+
+// stashCommit returns the commit of stash@{index}
+func (s *GitService) stashCommit(repo *git.Repository, index int) (*object.Commit, error) {
+	entries, err := s.readReflog(repo, stashRef)
+	if err != nil {
+		return nil, err
+	}
+
+	if index < 0 || index >= len(entries) {
+		return nil, fmt.Errorf("stash@{%d} does not exist", index)
+	}
+
+	commit, err := repo.CommitObject(entries[len(entries)-1-index].New)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stash commit: %w", err)
+	}
+
+	return commit, nil
+}
+
+// stashTrees returns the trees of the commit a stash was made on, of the stashed
+// working tree and of the stashed untracked files (nil if there are none)
+func (s *GitService) stashTrees(stash *object.Commit) (base, stashed, untracked *object.Tree, err error) {
+	if stash.NumParents() < 2 {
+		return nil, nil, nil, fmt.Errorf("%s is not a stash commit", stash.Hash)
+	}
+
+	baseCommit, err := stash.Parent(0)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get stash base commit: %w", err)
+	}
+
+	if base, err = baseCommit.Tree(); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get stash base tree: %w", err)
+	}
+
+	if stashed, err = stash.Tree(); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get stash tree: %w", err)
+	}
+
+	if stash.NumParents() > 2 {
+		untrackedCommit, err := stash.Parent(2)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to get untracked files commit: %w", err)
+		}
+		if untracked, err = untrackedCommit.Tree(); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to get untracked files tree: %w", err)
+		}
+	}
+
+	return base, stashed, untracked, nil
+}
+
+// applyStash merges the changes of stash@{index} into the working tree.
+// Nothing is written when a stashed change conflicts with HEAD or with local changes.
+func (s *GitService) applyStash(repo *git.Repository, index int) error {
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	stash, err := s.stashCommit(repo, index)
+	if err != nil {
+		return err
+	}
+
+	baseTree, stashTree, untrackedTree, err := s.stashTrees(stash)
+	if err != nil {
+		return err
+	}
+
+	headTree, err := s.headTree(repo)
+	if err != nil {
+		return err
+	}
+
+	label := fmt.Sprintf("stash@{%d}", index)
+	result, err := s.mergeTrees(repo, worktree, baseTree, headTree, stashTree, mergeLabels{ours: "Updated upstream", theirs: label})
+	if err != nil {
+		return err
+	}
+	if len(result.conflicts) > 0 {
+		return &MergeConflictError{Files: result.conflictPaths()}
+	}
+	updates := result.updates
+
+	// Untracked files are restored as they were, but never over existing files
+	var untracked []object.TreeEntry
+	if untrackedTree != nil {
+		var existing []string
+		files := untrackedTree.Files()
+		err := files.ForEach(func(file *object.File) error {
+			if _, err := os.Lstat(filepath.Join(worktree.Filesystem.Root(), file.Name)); err == nil {
+				existing = append(existing, file.Name)
+			}
+			untracked = append(untracked, object.TreeEntry{Name: file.Name, Mode: file.Mode, Hash: file.Hash})
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read untracked files: %w", err)
+		}
+		if len(existing) > 0 {
+			return &CheckoutConflictError{Branch: label, Files: existing}
+		}
+	}
+
+	for path, entry := range updates {
+		if err := s.writeTreeEntry(repo, worktree, path, entry); err != nil {
+			return err
+		}
+	}
+
+	// Leave changes to files known to HEAD unstaged
+	if err := s.resetIndexEntries(repo, headTree, updates); err != nil {
+		return err
+	}
+
+	for i := range untracked {
+		entry := &untracked[i]
+		if err := s.writeEntryFile(repo, filepath.Join(worktree.Filesystem.Root(), entry.Name), entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resetIndexEntries points the index entries of the given paths back at their
+// version in tree. Paths missing from tree keep their index entry.
+func (s *GitService) resetIndexEntries(repo *git.Repository, tree *object.Tree, paths map[string]*object.TreeEntry) error {
+	if tree == nil {
+		return nil
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	for path := range paths {
+		treeEntry, err := tree.FindEntry(path)
+		if err != nil {
+			continue
+		}
+
+		blob, err := repo.BlobObject(treeEntry.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to get blob object: %w", err)
+		}
+
+		entry, err := idx.Entry(path)
+		if err != nil {
+			entry = idx.Add(path)
+		}
+		entry.Hash = treeEntry.Hash
+		entry.Mode = treeEntry.Mode
+		entry.Size = uint32(blob.Size)
+		// Clear the stat data so the working tree file is compared by content again
+		entry.ModifiedAt = time.Time{}
+		entry.CreatedAt = time.Time{}
+	}
+
+	if err := repo.Storer.SetIndex(idx); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	return nil
+}
+
+// dropStash removes stash@{index} from the stash reflog, deleting refs/stash with the last entry
+func (s *GitService) dropStash(repo *git.Repository, index int) error {
+	entries, err := s.readReflog(repo, stashRef)
+	if err != nil {
+		return err
+	}
+
+	if index < 0 || index >= len(entries) {
+		return fmt.Errorf("stash@{%d} does not exist", index)
+	}
+
+	pos := len(entries) - 1 - index
+	entries = append(entries[:pos], entries[pos+1:]...)
+
+	// Keep the log chained, the entry after the dropped one now follows the one before it
+	if pos < len(entries) {
+		entries[pos].Old = plumbing.ZeroHash
+		if pos > 0 {
+			entries[pos].Old = entries[pos-1].New
+		}
+	}
+
+	if err := s.writeReflog(repo, stashRef, entries); err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		if err := repo.Storer.RemoveReference(stashRef); err != nil {
+			return fmt.Errorf("failed to delete stash: %w", err)
+		}
+		return nil
+	}
+
+	top := entries[len(entries)-1].New
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(stashRef, top)); err != nil {
+		return fmt.Errorf("failed to update stash: %w", err)
+	}
+
+	return nil
+}
+
+// removeWorktreeFile deletes a file and the directories it leaves empty
+func (s *GitService) removeWorktreeFile(worktree *git.Worktree, path string) error {
+	root := worktree.Filesystem.Root()
+	fullPath := filepath.Join(root, path)
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete %s: %w", path, err)
+	}
+
+	for dir := filepath.Dir(fullPath); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			// Not empty
+			break
+		}
+	}
+
+	return nil
+}
+
+// stashBranch returns the branch named in a stash message such as "WIP on main: ..."
+func stashBranch(message string) string {
+	rest, ok := strings.CutPrefix(message, "WIP on ")
+	if !ok {
+		if rest, ok = strings.CutPrefix(message, "On "); !ok {
+			return ""
+		}
+	}
+	branch, _, _ := strings.Cut(rest, ":")
+	return branch
+}
+
+// firstLine returns the first line of a commit message
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return strings.TrimSpace(line)
+}
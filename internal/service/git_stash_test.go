@@ -0,0 +1,146 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// createStashRepo creates a repository with staged, unstaged, deleted and untracked changes
+func createStashRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := initTestRepo(t)
+	writeTestFile(t, dir, "a.txt", "a\n")
+	writeTestFile(t, dir, "d/b.txt", "b\n")
+	writeTestFile(t, dir, "c.txt", "c\n")
+	commitAll(t, dir, "Add files")
+
+	writeTestFile(t, dir, "a.txt", "a changed\n")
+	writeTestFile(t, dir, "new.txt", "new\n")
+	runGit(t, dir, "add", "new.txt")
+	writeTestFile(t, dir, "d/b.txt", "b staged\n")
+	runGit(t, dir, "add", "d/b.txt")
+	writeTestFile(t, dir, "d/b.txt", "b staged and changed\n")
+	if err := os.Remove(filepath.Join(dir, "c.txt")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, dir, "u/untracked.txt", "untracked\n")
+	return dir
+}
+
+// TestStashSavePop stashes and pops changes and checks the repository against
+// the same stash made and popped with git
+func TestStashSavePop(t *testing.T) {
+	dir := createStashRepo(t)
+	expected := createStashRepo(t)
+	before := runGit(t, dir, "status", "--porcelain")
+
+	s := NewGitService(nil)
+	entry, err := s.StashSave(dir, StashOptions{Message: "my work", IncludeUntracked: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, expected, "stash", "push", "-q", "--include-untracked", "-m", "my work")
+
+	if status := runGit(t, dir, "status", "--porcelain"); status != "" {
+		t.Errorf("expected a clean status after stashing, got:\n%s", status)
+	}
+	if list := runGit(t, dir, "stash", "list"); list != runGit(t, expected, "stash", "list") {
+		t.Errorf("unexpected stash list:\n%s", list)
+	}
+	if !strings.HasPrefix(runGit(t, dir, "rev-parse", "stash@{0}"), entry.Hash) {
+		t.Errorf("stash@{0} is not %s", entry.Hash)
+	}
+	for _, args := range [][]string{
+		{"stash", "show", "--name-status"},
+		{"stash", "show", "-p", "--include-untracked"},
+		{"rev-parse", "stash@{0}^{tree}", "stash@{0}^2^{tree}", "stash@{0}^3^{tree}"},
+	} {
+		if got, want := runGit(t, dir, args...), runGit(t, expected, args...); got != want {
+			t.Errorf("git %s differs from git's stash:\n%s\nexpected:\n%s", strings.Join(args, " "), got, want)
+		}
+	}
+	runGit(t, dir, "fsck", "--no-progress")
+
+	if err := s.StashPop(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, expected, "stash", "pop", "-q")
+
+	if list := runGit(t, dir, "stash", "list"); list != "" {
+		t.Errorf("expected an empty stash after popping, got:\n%s", list)
+	}
+	status := runGit(t, dir, "status", "--porcelain")
+	if want := runGit(t, expected, "status", "--porcelain"); status != want {
+		t.Errorf("unexpected status after popping:\n%s\nexpected:\n%s\nbefore stashing:\n%s", status, want, before)
+	}
+	if got, want := runGit(t, dir, "diff", "HEAD"), runGit(t, expected, "diff", "HEAD"); got != want {
+		t.Errorf("unexpected changes after popping:\n%s\nexpected:\n%s", got, want)
+	}
+}
+
+// TestStashApplyGitStash applies a stash made by git
+func TestStashApplyGitStash(t *testing.T) {
+	dir := createStashRepo(t)
+	expected := createStashRepo(t)
+	runGit(t, dir, "stash", "push", "-q", "--include-untracked")
+	runGit(t, expected, "stash", "push", "-q", "--include-untracked")
+
+	s := NewGitService(nil)
+	stashes, err := s.StashList(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stashes) != 1 || stashes[0].Branch != "main" {
+		t.Fatalf("unexpected stash list: %+v", stashes)
+	}
+
+	if err := s.StashApply(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, expected, "stash", "apply", "-q")
+
+	if got, want := runGit(t, dir, "status", "--porcelain"), runGit(t, expected, "status", "--porcelain"); got != want {
+		t.Errorf("unexpected status after applying:\n%s\nexpected:\n%s", got, want)
+	}
+	if list := runGit(t, dir, "stash", "list"); !strings.Contains(list, "stash@{0}") {
+		t.Errorf("apply dropped the stash:\n%s", list)
+	}
+}
+
+// TestStashRemovedFromIndex stashes a file removed from the index but kept on disk,
+// which git keeps in the working tree as an untracked file
+func TestStashRemovedFromIndex(t *testing.T) {
+	setup := func() string {
+		dir := initTestRepo(t)
+		writeTestFile(t, dir, "a.txt", "a\n")
+		writeTestFile(t, dir, "cached.txt", "cached\n")
+		commitAll(t, dir, "Add files")
+		writeTestFile(t, dir, "a.txt", "a changed\n")
+		runGit(t, dir, "rm", "-q", "--cached", "cached.txt")
+		return dir
+	}
+	dir := setup()
+	expected := setup()
+
+	s := NewGitService(nil)
+	if _, err := s.StashSave(dir, StashOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, expected, "stash", "push", "-q")
+
+	for _, args := range [][]string{
+		{"status", "--porcelain"},
+		{"stash", "show", "--name-status"},
+		{"rev-parse", "stash@{0}^{tree}", "stash@{0}^2^{tree}"},
+	} {
+		if got, want := runGit(t, dir, args...), runGit(t, expected, args...); got != want {
+			t.Errorf("git %s differs from git's stash:\n%s\nexpected:\n%s", strings.Join(args, " "), got, want)
+		}
+	}
+	if content := readTestFile(t, dir, "cached.txt"); content != "cached\n" {
+		t.Errorf("cached.txt changed on disk: %q", content)
+	}
+}
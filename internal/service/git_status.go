@@ -0,0 +1,476 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// status computes the status of the worktree like worktree.Status, but without hashing
+// every file: tracked files whose size and modification time match the index are taken
+// as unchanged, and gitignored directories are not walked. Only changed files are listed.
+func (s *GitService) status(repo *git.Repository, worktree *git.Worktree) (git.Status, error) {
+	idx, indexTime, err := s.readIndex(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns, err := s.excludePatterns(repo, worktree)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make(git.Status)
+	tracked := make(map[string]bool, len(idx.Entries))
+	var entries []*index.Entry
+	for _, entry := range idx.Entries {
+		if tracked[entry.Name] {
+			continue
+		}
+		tracked[entry.Name] = true
+
+		// Unmerged files are changed in every way until they are resolved
+		if entry.Stage != 0 {
+			status[entry.Name] = &git.FileStatus{Staging: git.Modified, Worktree: git.Modified}
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	// Reading HEAD and looking for untracked files run alongside the stat calls
+	root := worktree.Filesystem.Root()
+	var wg sync.WaitGroup
+	var headEntries map[string]object.TreeEntry
+	var untracked []string
+	var headErr, walkErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		headEntries, headErr = s.headEntries(repo)
+	}()
+	go func() {
+		defer wg.Done()
+		walkErr = s.walkUntracked(root, nil, patterns, tracked, func(name string) {
+			untracked = append(untracked, name)
+		})
+	}()
+
+	codes, err := s.worktreeCodes(root, entries, indexTime)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if headErr != nil {
+		return nil, headErr
+	}
+	if walkErr != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %w", walkErr)
+	}
+
+	for i, entry := range entries {
+		staging, worktreeCode := git.Unmodified, codes[i]
+		if head, ok := headEntries[entry.Name]; !ok {
+			staging = git.Added
+		} else if head.Hash != entry.Hash || head.Mode != entry.Mode {
+			staging = git.Modified
+		}
+		if staging != git.Unmodified || worktreeCode != git.Unmodified {
+			status[entry.Name] = &git.FileStatus{Staging: staging, Worktree: worktreeCode}
+		}
+	}
+
+	for name := range headEntries {
+		if !tracked[name] {
+			status[name] = &git.FileStatus{Staging: git.Deleted, Worktree: git.Unmodified}
+		}
+	}
+
+	for _, name := range untracked {
+		// A file deleted from the index but still on disk is both staged and untracked
+		if fileStatus, ok := status[name]; ok {
+			fileStatus.Worktree = git.Untracked
+			continue
+		}
+		status[name] = &git.FileStatus{Staging: git.Untracked, Worktree: git.Untracked}
+	}
+
+	return status, nil
+}
+
+// fileStatus returns the status of a single file without computing the status of the
+// whole worktree. An unchanged file has both codes set to git.Unmodified.
+func (s *GitService) fileStatus(repo *git.Repository, worktree *git.Worktree, file string) (*git.FileStatus, error) {
+	idx, indexTime, err := s.readIndex(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *index.Entry
+	for _, e := range idx.Entries {
+		if e.Name != file {
+			continue
+		}
+		if e.Stage != 0 {
+			return &git.FileStatus{Staging: git.Modified, Worktree: git.Modified}, nil
+		}
+		entry = e
+	}
+
+	tree, err := s.headTree(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var head *object.TreeEntry
+	if tree != nil {
+		if head, err = tree.FindEntry(file); err != nil {
+			if !errors.Is(err, object.ErrEntryNotFound) && !errors.Is(err, object.ErrDirectoryNotFound) {
+				return nil, fmt.Errorf("failed to find %s: %w", file, err)
+			}
+			head = nil
+		}
+	}
+
+	fileStatus := &git.FileStatus{Staging: git.Unmodified, Worktree: git.Unmodified}
+	fullPath := filepath.Join(worktree.Filesystem.Root(), filepath.FromSlash(file))
+
+	if entry == nil {
+		if head != nil {
+			fileStatus.Staging = git.Deleted
+			return fileStatus, nil
+		}
+		if info, err := os.Lstat(fullPath); err == nil && !info.IsDir() {
+			return &git.FileStatus{Staging: git.Untracked, Worktree: git.Untracked}, nil
+		}
+		return fileStatus, nil
+	}
+
+	if head == nil {
+		fileStatus.Staging = git.Added
+	} else if head.Hash != entry.Hash || head.Mode != entry.Mode {
+		fileStatus.Staging = git.Modified
+	}
+
+	if fileStatus.Worktree, err = s.worktreeCode(fullPath, entry, indexTime); err != nil {
+		return nil, err
+	}
+
+	return fileStatus, nil
+}
+
+// headEntries returns the files of the HEAD tree by path, none on an unborn branch
+func (s *GitService) headEntries(repo *git.Repository) (map[string]object.TreeEntry, error) {
+	entries := make(map[string]object.TreeEntry)
+
+	tree, err := s.headTree(repo)
+	if err != nil || tree == nil {
+		return entries, err
+	}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk HEAD tree: %w", err)
+		}
+		if entry.Mode != filemode.Dir {
+			entries[name] = entry
+		}
+	}
+
+	return entries, nil
+}
+
+// readIndex returns the index of a repository and when it was last written, which
+// tells which stat data in it can be trusted. The decoded index is cached until the
+// index file changes, so it must not be modified; use repo.Storer.Index to update it.
+// Two writes can leave the same modification time and size, so the checksum the file
+// ends with is compared too.
+func (s *GitService) readIndex(repo *git.Repository) (*index.Index, time.Time, error) {
+	dir, err := s.gitDir(repo)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	file := filepath.Join(dir, "index")
+	info, err := os.Stat(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, time.Time{}, fmt.Errorf("failed to stat index: %w", err)
+	}
+
+	var modTime time.Time
+	var size int64
+	if info != nil {
+		modTime, size = info.ModTime(), info.Size()
+	}
+
+	// Read before the index so a write in between only makes the next call read it again
+	checksum, err := indexChecksum(file, size)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	s.indexesMu.Lock()
+	cached, ok := s.indexes[file]
+	s.indexesMu.Unlock()
+	if ok && cached.modTime.Equal(modTime) && cached.size == size && cached.checksum == checksum {
+		return cached.idx, modTime, nil
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to get index: %w", err)
+	}
+
+	s.indexesMu.Lock()
+	s.indexes[file] = &cachedIndex{idx: idx, modTime: modTime, size: size, checksum: checksum}
+	s.indexesMu.Unlock()
+
+	return idx, modTime, nil
+}
+
+// indexChecksum reads the checksum an index file ends with, none when there is no index
+func indexChecksum(file string, size int64) (plumbing.Hash, error) {
+	var checksum plumbing.Hash
+	if size < int64(len(checksum)) {
+		return checksum, nil
+	}
+
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return checksum, nil
+	}
+	if err != nil {
+		return checksum, fmt.Errorf("failed to open index: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.ReadAt(checksum[:], size-int64(len(checksum))); err != nil {
+		return checksum, fmt.Errorf("failed to read index checksum: %w", err)
+	}
+	return checksum, nil
+}
+
+// worktreeCodes compares the tracked files to the working tree in parallel
+func (s *GitService) worktreeCodes(root string, entries []*index.Entry, indexTime time.Time) ([]git.StatusCode, error) {
+	codes := make([]git.StatusCode, len(entries))
+	errs := make([]error, len(entries))
+
+	workers := runtime.NumCPU()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(entries); i += workers {
+				fullPath := filepath.Join(root, filepath.FromSlash(entries[i].Name))
+				codes[i], errs[i] = s.worktreeCode(fullPath, entries[i], indexTime)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// worktreeCode compares a tracked file to its index entry. The content is only hashed
+// when the stat data recorded in the index doesn't match, or can't be trusted because
+// the file changed in the same instant the index was written.
+func (s *GitService) worktreeCode(fullPath string, entry *index.Entry, indexTime time.Time) (git.StatusCode, error) {
+	// Submodules are compared by their checkout
+	if entry.Mode == filemode.Submodule {
+		return s.submoduleCode(fullPath, entry.Hash)
+	}
+
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) || errors.Is(err, fs.ErrNotExist) {
+			return git.Deleted, nil
+		}
+		return git.Unmodified, fmt.Errorf("failed to stat %s: %w", entry.Name, err)
+	}
+	if info.IsDir() {
+		return git.Deleted, nil
+	}
+
+	mode, err := filemode.NewFromOSFileMode(info.Mode())
+	if err != nil {
+		return git.Modified, nil
+	}
+	if mode != entry.Mode {
+		return git.Modified, nil
+	}
+
+	if entry.Size == uint32(info.Size()) && entry.ModifiedAt.Equal(info.ModTime()) && info.ModTime().Before(indexTime) {
+		return git.Unmodified, nil
+	}
+
+	hash, err := hashWorktreeFile(fullPath, info)
+	if err != nil {
+		return git.Unmodified, fmt.Errorf("failed to hash %s: %w", entry.Name, err)
+	}
+	if hash != entry.Hash {
+		return git.Modified, nil
+	}
+
+	return git.Unmodified, nil
+}
+
+// hashWorktreeFile returns the blob hash of a file, or of the target of a symlink
+func hashWorktreeFile(fullPath string, info os.FileInfo) (plumbing.Hash, error) {
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(fullPath)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return plumbing.ComputeHash(plumbing.BlobObject, []byte(filepath.ToSlash(target))), nil
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer file.Close()
+
+	hasher := plumbing.NewHasher(plumbing.BlobObject, info.Size())
+	if _, err := io.Copy(hasher, file); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return hasher.Sum(), nil
+}
+
+// excludePatterns returns the ignore rules that apply to the whole worktree: the global
+// excludes file, .git/info/exclude and the worktree's own excludes. The .gitignore
+// files are read while walking.
+func (s *GitService) excludePatterns(repo *git.Repository, worktree *git.Worktree) ([]gitignore.Pattern, error) {
+	patterns, err := gitignore.LoadGlobalPatterns(osfs.New("/"))
+	if err != nil {
+		// A broken global config shouldn't prevent showing the status
+		patterns = nil
+	}
+
+	dir, err := s.commonDir(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := readIgnorePatterns(filepath.Join(dir, "info", "exclude"), nil)
+	if err != nil {
+		return nil, err
+	}
+	patterns = append(patterns, exclude...)
+
+	return append(patterns, worktree.Excludes...), nil
+}
+
+// readIgnorePatterns parses an ignore file whose rules apply below domain, no rules if it doesn't exist
+func readIgnorePatterns(file string, domain []string) ([]gitignore.Pattern, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	defer f.Close()
+
+	var patterns []gitignore.Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") && len(strings.TrimSpace(line)) > 0 {
+			patterns = append(patterns, gitignore.ParsePattern(line, domain))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	return patterns, nil
+}
+
+// walkUntracked reports the files below dir that are neither tracked nor ignored.
+// Ignored directories and nested repositories are not entered.
+func (s *GitService) walkUntracked(root string, dir []string, patterns []gitignore.Pattern, tracked map[string]bool, report func(name string)) error {
+	fullDir := filepath.Join(append([]string{root}, dir...)...)
+
+	local, err := readIgnorePatterns(filepath.Join(fullDir, ".gitignore"), dir)
+	if err != nil {
+		return err
+	}
+	if len(local) > 0 {
+		patterns = append(patterns[:len(patterns):len(patterns)], local...)
+	}
+	matcher := gitignore.NewMatcher(patterns)
+
+	children, err := os.ReadDir(fullDir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].Name() < children[j].Name()
+	})
+
+	for _, child := range children {
+		if child.Name() == ".git" {
+			continue
+		}
+
+		parts := append(dir[:len(dir):len(dir)], child.Name())
+		name := path.Join(parts...)
+		isDir := child.IsDir()
+
+		if tracked[name] {
+			continue
+		}
+		if matcher.Match(parts, isDir) {
+			continue
+		}
+
+		if !isDir {
+			report(name)
+			continue
+		}
+
+		// Repositories inside the worktree manage their own files
+		if _, err := os.Lstat(filepath.Join(fullDir, child.Name(), ".git")); err == nil {
+			continue
+		}
+
+		if err := s.walkUntracked(root, parts, patterns, tracked, report); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
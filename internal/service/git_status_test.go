@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// benchmarkFiles is how many files the repository of BenchmarkGetStatus tracks
+const benchmarkFiles = 50000
+
+// BenchmarkGetStatus gets the status of a repository with many committed files, a few of
+// them modified, deleted or untracked, and an ignored directory.
+// The target is under 500ms per status on a single core, most of it one lstat per tracked
+// file; the files are compared in parallel, so more cores take less.
+func BenchmarkGetStatus(b *testing.B) {
+	dir := b.TempDir()
+	createStatusRepo(b, dir, benchmarkFiles)
+
+	s := NewGitService(nil)
+	if _, err := s.GetStatus(dir); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		statuses, err := s.GetStatus(dir)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(statuses) != 30 {
+			b.Fatalf("expected 30 changed files, got %d", len(statuses))
+		}
+	}
+}
+
+// createStatusRepo creates a repository with a commit of the given number of files spread
+// over directories, then changes 10 of them, deletes 10 and adds 10 untracked files
+func createStatusRepo(b *testing.B, dir string, files int) {
+	b.Helper()
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	s := NewGitService(nil)
+	idx := &index.Index{Version: 2}
+	entries := make(map[string]object.TreeEntry, files)
+	for i := 0; i < files; i++ {
+		name := fmt.Sprintf("pkg%03d/sub%02d/file%05d.go", i%500, i%20, i)
+		content := []byte(fmt.Sprintf("package pkg%03d\n\n// File %d\n", i%500, i))
+
+		fullPath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			b.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, content, 0644); err != nil {
+			b.Fatal(err)
+		}
+		info, err := os.Lstat(fullPath)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		hash, err := s.storeBlob(repo, content)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		entries[name] = object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: hash}
+		idx.Entries = append(idx.Entries, &index.Entry{
+			Name:       name,
+			Hash:       hash,
+			Mode:       filemode.Regular,
+			Size:       uint32(info.Size()),
+			ModifiedAt: info.ModTime(),
+		})
+	}
+
+	// The stat data of files written in the same instant as the index can't be trusted
+	time.Sleep(10 * time.Millisecond)
+	if err := s.setIndex(repo, idx); err != nil {
+		b.Fatal(err)
+	}
+
+	tree, err := s.buildTree(repo, entries)
+	if err != nil {
+		b.Fatal(err)
+	}
+	signature := object.Signature{Name: "Bench", Email: "bench@example.com", When: time.Now()}
+	commit, err := s.storeCommit(repo, &object.Commit{
+		Author:    signature,
+		Committer: signature,
+		Message:   "Add files\n",
+		TreeHash:  tree,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.Master, commit)); err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		name := filepath.FromSlash(idx.Entries[i*7].Name)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("changed\n"), 0644); err != nil {
+			b.Fatal(err)
+		}
+		name = filepath.FromSlash(idx.Entries[i*7+1].Name)
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			b.Fatal(err)
+		}
+		name = fmt.Sprintf("pkg%03d/new%d.go", i, i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package new\n"), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".git", "info"), 0755); err != nil {
+		b.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".git", "info", "exclude"), []byte("/build/\n"), 0644); err != nil {
+		b.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "build"), 0755); err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		name := filepath.Join(dir, "build", fmt.Sprintf("out%04d.o", i))
+		if err := os.WriteFile(name, []byte("object\n"), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
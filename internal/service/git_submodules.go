@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+)
+
+// SubmoduleInfo describes a submodule of a project and the state of its checkout
+type SubmoduleInfo struct {
+	Name        string `json:"name"`        // Name in .gitmodules
+	Path        string `json:"path"`        // Path relative to the project, open it as a project for its own status and diffs
+	URL         string `json:"url"`         // Repository the submodule is cloned from
+	Branch      string `json:"branch"`      // Branch to follow, if .gitmodules sets one
+	Commit      string `json:"commit"`      // Commit the project records for the submodule in its index
+	Head        string `json:"head"`        // Commit checked out in the submodule, empty when not initialized
+	Initialized bool   `json:"initialized"` // Whether the submodule is cloned and checked out
+	Modified    bool   `json:"modified"`    // Whether another commit than the recorded one is checked out
+	Dirty       bool   `json:"dirty"`       // Whether the submodule has uncommitted changes or untracked files
+}
+
+// submoduleState is what a submodule has checked out
+type submoduleState struct {
+	initialized bool
+	head        plumbing.Hash
+	dirty       bool
+}
+
+// ListSubmodules returns the submodules of a project: those in .gitmodules and any other
+// repository the index records as a submodule, ordered by path
+func (s *GitService) ListSubmodules(projectPath string) ([]SubmoduleInfo, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	root := worktree.Filesystem.Root()
+
+	modules := config.NewModules()
+	if data, err := os.ReadFile(filepath.Join(root, ".gitmodules")); err == nil {
+		if err := modules.Unmarshal(data); err != nil {
+			return nil, fmt.Errorf("failed to parse .gitmodules: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read .gitmodules: %w", err)
+	}
+
+	byPath := make(map[string]*SubmoduleInfo)
+	for _, module := range modules.Submodules {
+		byPath[module.Path] = &SubmoduleInfo{
+			Name:   module.Name,
+			Path:   module.Path,
+			URL:    module.URL,
+			Branch: module.Branch,
+		}
+	}
+
+	idx, _, err := s.readIndex(repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range idx.Entries {
+		if entry.Mode != filemode.Submodule || entry.Stage != 0 {
+			continue
+		}
+		info, ok := byPath[entry.Name]
+		if !ok {
+			info = &SubmoduleInfo{Name: entry.Name, Path: entry.Name}
+			byPath[entry.Name] = info
+		}
+		info.Commit = entry.Hash.String()
+	}
+
+	submodules := make([]SubmoduleInfo, 0, len(byPath))
+	for _, info := range byPath {
+		state, err := s.submoduleState(filepath.Join(root, filepath.FromSlash(info.Path)))
+		if err != nil {
+			return nil, err
+		}
+
+		info.Initialized = state.initialized
+		if state.initialized {
+			info.Dirty = state.dirty
+			if !state.head.IsZero() {
+				info.Head = state.head.String()
+			}
+			info.Modified = info.Head != info.Commit
+		}
+		submodules = append(submodules, *info)
+	}
+
+	sort.Slice(submodules, func(i, j int) bool {
+		return submodules[i].Path < submodules[j].Path
+	})
+
+	return submodules, nil
+}
+
+// submoduleState reads the checkout of the submodule at fullPath. A submodule that isn't
+// cloned yet is an empty directory, or no directory at all.
+func (s *GitService) submoduleState(fullPath string) (submoduleState, error) {
+	if _, err := os.Lstat(filepath.Join(fullPath, ".git")); err != nil {
+		return submoduleState{}, nil
+	}
+
+	repo, err := s.openRepository(fullPath)
+	if err != nil {
+		return submoduleState{}, err
+	}
+
+	state := submoduleState{initialized: true}
+	if head, err := repo.Head(); err == nil {
+		state.head = head.Hash()
+	} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return submoduleState{}, fmt.Errorf("failed to get submodule HEAD: %w", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return submoduleState{}, fmt.Errorf("failed to get submodule worktree: %w", err)
+	}
+
+	status, err := s.status(repo, worktree)
+	if err != nil {
+		return submoduleState{}, err
+	}
+	state.dirty = len(status) > 0
+
+	return state, nil
+}
+
+// submoduleCode compares a submodule to the commit recorded for it. Like git, a submodule
+// that has another commit checked out or local changes is modified, and one that is not
+// initialized is unchanged.
+func (s *GitService) submoduleCode(fullPath string, recorded plumbing.Hash) (git.StatusCode, error) {
+	state, err := s.submoduleState(fullPath)
+	if err != nil {
+		return git.Unmodified, err
+	}
+
+	if state.initialized && (state.head != recorded || state.dirty) {
+		return git.Modified, nil
+	}
+	return git.Unmodified, nil
+}
+
+// submoduleSide returns the checkout of a submodule as a diff side, in the form git
+// diffs submodules: "Subproject commit <hash>", with -dirty for local changes
+func (s *GitService) submoduleSide(fullPath string) (diffSide, error) {
+	state, err := s.submoduleState(fullPath)
+	if err != nil || !state.initialized {
+		return diffSide{}, err
+	}
+	return diffSide{content: subprojectCommit(state.head, state.dirty), exists: true}, nil
+}
+
+// subprojectCommit is the content of a submodule in diffs
+func subprojectCommit(hash plumbing.Hash, dirty bool) string {
+	if dirty {
+		return fmt.Sprintf("Subproject commit %s-dirty\n", hash)
+	}
+	return fmt.Sprintf("Subproject commit %s\n", hash)
+}
+
+// stageSubmodule records the commit checked out in a submodule in the index, like git add
+// does for a submodule path. It reports false when file is not a submodule.
+func (s *GitService) stageSubmodule(repo *git.Repository, worktree *git.Worktree, file string) (bool, error) {
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return false, fmt.Errorf("failed to get index: %w", err)
+	}
+
+	entry, err := idx.Entry(file)
+	if err != nil || entry.Mode != filemode.Submodule || entry.Stage != 0 {
+		return false, nil
+	}
+
+	state, err := s.submoduleState(filepath.Join(worktree.Filesystem.Root(), filepath.FromSlash(file)))
+	if err != nil {
+		return true, err
+	}
+	if !state.initialized || state.head.IsZero() || state.head == entry.Hash {
+		return true, nil
+	}
+
+	entry.Hash = state.head
+	if err := s.setIndex(repo, idx); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
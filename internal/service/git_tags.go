@@ -0,0 +1,318 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// TagInfo represents a lightweight or annotated tag
+type TagInfo struct {
+	Name        string    `json:"name"`
+	Hash        string    `json:"hash"`        // Commit the tag points at, through any annotated tags
+	Annotated   bool      `json:"annotated"`   // Whether the tag is a tag object rather than a plain ref
+	Message     string    `json:"message"`     // Annotation message, empty for lightweight tags
+	Tagger      string    `json:"tagger"`      // Who created an annotated tag
+	TaggerEmail string    `json:"taggerEmail"` // Email of the tagger
+	Date        time.Time `json:"date"`        // When an annotated tag was made, or the commit date of a lightweight tag
+	Signed      bool      `json:"signed"`      // Whether the tag object has a signature
+	Verified    bool      `json:"verified"`    // Whether the signature is good and made by a trusted key
+}
+
+// ListTags returns all tags, newest first
+func (s *GitService) ListTags(projectPath string) ([]TagInfo, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer refs.Close()
+
+	var tags []TagInfo
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		tag, err := s.tagInfo(repo, ref)
+		if err != nil {
+			return err
+		}
+		tags = append(tags, *tag)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		if !tags[i].Date.Equal(tags[j].Date) {
+			return tags[i].Date.After(tags[j].Date)
+		}
+		return tags[i].Name > tags[j].Name
+	})
+
+	return tags, nil
+}
+
+// tagInfo describes the tag a ref points at
+func (s *GitService) tagInfo(repo *git.Repository, ref *plumbing.Reference) (*TagInfo, error) {
+	info := &TagInfo{Name: ref.Name().Short(), Hash: ref.Hash().String()}
+
+	tag, err := repo.TagObject(ref.Hash())
+	switch {
+	case err == nil:
+		info.Annotated = true
+		info.Message = tag.Message
+		info.Tagger = tag.Tagger.Name
+		info.TaggerEmail = tag.Tagger.Email
+		info.Date = tag.Tagger.When
+		info.Signed = tag.PGPSignature != ""
+
+		info.Verified = s.verifyTag(repo, tag)
+
+		target, err := s.peelTag(repo, tag)
+		if err != nil {
+			return nil, err
+		}
+		info.Hash = target.String()
+	case errors.Is(err, plumbing.ErrObjectNotFound):
+		// A lightweight tag, dated by its commit
+		if commit, err := repo.CommitObject(ref.Hash()); err == nil {
+			info.Date = commit.Committer.When
+		}
+	default:
+		return nil, fmt.Errorf("failed to get tag %s: %w", info.Name, err)
+	}
+
+	return info, nil
+}
+
+// peelTag follows an annotated tag, and the tags it may point at, to the object it tags
+func (s *GitService) peelTag(repo *git.Repository, tag *object.Tag) (plumbing.Hash, error) {
+	for tag.TargetType == plumbing.TagObject {
+		next, err := repo.TagObject(tag.Target)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to get tag %s: %w", tag.Name, err)
+		}
+		tag = next
+	}
+	return tag.Target, nil
+}
+
+// tagCommit returns the commit a tag points at
+func (s *GitService) tagCommit(repo *git.Repository, name string) (plumbing.Hash, error) {
+	ref, err := repo.Tag(name)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("tag %q not found", name)
+	}
+
+	target := ref.Hash()
+	if tag, err := repo.TagObject(ref.Hash()); err == nil {
+		if target, err = s.peelTag(repo, tag); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	if _, err := repo.CommitObject(target); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("tag %q does not point at a commit", name)
+	}
+
+	return target, nil
+}
+
+// CreateTag tags a commit (HEAD when target is empty). With a message it creates an
+// annotated tag, signed with the configured signing key when sign is set; without one
+// it creates a lightweight tag.
+func (s *GitService) CreateTag(projectPath string, name string, target string, message string, sign bool) (*TagInfo, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	refName := plumbing.NewTagReferenceName(name)
+	if err := refName.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid tag name %q: %w", name, err)
+	}
+
+	if _, err := repo.Reference(refName, false); err == nil {
+		return nil, fmt.Errorf("a tag named %q already exists", name)
+	}
+
+	commit, err := s.resolveCommit(repo, target)
+	if err != nil {
+		return nil, err
+	}
+
+	message = cleanupMessage(message)
+	if message == "" && sign {
+		return nil, errors.New("a signed tag needs a message")
+	}
+
+	ref := plumbing.NewHashReference(refName, commit.Hash)
+	if message != "" {
+		hash, err := s.createTagObject(repo, name, commit.Hash, message, sign)
+		if err != nil {
+			return nil, err
+		}
+		ref = plumbing.NewHashReference(refName, hash)
+	}
+
+	if err := repo.Storer.SetReference(ref); err != nil {
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	return s.tagInfo(repo, ref)
+}
+
+// createTagObject writes an annotated tag object, tagged by the committer identity
+func (s *GitService) createTagObject(repo *git.Repository, name string, target plumbing.Hash, message string, sign bool) (plumbing.Hash, error) {
+	tagger, err := s.signature(repo)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	tag := &object.Tag{
+		Name:       name,
+		Tagger:     *tagger,
+		Message:    commitMessage(message),
+		TargetType: plumbing.CommitObject,
+		Target:     target,
+	}
+
+	if sign {
+		scopes, err := s.loadConfigScopes(repo)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		signer, err := s.signer(repo, scopes)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		payload, err := tagPayload(tag)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		signature, err := signer.Sign(bytes.NewReader(payload))
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		tag.PGPSignature = string(signature)
+	}
+
+	obj := repo.Storer.NewEncodedObject()
+	if err := tag.Encode(obj); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to encode tag: %w", err)
+	}
+
+	hash, err := repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store tag: %w", err)
+	}
+
+	return hash, nil
+}
+
+// verifyTag reports whether a tag has a good signature from a trusted key, see verifySignature.
+// A tag that can't be checked is not verified.
+func (s *GitService) verifyTag(repo *git.Repository, tag *object.Tag) bool {
+	if tag.PGPSignature == "" {
+		return false
+	}
+
+	payload, err := tagPayload(tag)
+	if err != nil {
+		log.Printf("[GitService] Failed to verify tag %s: %v", tag.Name, err)
+		return false
+	}
+
+	return s.verifySignature(repo, tag.Hash, tag.PGPSignature, payload)
+}
+
+// tagPayload returns the encoded tag without its signature, which is what gets signed
+func tagPayload(tag *object.Tag) ([]byte, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := tag.EncodeWithoutSignature(encoded); err != nil {
+		return nil, fmt.Errorf("failed to encode tag: %w", err)
+	}
+
+	reader, err := encoded.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag: %w", err)
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// DeleteTag deletes a local tag
+func (s *GitService) DeleteTag(projectPath string, name string) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	refName := plumbing.NewTagReferenceName(name)
+	if _, err := repo.Reference(refName, false); err != nil {
+		return fmt.Errorf("tag %q not found", name)
+	}
+
+	if err := repo.Storer.RemoveReference(refName); err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	return nil
+}
+
+// PushTag pushes a tag to a remote, "origin" unless opts.Remote is set.
+// An existing tag of the same name on the remote is only replaced when opts.Force is set.
+func (s *GitService) PushTag(projectPath string, name string, opts RemoteOptions) error {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+
+	refName := plumbing.NewTagReferenceName(name)
+	if _, err := repo.Reference(refName, false); err != nil {
+		return fmt.Errorf("tag %q not found", name)
+	}
+
+	remoteName := opts.Remote
+	if remoteName == "" {
+		remoteName = git.DefaultRemoteName
+	}
+
+	auth, err := s.remoteAuth(repo, remoteName, opts.Auth)
+	if err != nil {
+		return err
+	}
+
+	refSpec := config.RefSpec(fmt.Sprintf("%s:%s", refName, refName))
+	if opts.Force {
+		refSpec = "+" + refSpec
+	}
+
+	err = repo.Push(&git.PushOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       auth,
+		Progress:   &progressWriter{service: s, projectPath: projectPath, operation: "push"},
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return remoteError("push", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testGitEnv gives the git CLI a fixed identity and date and no user or system
+// configuration, so that repositories created the same way have the same commits
+var testGitEnv = []string{
+	"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+	"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com",
+	"GIT_AUTHOR_DATE=2024-01-01T12:00:00Z", "GIT_COMMITTER_DATE=2024-01-01T12:00:00Z",
+	"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+}
+
+// runGit runs the git CLI in dir with a fixed identity and returns its output
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), testGitEnv...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// initTestRepo creates a repository on branch main in a temporary directory
+func initTestRepo(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q", "-b", "main")
+	runGit(t, dir, "config", "user.name", "Test")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+	return dir
+}
+
+// writeTestFile writes a file of the working tree, creating its directories
+func writeTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTestFile reads a file of the working tree
+func readTestFile(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// commitAll stages every change and commits it with the git CLI
+func commitAll(t *testing.T, dir, message string) {
+	t.Helper()
+
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", message)
+}
+
+// runGitFails runs the git CLI like runGit for a command expected to fail, such as a
+// merge that stops on conflicts
+func runGitFails(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), testGitEnv...)
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected git %s to fail:\n%s", strings.Join(args, " "), out)
+	}
+	return string(out)
+}
+
+// TestListCommitsPagination lists the commits of a branch one page at a time and checks
+// every page against git log
+func TestListCommitsPagination(t *testing.T) {
+	dir := initTestRepo(t)
+	for i := 1; i <= 5; i++ {
+		writeTestFile(t, dir, "a.txt", strings.Repeat("a\n", i))
+		commitAll(t, dir, "Commit "+strconv.Itoa(i))
+	}
+	expected := strings.Fields(runGit(t, dir, "log", "--format=%H"))
+
+	s := NewGitService(nil)
+	for _, limit := range []int{1, 2, 5, 6} {
+		var hashes []string
+		offsetHash := ""
+		for {
+			commits, err := s.ListCommits(dir, CommitFilter{Limit: limit, OffsetHash: offsetHash})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(commits) == 0 || len(commits) > limit {
+				t.Fatalf("limit %d: got a page of %d commits", limit, len(commits))
+			}
+			for _, commit := range commits {
+				hashes = append(hashes, commit.Hash)
+			}
+
+			last := commits[len(commits)-1]
+			if last.HasMore != (len(hashes) < len(expected)) {
+				t.Fatalf("limit %d: HasMore is %v after %d commits", limit, last.HasMore, len(hashes))
+			}
+			if !last.HasMore {
+				break
+			}
+			offsetHash = last.Hash
+		}
+
+		if strings.Join(hashes, " ") != strings.Join(expected, " ") {
+			t.Errorf("limit %d: got commits %v, expected %v", limit, hashes, expected)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// WorktreeInfo describes a working tree of a repository, like git worktree list
+type WorktreeInfo struct {
+	Path      string `json:"path"`      // Directory of the working tree, can be opened as its own project
+	Name      string `json:"name"`      // Name under .git/worktrees, empty for the main working tree
+	Branch    string `json:"branch"`    // Checked out branch, empty when HEAD is detached
+	Head      string `json:"head"`      // Checked out commit, empty on an unborn branch
+	IsMain    bool   `json:"isMain"`    // Whether this is the working tree the repository was created in
+	IsCurrent bool   `json:"isCurrent"` // Whether this is the working tree of the project asked about
+	Locked    bool   `json:"locked"`    // Whether git worktree lock protects it from removal
+	Prunable  bool   `json:"prunable"`  // Whether its directory is gone, so git worktree prune would drop it
+}
+
+// ListWorktrees returns the main working tree of a repository followed by its linked ones
+func (s *GitService) ListWorktrees(projectPath string) ([]WorktreeInfo, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	common, err := s.commonDir(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	currentPath := filepath.Clean(current.Filesystem.Root())
+
+	var worktrees []WorktreeInfo
+
+	// The main working tree holds the common directory, unless the repository is bare
+	if filepath.Base(common) == ".git" {
+		main := WorktreeInfo{Path: filepath.Dir(common), IsMain: true}
+		if err := s.readWorktreeHead(repo, filepath.Join(common, "HEAD"), &main); err != nil {
+			return nil, err
+		}
+		worktrees = append(worktrees, main)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(common, "worktrees"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		admin := filepath.Join(common, "worktrees", entry.Name())
+
+		// gitdir points at the .git file in the working tree
+		gitdir, err := os.ReadFile(filepath.Join(admin, "gitdir"))
+		if err != nil {
+			continue
+		}
+		info := WorktreeInfo{
+			Path: filepath.Dir(strings.TrimSpace(string(gitdir))),
+			Name: entry.Name(),
+		}
+		if _, err := os.Stat(filepath.Join(admin, "locked")); err == nil {
+			info.Locked = true
+		}
+		if _, err := os.Stat(info.Path); os.IsNotExist(err) {
+			info.Prunable = true
+		}
+		if err := s.readWorktreeHead(repo, filepath.Join(admin, "HEAD"), &info); err != nil {
+			return nil, err
+		}
+		worktrees = append(worktrees, info)
+	}
+
+	for i := range worktrees {
+		worktrees[i].IsCurrent = sameFile(worktrees[i].Path, currentPath)
+	}
+
+	return worktrees, nil
+}
+
+// readWorktreeHead sets the branch and commit from the HEAD file of a working tree
+func (s *GitService) readWorktreeHead(repo *git.Repository, headFile string, info *WorktreeInfo) error {
+	data, err := os.ReadFile(headFile)
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD of %s: %w", info.Path, err)
+	}
+
+	head := strings.TrimSpace(string(data))
+	if target, ok := strings.CutPrefix(head, "ref: "); ok {
+		branch := plumbing.ReferenceName(target)
+		info.Branch = branch.Short()
+		if ref, err := repo.Reference(branch, true); err == nil {
+			info.Head = ref.Hash().String()
+		}
+		return nil
+	}
+
+	info.Head = head
+	return nil
+}
+
+// AddWorktree checks out a branch in a new working tree at path, which must not exist or
+// be empty. With newBranch the branch is created at HEAD first; an empty branch checks out
+// HEAD detached. Like git, a branch can only be checked out in one working tree at a time.
+func (s *GitService) AddWorktree(projectPath string, path string, branch string, newBranch bool) (*WorktreeInfo, error) {
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	if entries, err := os.ReadDir(path); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("%s already exists and is not empty", path)
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get HEAD reference: %w", err)
+	}
+
+	// The HEAD of the new working tree: a branch, or the current commit when detached
+	headContent := head.Hash().String()
+	target := head.Hash()
+	if branch != "" {
+		refName := plumbing.NewBranchReferenceName(branch)
+		if !newBranch {
+			ref, err := repo.Reference(refName, true)
+			if err != nil {
+				return nil, fmt.Errorf("branch %q not found", branch)
+			}
+			target = ref.Hash()
+
+			worktrees, err := s.ListWorktrees(projectPath)
+			if err != nil {
+				return nil, err
+			}
+			for _, wt := range worktrees {
+				if wt.Branch == branch {
+					return nil, fmt.Errorf("branch %q is already checked out at %s", branch, wt.Path)
+				}
+			}
+		}
+		headContent = "ref: " + refName.String()
+	}
+
+	common, err := s.commonDir(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	admin, name, err := newWorktreeAdminDir(common, filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+
+	// Undo everything on failure, git would leave nothing behind either
+	created, branchCreated := false, false
+	defer func() {
+		if !created {
+			os.RemoveAll(admin)
+			os.RemoveAll(path)
+			if branchCreated {
+				repo.Storer.RemoveReference(plumbing.NewBranchReferenceName(branch))
+			}
+		}
+	}()
+
+	if branch != "" && newBranch {
+		if err := s.CreateBranch(projectPath, branch, target.String()); err != nil {
+			return nil, err
+		}
+		branchCreated = true
+	}
+
+	files := map[string]string{
+		"HEAD":      headContent + "\n",
+		"commondir": "../..\n",
+		"gitdir":    filepath.Join(path, ".git") + "\n",
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(admin, file), []byte(content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write worktree %s: %w", file, err)
+		}
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	if err := os.WriteFile(filepath.Join(path, ".git"), []byte("gitdir: "+admin+"\n"), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write .git file: %w", err)
+	}
+
+	wtRepo, err := s.openRepository(path)
+	if err != nil {
+		return nil, err
+	}
+	worktree, err := wtRepo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := worktree.Reset(&git.ResetOptions{Commit: target, Mode: git.HardReset}); err != nil {
+		return nil, fmt.Errorf("failed to check out worktree: %w", err)
+	}
+	created = true
+
+	return &WorktreeInfo{
+		Path:   path,
+		Name:   name,
+		Branch: branch,
+		Head:   target.String(),
+	}, nil
+}
+
+// newWorktreeAdminDir creates the directory under .git/worktrees that holds the HEAD and
+// index of a new working tree, named after it and numbered when the name is taken
+func newWorktreeAdminDir(common, base string) (string, string, error) {
+	if err := os.MkdirAll(filepath.Join(common, "worktrees"), 0755); err != nil {
+		return "", "", fmt.Errorf("failed to create worktrees directory: %w", err)
+	}
+
+	name := base
+	for i := 1; ; i++ {
+		dir := filepath.Join(common, "worktrees", name)
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			return dir, name, nil
+		}
+		if !os.IsExist(err) {
+			return "", "", fmt.Errorf("failed to create worktree directory: %w", err)
+		}
+		name = base + strconv.Itoa(i)
+	}
+}
+
+// RemoveWorktree deletes a linked working tree and its administrative files. Unless force
+// is set, a working tree with local changes or one that is locked is kept.
+func (s *GitService) RemoveWorktree(projectPath string, path string, force bool) error {
+	worktrees, err := s.ListWorktrees(projectPath)
+	if err != nil {
+		return err
+	}
+
+	var info *WorktreeInfo
+	for i := range worktrees {
+		if sameFile(worktrees[i].Path, path) {
+			info = &worktrees[i]
+		}
+	}
+	if info == nil {
+		return fmt.Errorf("%s is not a working tree of this repository", path)
+	}
+	if info.IsMain {
+		return errors.New("the main working tree cannot be removed")
+	}
+	if info.Locked && !force {
+		return fmt.Errorf("working tree %s is locked", info.Path)
+	}
+
+	if !force && !info.Prunable {
+		repo, err := s.openRepository(info.Path)
+		if err != nil {
+			return err
+		}
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return fmt.Errorf("failed to get worktree: %w", err)
+		}
+		status, err := s.status(repo, worktree)
+		if err != nil {
+			return err
+		}
+		if len(status) > 0 {
+			return fmt.Errorf("working tree %s has local changes", info.Path)
+		}
+	}
+
+	repo, err := s.openRepository(projectPath)
+	if err != nil {
+		return err
+	}
+	common, err := s.commonDir(repo)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(info.Path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", info.Path, err)
+	}
+	if err := os.RemoveAll(filepath.Join(common, "worktrees", info.Name)); err != nil {
+		return fmt.Errorf("failed to remove worktree files: %w", err)
+	}
+
+	s.InvalidateRepository(info.Path)
+	return nil
+}
+
+// sameFile reports whether two paths name the same directory, resolving symlinks
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(infoA, infoB)
+}